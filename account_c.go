@@ -109,3 +109,104 @@ func MCTxOutMatchesSubaddress(txOutTargetKeyStr, txOutPublicKeyStr, viewPrivateK
 	}
 	return result, nil
 }
+
+// mc_account_private_keys_from_root_entropy
+func MCAccountPrivateKeysFromRootEntropy(rootEntropy []byte) (string, string, error) {
+	if len(rootEntropy) != 32 {
+		return "", "", errors.New("invalid root entropy")
+	}
+	root_entropy_bytes := C.CBytes(rootEntropy)
+	defer C.free(root_entropy_bytes)
+	root_entropy := &C.McBuffer{
+		buffer: (*C.uint8_t)(root_entropy_bytes),
+		len:    C.size_t(len(rootEntropy)),
+	}
+
+	out_view_private_buf := make([]byte, 32)
+	out_view_private_bytes := C.CBytes(out_view_private_buf)
+	defer C.free(out_view_private_bytes)
+	out_view_private := &C.McMutableBuffer{
+		buffer: (*C.uint8_t)(out_view_private_bytes),
+		len:    C.size_t(len(out_view_private_buf)),
+	}
+
+	out_spend_private_buf := make([]byte, 32)
+	out_spend_private_bytes := C.CBytes(out_spend_private_buf)
+	defer C.free(out_spend_private_bytes)
+	out_spend_private := &C.McMutableBuffer{
+		buffer: (*C.uint8_t)(out_spend_private_bytes),
+		len:    C.size_t(len(out_spend_private_buf)),
+	}
+
+	b, err := C.mc_account_private_keys_from_root_entropy(root_entropy, out_view_private, out_spend_private)
+	if err != nil {
+		return "", "", err
+	}
+	if !b {
+		return "", "", errors.New("invalid root entropy")
+	}
+
+	return hex.EncodeToString(C.GoBytes(out_view_private_bytes, 32)), hex.EncodeToString(C.GoBytes(out_spend_private_bytes, 32)), nil
+}
+
+// mc_account_key_get_short_address_hash, the hex 16 bytes hash of the address
+// which sender memos carry
+func MCAccountKeyGetShortAddressHash(address *account.PublicAddress) (string, error) {
+	public_address, free_public_address := newPublicAddressC(address)
+	defer free_public_address()
+	out_short_address_hash, free_out_short_address_hash := newMutableBufferC(16)
+	defer free_out_short_address_hash()
+
+	var out_error *C.McError
+	b, err := C.mc_account_key_get_short_address_hash(public_address, out_short_address_hash, &out_error)
+	if err != nil {
+		return "", err
+	}
+	if !b {
+		return "", mcError("mc_account_key_get_short_address_hash", out_error)
+	}
+	return hex.EncodeToString(mutableBufferBytes(out_short_address_hash)), nil
+}
+
+// mc_account_key_get_public_address_public_keys, the hex view and spend public
+// keys of the subaddress
+func MCAccountKeyGetPublicAddressPublicKeys(viewPrivateKeyStr, spendPrivateKeyStr string, index uint64) (string, string, error) {
+	view_private_key, free_view_private_key := newBufferC(account.HexToScalar(viewPrivateKeyStr).Bytes())
+	defer free_view_private_key()
+	spend_private_key, free_spend_private_key := newBufferC(account.HexToScalar(spendPrivateKeyStr).Bytes())
+	defer free_spend_private_key()
+	out_view_public_key, free_out_view_public_key := newMutableBufferC(32)
+	defer free_out_view_public_key()
+	out_spend_public_key, free_out_spend_public_key := newMutableBufferC(32)
+	defer free_out_spend_public_key()
+
+	b, err := C.mc_account_key_get_public_address_public_keys(view_private_key, spend_private_key, C.uint64_t(index), out_view_public_key, out_spend_public_key)
+	if err != nil {
+		return "", "", err
+	}
+	if !b {
+		return "", "", errors.New("invalid private key")
+	}
+	return hex.EncodeToString(mutableBufferBytes(out_view_public_key)), hex.EncodeToString(mutableBufferBytes(out_spend_public_key)), nil
+}
+
+// mc_account_key_get_public_address_fog_authority_sig, the hex 64 bytes
+// signature of the fog authority by the subaddress view private key
+func MCAccountKeyGetPublicAddressFogAuthoritySig(viewPrivateKeyStr, spendPrivateKeyStr string, fog *AccountFogInfo, index uint64) (string, error) {
+	if fog == nil || fog.ReportUrl == "" || len(fog.AuthoritySpki) == 0 {
+		return "", errors.New("invalid fog info")
+	}
+	account_key, free_account_key := newFogAccountKeyC(account.HexToScalar(viewPrivateKeyStr), account.HexToScalar(spendPrivateKeyStr), fog)
+	defer free_account_key()
+	out_fog_authority_sig, free_out_fog_authority_sig := newMutableBufferC(64)
+	defer free_out_fog_authority_sig()
+
+	b, err := C.mc_account_key_get_public_address_fog_authority_sig(account_key, C.uint64_t(index), out_fog_authority_sig)
+	if err != nil {
+		return "", err
+	}
+	if !b {
+		return "", errors.New("mc_account_key_get_public_address_fog_authority_sig failure")
+	}
+	return hex.EncodeToString(mutableBufferBytes(out_fog_authority_sig)), nil
+}
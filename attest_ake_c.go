@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo darwin LDFLAGS: ${SRCDIR}/include/libmobilecoin.a -framework Security -framework Foundation
+// #cgo linux LDFLAGS: ${SRCDIR}/include/libmobilecoin_linux.a -lm -ldl
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <errno.h>
+// #include "libmobilecoin.h"
+import "C"
+
+// AttestAke is the client of the AKE handshake with a fog enclave, attested
+// against the enclaves and signers of a FogVerifier. After the handshake it
+// encrypts the requests and decrypts the responses of the channel. It must
+// be freed by Free.
+type AttestAke struct {
+	ake      *C.McAttestAke
+	verifier *C.McVerifier
+	frees    *fogResolverC
+}
+
+func NewAttestAke(fogVerifier *FogVerifier) (*AttestAke, error) {
+	frees, verifier, err := newFogVerifierC(fogVerifier)
+	if err != nil {
+		return nil, err
+	}
+	attest_ake, err := C.mc_attest_ake_create()
+	if err != nil {
+		frees.free()
+		return nil, err
+	}
+	if attest_ake == nil {
+		frees.free()
+		return nil, errors.New("mc_attest_ake_create failure")
+	}
+	return &AttestAke{ake: attest_ake, verifier: verifier, frees: frees}, nil
+}
+
+func (a *AttestAke) Free() {
+	C.mc_attest_ake_free(a.ake)
+	a.frees.free()
+}
+
+// AuthRequest starts the handshake with the responder id, the host:port of
+// the fog service, the first call with a nil buffer gets the size.
+func (a *AttestAke) AuthRequest(responderID string) ([]byte, error) {
+	responder_id := C.CString(responderID)
+	defer C.free(unsafe.Pointer(responder_id))
+
+	var rng_callback *C.McRngCallback
+	var out_size *C.McMutableBuffer
+	request_size := C.mc_attest_ake_get_auth_request(a.ake, responder_id, rng_callback, out_size)
+	if request_size <= 0 {
+		return nil, errors.New("mc_attest_ake_get_auth_request failure")
+	}
+	out_auth_request, free_out_auth_request := newMutableBufferC(int(request_size))
+	defer free_out_auth_request()
+	request_size = C.mc_attest_ake_get_auth_request(a.ake, responder_id, rng_callback, out_auth_request)
+	if request_size <= 0 {
+		return nil, errors.New("mc_attest_ake_get_auth_request failure")
+	}
+	return C.GoBytes(unsafe.Pointer(out_auth_request.buffer), C.int(request_size)), nil
+}
+
+// ProcessAuthResponse verifies the attestation of the enclave
+func (a *AttestAke) ProcessAuthResponse(data []byte) error {
+	auth_response_data, free_auth_response_data := newBufferC(data)
+	defer free_auth_response_data()
+
+	var out_error *C.McError
+	b := C.mc_attest_ake_process_auth_response(a.ake, auth_response_data, a.verifier, &out_error)
+	if !b {
+		return mcError("mc_attest_ake_process_auth_response", out_error)
+	}
+	return nil
+}
+
+func (a *AttestAke) IsAttested() bool {
+	var attested bool
+	b := C.mc_attest_ake_is_attested(a.ake, (*C.bool)(&attested))
+	return bool(b) && attested
+}
+
+// Binding is the channel id of the attested channel
+func (a *AttestAke) Binding() ([]byte, error) {
+	if !a.IsAttested() {
+		return nil, errors.New("attest ake not attested")
+	}
+	var out_size *C.McMutableBuffer
+	binding_size := C.mc_attest_ake_get_binding(a.ake, out_size)
+	if binding_size < 0 {
+		return nil, errors.New("mc_attest_ake_get_binding failure")
+	}
+	out_binding, free_out_binding := newMutableBufferC(int(binding_size))
+	defer free_out_binding()
+	binding_size = C.mc_attest_ake_get_binding(a.ake, out_binding)
+	if binding_size < 0 {
+		return nil, errors.New("mc_attest_ake_get_binding failure")
+	}
+	return C.GoBytes(unsafe.Pointer(out_binding.buffer), C.int(binding_size)), nil
+}
+
+func (a *AttestAke) Encrypt(aad, plaintext []byte) ([]byte, error) {
+	c_aad, free_aad := newBufferC(aad)
+	defer free_aad()
+	c_plaintext, free_plaintext := newBufferC(plaintext)
+	defer free_plaintext()
+
+	var out_error *C.McError
+	var out_size *C.McMutableBuffer
+	ciphertext_size := C.mc_attest_ake_encrypt(a.ake, c_aad, c_plaintext, out_size, &out_error)
+	if ciphertext_size < 0 {
+		return nil, mcError("mc_attest_ake_encrypt", out_error)
+	}
+	out_ciphertext, free_out_ciphertext := newMutableBufferC(int(ciphertext_size))
+	defer free_out_ciphertext()
+	ciphertext_size = C.mc_attest_ake_encrypt(a.ake, c_aad, c_plaintext, out_ciphertext, &out_error)
+	if ciphertext_size < 0 {
+		return nil, mcError("mc_attest_ake_encrypt", out_error)
+	}
+	return C.GoBytes(unsafe.Pointer(out_ciphertext.buffer), C.int(ciphertext_size)), nil
+}
+
+func (a *AttestAke) Decrypt(aad, ciphertext []byte) ([]byte, error) {
+	c_aad, free_aad := newBufferC(aad)
+	defer free_aad()
+	c_ciphertext, free_ciphertext := newBufferC(ciphertext)
+	defer free_ciphertext()
+	out_plaintext, free_out_plaintext := newMutableBufferC(len(ciphertext))
+	defer free_out_plaintext()
+
+	var out_error *C.McError
+	plaintext_size := C.mc_attest_ake_decrypt(a.ake, c_aad, c_ciphertext, out_plaintext, &out_error)
+	if plaintext_size < 0 {
+		return nil, mcError("mc_attest_ake_decrypt", out_error)
+	}
+	return C.GoBytes(unsafe.Pointer(out_plaintext.buffer), C.int(plaintext_size)), nil
+}
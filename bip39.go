@@ -0,0 +1,175 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/bwesterb/go-ristretto"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	SLIP10_ED25519_SEED_KEY   = "ed25519 seed"
+	SLIP10_MOBILECOIN_COIN    = 866
+	SLIP10_HARDENED_OFFSET    = 0x80000000
+	SLIP10_SPEND_DOMAIN_TAG   = "mobilecoin-ristretto255-spend"
+	SLIP10_VIEW_DOMAIN_TAG    = "mobilecoin-ristretto255-view"
+	BIP39_SEED_ITERATIONS     = 2048
+	BIP39_DEFAULT_ENTROPY_LEN = 32
+)
+
+// the sha256 of the file is 2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda
+//
+//go:embed bip39_english.txt
+var bip39EnglishText string
+
+var bip39English = strings.Fields(bip39EnglishText)
+
+// NewMnemonic of 24 words from random entropy
+func NewMnemonic() (string, error) {
+	entropy := make([]byte, BIP39_DEFAULT_ENTROPY_LEN)
+	_, err := rand.Read(entropy)
+	if err != nil {
+		return "", err
+	}
+	return Bip39MnemonicFromEntropy(entropy)
+}
+
+// Bip39MnemonicFromEntropy encodes 16 to 32 bytes entropy, in multiples of 4,
+// with its sha256 checksum as english words.
+func Bip39MnemonicFromEntropy(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("invalid entropy length %d", len(entropy))
+	}
+	checksumBits := len(entropy) / 4
+	sum := sha256.Sum256(entropy)
+
+	var bits big.Int
+	bits.SetBytes(entropy)
+	bits.Lsh(&bits, uint(checksumBits))
+	bits.Or(&bits, big.NewInt(int64(sum[0]>>(8-checksumBits))))
+
+	count := (len(entropy)*8 + checksumBits) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	for i := count - 1; i >= 0; i-- {
+		var index big.Int
+		words[i] = bip39English[index.And(&bits, mask).Int64()]
+		bits.Rsh(&bits, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// Bip39EntropyFromMnemonic validates the words and the checksum of mnemonic
+func Bip39EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("invalid mnemonic words count %d", len(words))
+	}
+
+	var bits big.Int
+	for _, word := range words {
+		index := sort.SearchStrings(bip39English, word)
+		if index == len(bip39English) || bip39English[index] != word {
+			return nil, fmt.Errorf("invalid mnemonic word %s", word)
+		}
+		bits.Lsh(&bits, 11)
+		bits.Or(&bits, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) / 3
+	var checksum big.Int
+	checksum.And(&bits, big.NewInt(int64(1<<checksumBits-1)))
+	bits.Rsh(&bits, uint(checksumBits))
+	entropy := bits.FillBytes(make([]byte, checksumBits*4))
+	sum := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(sum[0]>>(8-checksumBits)) {
+		return nil, fmt.Errorf("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+// Bip39WordsByPrefix lists the english words starting with prefix, for the
+// autocomplete of the mnemonic input.
+func Bip39WordsByPrefix(prefix string) []string {
+	var words []string
+	start := sort.SearchStrings(bip39English, prefix)
+	for _, word := range bip39English[start:] {
+		if !strings.HasPrefix(word, prefix) {
+			break
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// Slip10AccountPrivateKeysFromMnemonic derives the hex view and spend private
+// keys at m/44'/866'/accountIndex' of the mnemonic without passphrase.
+func Slip10AccountPrivateKeysFromMnemonic(mnemonic string, accountIndex uint32) (string, string, error) {
+	_, err := Bip39EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return "", "", err
+	}
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	seed := pbkdf2.Key([]byte(normalized), []byte("mnemonic"), BIP39_SEED_ITERATIONS, 64, sha512.New)
+	key := slip10DeriveEd25519(seed, []uint32{44, SLIP10_MOBILECOIN_COIN, accountIndex})
+
+	view, err := slip10RistrettoPrivate(key, SLIP10_VIEW_DOMAIN_TAG)
+	if err != nil {
+		return "", "", err
+	}
+	spend, err := slip10RistrettoPrivate(key, SLIP10_SPEND_DOMAIN_TAG)
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes()), nil
+}
+
+// SpendAccountFromMnemonic is the default subaddress of the account
+func SpendAccountFromMnemonic(mnemonic string, accountIndex uint32) (*SpendAccount, error) {
+	view, spend, err := Slip10AccountPrivateKeysFromMnemonic(mnemonic, accountIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &SpendAccount{ViewPrivateKey: view, SpendPrivateKey: spend}, nil
+}
+
+// slip10DeriveEd25519 returns the private key of the hardened path
+func slip10DeriveEd25519(seed []byte, path []uint32) []byte {
+	mac := hmac.New(sha512.New, []byte(SLIP10_ED25519_SEED_KEY))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := sum[:32], sum[32:]
+
+	for _, index := range path {
+		data := append([]byte{0}, key...)
+		data = binary.BigEndian.AppendUint32(data, index|SLIP10_HARDENED_OFFSET)
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+		key, chainCode = sum[:32], sum[32:]
+	}
+	return key
+}
+
+func slip10RistrettoPrivate(key []byte, domain string) (*ristretto.Scalar, error) {
+	kdf := hkdf.New(sha512.New, key, []byte(domain), nil)
+	var wide [64]byte
+	_, err := io.ReadFull(kdf, wide[:])
+	if err != nil {
+		return nil, err
+	}
+	var s ristretto.Scalar
+	return s.SetReduced(&wide), nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unsafe"
+)
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo darwin LDFLAGS: ${SRCDIR}/include/libmobilecoin.a -framework Security -framework Foundation
+// #cgo linux LDFLAGS: ${SRCDIR}/include/libmobilecoin_linux.a -lm -ldl
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <errno.h>
+// #include "libmobilecoin.h"
+import "C"
+
+func MCBip39MnemonicFromEntropy(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", errors.New("invalid entropy length")
+	}
+	c_entropy, free_entropy := newBufferC(entropy)
+	defer free_entropy()
+
+	c_mnemonic, err := C.mc_bip39_mnemonic_from_entropy(c_entropy)
+	if err != nil {
+		return "", err
+	}
+	if c_mnemonic == nil {
+		return "", errors.New("mc_bip39_mnemonic_from_entropy failure")
+	}
+	defer C.mc_string_free(c_mnemonic)
+	return C.GoString(c_mnemonic), nil
+}
+
+// MCBip39EntropyFromMnemonic, the first call with a nil buffer gets the size
+func MCBip39EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	c_mnemonic := C.CString(mnemonic)
+	defer C.free(unsafe.Pointer(c_mnemonic))
+
+	var out_error *C.McError
+	var out_size *C.McMutableBuffer
+	entropy_size := C.mc_bip39_entropy_from_mnemonic(c_mnemonic, out_size, &out_error)
+	if entropy_size < 0 {
+		return nil, mcError("mc_bip39_entropy_from_mnemonic", out_error)
+	}
+
+	out_entropy, free_out_entropy := newMutableBufferC(int(entropy_size))
+	defer free_out_entropy()
+	entropy_size = C.mc_bip39_entropy_from_mnemonic(c_mnemonic, out_entropy, &out_error)
+	if entropy_size < 0 {
+		return nil, mcError("mc_bip39_entropy_from_mnemonic", out_error)
+	}
+	return C.GoBytes(unsafe.Pointer(out_entropy.buffer), C.int(entropy_size)), nil
+}
+
+// MCBip39WordsByPrefix, the words are joined by comma in C
+func MCBip39WordsByPrefix(prefix string) ([]string, error) {
+	c_prefix := C.CString(prefix)
+	defer C.free(unsafe.Pointer(c_prefix))
+
+	c_words, err := C.mc_bip39_words_by_prefix(c_prefix)
+	if err != nil {
+		return nil, err
+	}
+	if c_words == nil {
+		return nil, errors.New("mc_bip39_words_by_prefix failure")
+	}
+	defer C.mc_string_free(c_words)
+	words := C.GoString(c_words)
+	if words == "" {
+		return nil, nil
+	}
+	return strings.Split(words, ","), nil
+}
+
+func MCSlip10AccountPrivateKeysFromMnemonic(mnemonic string, accountIndex uint32) (string, string, error) {
+	c_mnemonic := C.CString(mnemonic)
+	defer C.free(unsafe.Pointer(c_mnemonic))
+
+	out_view_private_key, free_out_view_private_key := newMutableBufferC(32)
+	defer free_out_view_private_key()
+	out_spend_private_key, free_out_spend_private_key := newMutableBufferC(32)
+	defer free_out_spend_private_key()
+
+	var out_error *C.McError
+	b := C.mc_slip10_account_private_keys_from_mnemonic(c_mnemonic, C.uint32_t(accountIndex), out_view_private_key, out_spend_private_key, &out_error)
+	if !b {
+		return "", "", mcError("mc_slip10_account_private_keys_from_mnemonic", out_error)
+	}
+	return hex.EncodeToString(mutableBufferBytes(out_view_private_key)), hex.EncodeToString(mutableBufferBytes(out_spend_private_key)), nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBip39Mnemonic(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Len(bip39English, 2048)
+	mnemonic, err := Bip39MnemonicFromEntropy(make([]byte, 16))
+	assert.Nil(err)
+	assert.Equal(strings.Repeat("abandon ", 11)+"about", mnemonic)
+	mnemonic, err = Bip39MnemonicFromEntropy(bytes.Repeat([]byte{0x7f}, 16))
+	assert.Nil(err)
+	assert.Equal("legal winner thank year wave sausage worth useful legal winner thank yellow", mnemonic)
+	mnemonic, err = Bip39MnemonicFromEntropy(bytes.Repeat([]byte{0x80}, 32))
+	assert.Nil(err)
+	assert.Equal("letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless", mnemonic)
+	entropy, err := Bip39EntropyFromMnemonic(mnemonic)
+	assert.Nil(err)
+	assert.Equal(bytes.Repeat([]byte{0x80}, 32), entropy)
+
+	_, err = Bip39MnemonicFromEntropy(make([]byte, 15))
+	assert.NotNil(err)
+	_, err = Bip39EntropyFromMnemonic(strings.Repeat("abandon ", 12))
+	assert.NotNil(err)
+	_, err = Bip39EntropyFromMnemonic(strings.Repeat("abandon ", 11) + "mobilecoin")
+	assert.NotNil(err)
+
+	mnemonic, err = NewMnemonic()
+	assert.Nil(err)
+	assert.Len(strings.Fields(mnemonic), 24)
+	entropy, err = Bip39EntropyFromMnemonic(mnemonic)
+	assert.Nil(err)
+	assert.Len(entropy, 32)
+
+	assert.Equal([]string{"abandon", "ability", "able", "about", "above", "absent", "absorb", "abstract", "absurd", "abuse"}, Bip39WordsByPrefix("ab"))
+	assert.Equal([]string{"zoo"}, Bip39WordsByPrefix("zoo"))
+	assert.Nil(Bip39WordsByPrefix("xyz"))
+}
+
+func TestSlip10AccountPrivateKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	key := slip10DeriveEd25519(seed, nil)
+	assert.Equal("2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7", hex.EncodeToString(key))
+	key = slip10DeriveEd25519(seed, []uint32{0})
+	assert.Equal("68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3", hex.EncodeToString(key))
+
+	// the ristretto keys are the hkdf-sha512 of the slip10 key salted by the
+	// domain tags and reduced modulo the group order, computed apart from
+	// this package
+	key = slip10DeriveEd25519(seed, nil)
+	private, err := slip10RistrettoPrivate(key, SLIP10_VIEW_DOMAIN_TAG)
+	assert.Nil(err)
+	assert.Equal("43e3279502e82e70e8fa7fb85b646cd88c246677745b07ce7f693c17d711dd0e", hex.EncodeToString(private.Bytes()))
+	private, err = slip10RistrettoPrivate(key, SLIP10_SPEND_DOMAIN_TAG)
+	assert.Nil(err)
+	assert.Equal("0ad4d4508f0c95f0494b891cb8f5d634fedcbee83170a77edfba1ad9ed0a0f07", hex.EncodeToString(private.Bytes()))
+
+	mnemonic := strings.Repeat("abandon ", 11) + "about"
+	view, spend, err := Slip10AccountPrivateKeysFromMnemonic(mnemonic, 0)
+	assert.Nil(err)
+	assert.Equal("bc81a3710d75c69dc627b6c0497806350bb3d27b3b58d1c299df006da900c004", view)
+	assert.Equal("94b7d9bd493e57fcc7e7199860131a0e9a744c139dd97fd753fc78738c7aa306", spend)
+	other, _, err := Slip10AccountPrivateKeysFromMnemonic(mnemonic, 1)
+	assert.Nil(err)
+	assert.NotEqual(view, other)
+
+	owner, err := SpendAccountFromMnemonic(mnemonic, 0)
+	assert.Nil(err)
+	assert.Equal(view, owner.ViewPrivateKey)
+	assert.Equal(spend, owner.SpendPrivateKey)
+	_, err = owner.accountKey()
+	assert.Nil(err)
+
+	_, _, err = Slip10AccountPrivateKeysFromMnemonic(strings.Repeat("abandon ", 12), 0)
+	assert.NotNil(err)
+}
+
+func TestBip39MnemonicC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	mnemonic, err := NewMnemonic()
+	require.Nil(err)
+	entropy, err := Bip39EntropyFromMnemonic(mnemonic)
+	require.Nil(err)
+
+	c, err := MCBip39MnemonicFromEntropy(entropy)
+	require.Nil(err)
+	require.Equal(mnemonic, c)
+	centropy, err := MCBip39EntropyFromMnemonic(mnemonic)
+	require.Nil(err)
+	require.Equal(entropy, centropy)
+	words, err := MCBip39WordsByPrefix("ab")
+	require.Nil(err)
+	require.Equal(Bip39WordsByPrefix("ab"), words)
+
+	view, spend, err := Slip10AccountPrivateKeysFromMnemonic(mnemonic, 3)
+	require.Nil(err)
+	cview, cspend, err := MCSlip10AccountPrivateKeysFromMnemonic(mnemonic, 3)
+	require.Nil(err)
+	require.Equal(view, cview)
+	require.Equal(spend, cspend)
+}
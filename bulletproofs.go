@@ -0,0 +1,728 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bwesterb/go-ristretto"
+	"github.com/gtank/merlin"
+)
+
+const (
+	RANGE_PROOF_BITSIZE = 64
+	BP_GENS_CAPACITY    = 64
+	BP_PARTY_CAPACITY   = 64
+)
+
+var (
+	bpGens     *BulletproofGens
+	bpGensOnce sync.Once
+)
+
+// BPGenerators returns the BulletproofGens::new(64, 64) used by MobileCoin range proofs
+func BPGenerators() *BulletproofGens {
+	bpGensOnce.Do(func() {
+		bpGens = NewBulletproofGens(BP_GENS_CAPACITY, BP_PARTY_CAPACITY)
+	})
+	return bpGens
+}
+
+type InnerProductProof struct {
+	LVec []*ristretto.Point
+	RVec []*ristretto.Point
+	A    *ristretto.Scalar
+	B    *ristretto.Scalar
+}
+
+type RangeProof struct {
+	A          *ristretto.Point
+	S          *ristretto.Point
+	T1         *ristretto.Point
+	T2         *ristretto.Point
+	TX         *ristretto.Scalar
+	TXBlinding *ristretto.Scalar
+	EBlinding  *ristretto.Scalar
+	IPPProof   *InnerProductProof
+}
+
+// Bytes serializes the proof as A, S, T_1, T_2, t_x, t_x_blinding, e_blinding,
+// followed by the interleaved L and R of the inner product proof, a and b.
+func (p *RangeProof) Bytes() []byte {
+	buf := make([]byte, 0, (9+2*len(p.IPPProof.LVec))*32)
+	buf = append(buf, p.A.Bytes()...)
+	buf = append(buf, p.S.Bytes()...)
+	buf = append(buf, p.T1.Bytes()...)
+	buf = append(buf, p.T2.Bytes()...)
+	buf = append(buf, p.TX.Bytes()...)
+	buf = append(buf, p.TXBlinding.Bytes()...)
+	buf = append(buf, p.EBlinding.Bytes()...)
+	for i := range p.IPPProof.LVec {
+		buf = append(buf, p.IPPProof.LVec[i].Bytes()...)
+		buf = append(buf, p.IPPProof.RVec[i].Bytes()...)
+	}
+	buf = append(buf, p.IPPProof.A.Bytes()...)
+	buf = append(buf, p.IPPProof.B.Bytes()...)
+	return buf
+}
+
+func RangeProofFromBytes(buf []byte) (*RangeProof, error) {
+	if len(buf)%32 != 0 || len(buf) < 9*32 {
+		return nil, fmt.Errorf("invalid range proof length %d", len(buf))
+	}
+	lgN := (len(buf)/32 - 9) / 2
+	if (len(buf)/32-9)%2 != 0 || lgN >= 32 {
+		return nil, fmt.Errorf("invalid range proof length %d", len(buf))
+	}
+
+	points := make([]*ristretto.Point, 4+2*lgN)
+	for i := range points {
+		offset := i * 32
+		if i >= 4 {
+			offset += 3 * 32
+		}
+		p, err := decodePoint(hex.EncodeToString(buf[offset : offset+32]))
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	scalars := make([]*ristretto.Scalar, 5)
+	for i, offset := range []int{4 * 32, 5 * 32, 6 * 32, len(buf) - 64, len(buf) - 32} {
+		s, err := decodeScalar(hex.EncodeToString(buf[offset : offset+32]))
+		if err != nil {
+			return nil, err
+		}
+		scalars[i] = s
+	}
+
+	ipp := &InnerProductProof{A: scalars[3], B: scalars[4]}
+	for i := 0; i < lgN; i++ {
+		ipp.LVec = append(ipp.LVec, points[4+2*i])
+		ipp.RVec = append(ipp.RVec, points[4+2*i+1])
+	}
+	return &RangeProof{
+		A:          points[0],
+		S:          points[1],
+		T1:         points[2],
+		T2:         points[3],
+		TX:         scalars[0],
+		TXBlinding: scalars[1],
+		EBlinding:  scalars[2],
+		IPPProof:   ipp,
+	}, nil
+}
+
+// GenerateRangeProofs proves all the values are 64 bits, the values and
+// blindings are padded to a power of two by repeating the last element.
+func GenerateRangeProofs(values []uint64, blindings []*ristretto.Scalar, pc *PedersenGens) (*RangeProof, []*ristretto.Point, error) {
+	return generateRangeProofs(rand.Reader, values, blindings, pc)
+}
+
+// generateRangeProofs draws a_blinding, s_blinding, s_L and s_R of each party
+// from rng, then t_1_blinding and t_2_blinding of each party.
+func generateRangeProofs(rng io.Reader, values []uint64, blindings []*ristretto.Scalar, pc *PedersenGens) (*RangeProof, []*ristretto.Point, error) {
+	if len(values) == 0 || len(values) != len(blindings) {
+		return nil, nil, fmt.Errorf("invalid values len %d, blindings len %d", len(values), len(blindings))
+	}
+	values = resizeUint64ToPow2(values)
+	blindings = resizeScalarToPow2(blindings)
+
+	n, m := RANGE_PROOF_BITSIZE, len(values)
+	bp := BPGenerators()
+	if m > int(bp.PartyCapacity) {
+		return nil, nil, fmt.Errorf("invalid aggregation size %d", m)
+	}
+
+	t := merlin.NewTranscript(BULLETPROOF_DOMAIN_TAG)
+	rangeproofDomainSep(uint64(n), uint64(m), t)
+
+	// Bit commitments of each party
+	commitments := make([]*ristretto.Point, m)
+	aBlindings := make([]*ristretto.Scalar, m)
+	sBlindings := make([]*ristretto.Scalar, m)
+	sLs := make([][]*ristretto.Scalar, m)
+	sRs := make([][]*ristretto.Scalar, m)
+	var A, S ristretto.Point
+	A.SetZero()
+	S.SetZero()
+	for j := 0; j < m; j++ {
+		share := bp.Share(j)
+		G, H := share.G(int64(n)), share.H(int64(n))
+		commitments[j] = pc.Commit(uint64ToScalar(values[j]), blindings[j])
+
+		aBlinding, err := randomScalar(rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		aBlindings[j] = aBlinding
+		var Aj ristretto.Point
+		Aj.ScalarMult(pc.BBlinding, aBlindings[j])
+		for i := 0; i < n; i++ {
+			if (values[j]>>i)&1 == 1 {
+				Aj.Add(&Aj, G[i])
+			} else {
+				Aj.Sub(&Aj, H[i])
+			}
+		}
+
+		sBlindings[j], err = randomScalar(rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		sLs[j], err = randomScalars(rng, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		sRs[j], err = randomScalars(rng, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		scalars := append([]*ristretto.Scalar{sBlindings[j]}, sLs[j]...)
+		scalars = append(scalars, sRs[j]...)
+		points := append([]*ristretto.Point{pc.BBlinding}, G...)
+		points = append(points, H...)
+		Sj := multiscalarMul(scalars, points)
+
+		A.Add(&A, &Aj)
+		S.Add(&S, Sj)
+	}
+
+	for _, V := range commitments {
+		AppendPoint("V", V, t)
+	}
+	AppendPoint("A", &A, t)
+	AppendPoint("S", &S, t)
+	y := ChallengeScalar("y", t)
+	z := ChallengeScalar("z", t)
+
+	// Polynomial commitments of each party
+	var zz ristretto.Scalar
+	zz.Mul(z, z)
+	lPolys := make([][2][]*ristretto.Scalar, m)
+	rPolys := make([][2][]*ristretto.Scalar, m)
+	tPolys := make([][3]*ristretto.Scalar, m)
+	t1Blindings := make([]*ristretto.Scalar, m)
+	t2Blindings := make([]*ristretto.Scalar, m)
+	offsetZZs := make([]*ristretto.Scalar, m)
+	var T1, T2 ristretto.Point
+	T1.SetZero()
+	T2.SetZero()
+	for j := 0; j < m; j++ {
+		var offsetZZ ristretto.Scalar
+		offsetZZs[j] = offsetZZ.Mul(&zz, scalarExp(z, uint64(j)))
+
+		expY := scalarExp(y, uint64(j*n))
+		var exp2 ristretto.Scalar
+		exp2.SetOne()
+		l0, l1 := make([]*ristretto.Scalar, n), make([]*ristretto.Scalar, n)
+		r0, r1 := make([]*ristretto.Scalar, n), make([]*ristretto.Scalar, n)
+		for i := 0; i < n; i++ {
+			var aL, aR, one ristretto.Scalar
+			aL.SetUint64((values[j] >> i) & 1)
+			aR.Sub(&aL, one.SetOne())
+
+			var li0, ri0, ri1, t1, t2 ristretto.Scalar
+			l0[i] = li0.Sub(&aL, z)
+			l1[i] = sLs[j][i]
+			t1.Mul(expY, t1.Add(&aR, z))
+			t2.Mul(offsetZZs[j], &exp2)
+			r0[i] = ri0.Add(&t1, &t2)
+			r1[i] = ri1.Mul(expY, sRs[j][i])
+
+			expY = new(ristretto.Scalar).Mul(expY, y)
+			exp2.Add(&exp2, &exp2)
+		}
+		lPolys[j] = [2][]*ristretto.Scalar{l0, l1}
+		rPolys[j] = [2][]*ristretto.Scalar{r0, r1}
+
+		t0 := innerProduct(l0, r0)
+		t2 := innerProduct(l1, r1)
+		var t1 ristretto.Scalar
+		t1.Sub(innerProduct(addScalars(l0, l1), addScalars(r0, r1)), t0)
+		t1.Sub(&t1, t2)
+		tPolys[j] = [3]*ristretto.Scalar{t0, &t1, t2}
+
+		t1Blinding, err := randomScalar(rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		t2Blinding, err := randomScalar(rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		t1Blindings[j] = t1Blinding
+		t2Blindings[j] = t2Blinding
+		T1.Add(&T1, pc.Commit(&t1, t1Blindings[j]))
+		T2.Add(&T2, pc.Commit(t2, t2Blindings[j]))
+	}
+
+	AppendPoint("T_1", &T1, t)
+	AppendPoint("T_2", &T2, t)
+	x := ChallengeScalar("x", t)
+
+	// Proof shares of each party
+	var tx, txBlinding, eBlinding ristretto.Scalar
+	tx.SetZero()
+	txBlinding.SetZero()
+	eBlinding.SetZero()
+	var lVec, rVec []*ristretto.Scalar
+	for j := 0; j < m; j++ {
+		var vBlinding ristretto.Scalar
+		vBlinding.Mul(offsetZZs[j], blindings[j])
+		tx.Add(&tx, evalPoly2(tPolys[j][0], tPolys[j][1], tPolys[j][2], x))
+		txBlinding.Add(&txBlinding, evalPoly2(&vBlinding, t1Blindings[j], t2Blindings[j], x))
+
+		var e ristretto.Scalar
+		e.Mul(sBlindings[j], x)
+		e.Add(&e, aBlindings[j])
+		eBlinding.Add(&eBlinding, &e)
+
+		for i := 0; i < n; i++ {
+			var l, r ristretto.Scalar
+			l.Mul(lPolys[j][1][i], x)
+			lVec = append(lVec, l.Add(&l, lPolys[j][0][i]))
+			r.Mul(rPolys[j][1][i], x)
+			rVec = append(rVec, r.Add(&r, rPolys[j][0][i]))
+		}
+	}
+
+	AppendScalar("t_x", &tx, t)
+	AppendScalar("t_x_blinding", &txBlinding, t)
+	AppendScalar("e_blinding", &eBlinding, t)
+	w := ChallengeScalar("w", t)
+	var Q ristretto.Point
+	Q.ScalarMult(pc.B, w)
+
+	var yInv ristretto.Scalar
+	yInv.Inverse(y)
+	gFactors := make([]*ristretto.Scalar, n*m)
+	for i := range gFactors {
+		var one ristretto.Scalar
+		gFactors[i] = one.SetOne()
+	}
+	hFactors := expScalars(&yInv, n*m)
+
+	ipp := createInnerProductProof(t, &Q, gFactors, hFactors, collectGens(bp.G(int64(n), int64(m))), collectGens(bp.H(int64(n), int64(m))), lVec, rVec)
+
+	return &RangeProof{
+		A:          &A,
+		S:          &S,
+		T1:         &T1,
+		T2:         &T2,
+		TX:         &tx,
+		TXBlinding: &txBlinding,
+		EBlinding:  &eBlinding,
+		IPPProof:   ipp,
+	}, commitments, nil
+}
+
+// CheckRangeProofs verifies the proof against the commitments, which are
+// padded to a power of two the same way as GenerateRangeProofs.
+func CheckRangeProofs(proof *RangeProof, commitments []*ristretto.Point, pc *PedersenGens) error {
+	if len(commitments) == 0 {
+		return errors.New("empty commitments")
+	}
+	for len(commitments) < nextPowerOfTwo(len(commitments)) {
+		commitments = append(commitments, commitments[len(commitments)-1])
+	}
+
+	n, m := RANGE_PROOF_BITSIZE, len(commitments)
+	bp := BPGenerators()
+	if m > int(bp.PartyCapacity) {
+		return fmt.Errorf("invalid aggregation size %d", m)
+	}
+
+	t := merlin.NewTranscript(BULLETPROOF_DOMAIN_TAG)
+	rangeproofDomainSep(uint64(n), uint64(m), t)
+	for _, V := range commitments {
+		AppendPoint("V", V, t)
+	}
+	for _, p := range []struct {
+		label string
+		point *ristretto.Point
+	}{{"A", proof.A}, {"S", proof.S}} {
+		err := validateAndAppendPoint(p.label, p.point, t)
+		if err != nil {
+			return err
+		}
+	}
+	y := ChallengeScalar("y", t)
+	z := ChallengeScalar("z", t)
+	var zz, minusZ ristretto.Scalar
+	zz.Mul(z, z)
+	minusZ.Neg(z)
+
+	err := validateAndAppendPoint("T_1", proof.T1, t)
+	if err != nil {
+		return err
+	}
+	err = validateAndAppendPoint("T_2", proof.T2, t)
+	if err != nil {
+		return err
+	}
+	x := ChallengeScalar("x", t)
+
+	AppendScalar("t_x", proof.TX, t)
+	AppendScalar("t_x_blinding", proof.TXBlinding, t)
+	AppendScalar("e_blinding", proof.EBlinding, t)
+	w := ChallengeScalar("w", t)
+
+	// Challenge value for batching statements to be verified
+	var c ristretto.Scalar
+	c.Rand()
+
+	xSq, xInvSq, s, err := proof.IPPProof.verificationScalars(n*m, t)
+	if err != nil {
+		return err
+	}
+	a, b := proof.IPPProof.A, proof.IPPProof.B
+
+	var two ristretto.Scalar
+	two.SetUint64(2)
+	powersOf2 := expScalars(&two, n)
+	var concatZAnd2 []*ristretto.Scalar
+	for _, expZ := range expScalars(z, m) {
+		for _, exp2 := range powersOf2 {
+			var s ristretto.Scalar
+			concatZAnd2 = append(concatZAnd2, s.Mul(exp2, expZ))
+		}
+	}
+
+	var yInv ristretto.Scalar
+	yInv.Inverse(y)
+	expYInv := expScalars(&yInv, n*m)
+
+	var scalars []*ristretto.Scalar
+	var points []*ristretto.Point
+
+	var one, cx, cxx, eScalar, basepointScalar, tmp ristretto.Scalar
+	cx.Mul(&c, x)
+	cxx.Mul(&cx, x)
+	eScalar.Mul(&c, proof.TXBlinding)
+	eScalar.Neg(eScalar.Add(&eScalar, proof.EBlinding))
+	basepointScalar.Mul(w, basepointScalar.Sub(proof.TX, tmp.Mul(a, b)))
+	tmp.Sub(delta(n, m, y, z), proof.TX)
+	basepointScalar.Add(&basepointScalar, tmp.Mul(&c, &tmp))
+
+	scalars = append(scalars, one.SetOne(), x, &cx, &cxx)
+	points = append(points, proof.A, proof.S, proof.T1, proof.T2)
+	scalars = append(scalars, xSq...)
+	points = append(points, proof.IPPProof.LVec...)
+	scalars = append(scalars, xInvSq...)
+	points = append(points, proof.IPPProof.RVec...)
+	scalars = append(scalars, &eScalar, &basepointScalar)
+	points = append(points, pc.BBlinding, pc.B)
+
+	for _, si := range s {
+		var g ristretto.Scalar
+		g.Sub(&minusZ, g.Mul(a, si))
+		scalars = append(scalars, &g)
+	}
+	points = append(points, collectGens(bp.G(int64(n), int64(m)))...)
+
+	for i := range s {
+		var h, t ristretto.Scalar
+		t.Mul(&zz, concatZAnd2[i])
+		t.Sub(&t, h.Mul(b, s[len(s)-1-i]))
+		h.Mul(expYInv[i], &t)
+		h.Add(&h, z)
+		scalars = append(scalars, &h)
+	}
+	points = append(points, collectGens(bp.H(int64(n), int64(m)))...)
+
+	for i, zExp := range expScalars(z, m) {
+		var v ristretto.Scalar
+		v.Mul(&c, &zz)
+		scalars = append(scalars, v.Mul(&v, zExp))
+		points = append(points, commitments[i])
+	}
+
+	check := publicMultiscalarMul(scalars, points)
+	var identity ristretto.Point
+	if !check.Equals(identity.SetZero()) {
+		return errors.New("invalid range proof")
+	}
+	return nil
+}
+
+// CheckRangeProofsBytes verifies the range_proofs bytes of SignatureRctBulletproofs
+func CheckRangeProofsBytes(rangeProofs string, commitments []string, tokenID uint64) error {
+	buf, err := hex.DecodeString(rangeProofs)
+	if err != nil {
+		return err
+	}
+	proof, err := RangeProofFromBytes(buf)
+	if err != nil {
+		return err
+	}
+	points := make([]*ristretto.Point, len(commitments))
+	for i, c := range commitments {
+		points[i], err = decodePoint(c)
+		if err != nil {
+			return fmt.Errorf("invalid commitment %s: %v", c, err)
+		}
+	}
+	return CheckRangeProofs(proof, points, NewPedersenGensForToken(tokenID))
+}
+
+func createInnerProductProof(t *merlin.Transcript, Q *ristretto.Point, gFactors, hFactors []*ristretto.Scalar, G, H []*ristretto.Point, a, b []*ristretto.Scalar) *InnerProductProof {
+	n := len(G)
+	InnerproductDomainSep(uint64(n), t)
+
+	proof := &InnerProductProof{}
+	first := true
+	for n != 1 {
+		n = n / 2
+		aL, aR := a[:n], a[n:]
+		bL, bR := b[:n], b[n:]
+		GL, GR := G[:n], G[n:]
+		HL, HR := H[:n], H[n:]
+
+		cL := innerProduct(aL, bR)
+		cR := innerProduct(aR, bL)
+
+		var lScalars, rScalars []*ristretto.Scalar
+		if first {
+			for i := 0; i < n; i++ {
+				var s ristretto.Scalar
+				lScalars = append(lScalars, s.Mul(aL[i], gFactors[n+i]))
+			}
+			for i := 0; i < n; i++ {
+				var s ristretto.Scalar
+				lScalars = append(lScalars, s.Mul(bR[i], hFactors[i]))
+			}
+			for i := 0; i < n; i++ {
+				var s ristretto.Scalar
+				rScalars = append(rScalars, s.Mul(aR[i], gFactors[i]))
+			}
+			for i := 0; i < n; i++ {
+				var s ristretto.Scalar
+				rScalars = append(rScalars, s.Mul(bL[i], hFactors[n+i]))
+			}
+		} else {
+			lScalars = append(append(lScalars, aL...), bR...)
+			rScalars = append(append(rScalars, aR...), bL...)
+		}
+		lScalars = append(lScalars, cL)
+		rScalars = append(rScalars, cR)
+		L := multiscalarMul(lScalars, append(append(append([]*ristretto.Point{}, GR...), HL...), Q))
+		R := multiscalarMul(rScalars, append(append(append([]*ristretto.Point{}, GL...), HR...), Q))
+		proof.LVec = append(proof.LVec, L)
+		proof.RVec = append(proof.RVec, R)
+
+		AppendPoint("L", L, t)
+		AppendPoint("R", R, t)
+		u := ChallengeScalar("u", t)
+		var uInv ristretto.Scalar
+		uInv.Inverse(u)
+
+		nextA := make([]*ristretto.Scalar, n)
+		nextB := make([]*ristretto.Scalar, n)
+		nextG := make([]*ristretto.Point, n)
+		nextH := make([]*ristretto.Point, n)
+		for i := 0; i < n; i++ {
+			var ai, bi, t ristretto.Scalar
+			ai.Mul(aL[i], u)
+			nextA[i] = ai.Add(&ai, t.Mul(&uInv, aR[i]))
+			bi.Mul(bL[i], &uInv)
+			nextB[i] = bi.Add(&bi, t.Mul(u, bR[i]))
+
+			gL, gR, hL, hR := &uInv, u, u, &uInv
+			if first {
+				var s0, s1, s2, s3 ristretto.Scalar
+				gL, gR = s0.Mul(&uInv, gFactors[i]), s1.Mul(u, gFactors[n+i])
+				hL, hR = s2.Mul(u, hFactors[i]), s3.Mul(&uInv, hFactors[n+i])
+			}
+			nextG[i] = multiscalarMul([]*ristretto.Scalar{gL, gR}, []*ristretto.Point{GL[i], GR[i]})
+			nextH[i] = multiscalarMul([]*ristretto.Scalar{hL, hR}, []*ristretto.Point{HL[i], HR[i]})
+		}
+		a, b, G, H = nextA, nextB, nextG, nextH
+		first = false
+	}
+
+	proof.A = a[0]
+	proof.B = b[0]
+	return proof
+}
+
+// verificationScalars returns the squares of the challenges u_i, of their
+// inverses, and the s vector of the inner product verification.
+func (ipp *InnerProductProof) verificationScalars(n int, t *merlin.Transcript) ([]*ristretto.Scalar, []*ristretto.Scalar, []*ristretto.Scalar, error) {
+	lgN := len(ipp.LVec)
+	if lgN >= 32 || len(ipp.RVec) != lgN {
+		return nil, nil, nil, errors.New("invalid inner product proof")
+	}
+	if n != 1<<lgN {
+		return nil, nil, nil, fmt.Errorf("invalid inner product proof size %d, expected %d", 1<<lgN, n)
+	}
+
+	InnerproductDomainSep(uint64(n), t)
+	challenges := make([]*ristretto.Scalar, lgN)
+	for i := range ipp.LVec {
+		err := validateAndAppendPoint("L", ipp.LVec[i], t)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = validateAndAppendPoint("R", ipp.RVec[i], t)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		challenges[i] = ChallengeScalar("u", t)
+	}
+
+	var allInv ristretto.Scalar
+	allInv.SetOne()
+	challengesSq := make([]*ristretto.Scalar, lgN)
+	challengesInvSq := make([]*ristretto.Scalar, lgN)
+	for i, u := range challenges {
+		var uInv, sq, invSq ristretto.Scalar
+		uInv.Inverse(u)
+		allInv.Mul(&allInv, &uInv)
+		challengesSq[i] = sq.Mul(u, u)
+		challengesInvSq[i] = invSq.Mul(&uInv, &uInv)
+	}
+
+	s := make([]*ristretto.Scalar, n)
+	s[0] = &allInv
+	for i := 1; i < n; i++ {
+		lgI := 0
+		for (1 << (lgI + 1)) <= i {
+			lgI++
+		}
+		k := 1 << lgI
+		var si ristretto.Scalar
+		s[i] = si.Mul(s[i-k], challengesSq[(lgN-1)-lgI])
+	}
+	return challengesSq, challengesInvSq, s, nil
+}
+
+// delta(y, z) = (z - z^2) * <1, y^(n*m)> - z^3 * <1, 2^n> * <1, z^m>
+func delta(n, m int, y, z *ristretto.Scalar) *ristretto.Scalar {
+	var two ristretto.Scalar
+	two.SetUint64(2)
+	sumY := sumOfPowers(y, n*m)
+	sum2 := sumOfPowers(&two, n)
+	sumZ := sumOfPowers(z, m)
+
+	var zz, zzz, r, t ristretto.Scalar
+	zz.Mul(z, z)
+	zzz.Mul(&zz, z)
+	r.Sub(z, &zz)
+	r.Mul(&r, sumY)
+	t.Mul(&zzz, sum2)
+	t.Mul(&t, sumZ)
+	return r.Sub(&r, &t)
+}
+
+func rangeproofDomainSep(n, m uint64, t *merlin.Transcript) {
+	appendBytes([]byte("dom-sep"), []byte("rangeproof v1"), t)
+	appendInt64("n", n, t)
+	appendInt64("m", m, t)
+}
+
+func validateAndAppendPoint(label string, p *ristretto.Point, t *merlin.Transcript) error {
+	var identity ristretto.Point
+	if p.Equals(identity.SetZero()) {
+		return fmt.Errorf("identity point %s", label)
+	}
+	AppendPoint(label, p, t)
+	return nil
+}
+
+func publicMultiscalarMul(scalars []*ristretto.Scalar, points []*ristretto.Point) *ristretto.Point {
+	var p ristretto.Point
+	p.SetZero()
+	for i := range scalars {
+		var t ristretto.Point
+		t.PublicScalarMult(points[i], scalars[i])
+		p.Add(&p, &t)
+	}
+	return &p
+}
+
+func innerProduct(a, b []*ristretto.Scalar) *ristretto.Scalar {
+	var r ristretto.Scalar
+	r.SetZero()
+	for i := range a {
+		var t ristretto.Scalar
+		r.Add(&r, t.Mul(a[i], b[i]))
+	}
+	return &r
+}
+
+func addScalars(a, b []*ristretto.Scalar) []*ristretto.Scalar {
+	r := make([]*ristretto.Scalar, len(a))
+	for i := range a {
+		var s ristretto.Scalar
+		r[i] = s.Add(a[i], b[i])
+	}
+	return r
+}
+
+// evalPoly2 evaluates t0 + t1 * x + t2 * x^2
+func evalPoly2(t0, t1, t2, x *ristretto.Scalar) *ristretto.Scalar {
+	var r ristretto.Scalar
+	r.Mul(t2, x)
+	r.Add(&r, t1)
+	r.Mul(&r, x)
+	return r.Add(&r, t0)
+}
+
+// expScalars returns 1, x, x^2, ..., x^(n-1)
+func expScalars(x *ristretto.Scalar, n int) []*ristretto.Scalar {
+	r := make([]*ristretto.Scalar, n)
+	var exp ristretto.Scalar
+	exp.SetOne()
+	for i := 0; i < n; i++ {
+		var s ristretto.Scalar
+		r[i] = s.Set(&exp)
+		exp.Mul(&exp, x)
+	}
+	return r
+}
+
+func scalarExp(x *ristretto.Scalar, n uint64) *ristretto.Scalar {
+	var r, base ristretto.Scalar
+	r.SetOne()
+	base.Set(x)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r.Mul(&r, &base)
+		}
+		base.Mul(&base, &base)
+	}
+	return &r
+}
+
+func sumOfPowers(x *ristretto.Scalar, n int) *ristretto.Scalar {
+	var r ristretto.Scalar
+	r.SetZero()
+	for _, s := range expScalars(x, n) {
+		r.Add(&r, s)
+	}
+	return &r
+}
+
+func randomScalars(rng io.Reader, n int) ([]*ristretto.Scalar, error) {
+	r := make([]*ristretto.Scalar, n)
+	for i := range r {
+		s, err := randomScalar(rng)
+		if err != nil {
+			return nil, err
+		}
+		r[i] = s
+	}
+	return r, nil
+}
+
+func collectGens(iter *AggregatedGensIter) []*ristretto.Point {
+	var points []*ristretto.Point
+	for p := iter.Next(); p != nil; p = iter.Next() {
+		points = append(points, p)
+	}
+	return points
+}
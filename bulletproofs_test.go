@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeProofs(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tokenID := range []uint64{0, 1} {
+		generators := NewPedersenGensForToken(tokenID)
+		values := []uint64{0, 12 * MILLIMOB_TO_PICOMOB, MOB_MINIMUM_FEE}
+		blindings, err := randomScalars(rand.Reader, len(values))
+		assert.Nil(err)
+
+		proof, commitments, err := GenerateRangeProofs(values, blindings, generators)
+		assert.Nil(err)
+		assert.Len(commitments, 4)
+		assert.Len(proof.IPPProof.LVec, 8)
+		assert.Nil(CheckRangeProofs(proof, commitments[:len(values)], generators))
+
+		buf := proof.Bytes()
+		assert.Len(buf, (9+2*8)*32)
+		decoded, err := RangeProofFromBytes(buf)
+		assert.Nil(err)
+		assert.Equal(buf, decoded.Bytes())
+
+		hexCommitments := make([]string, len(values))
+		for i := range values {
+			hexCommitments[i] = hex.EncodeToString(commitments[i].Bytes())
+		}
+		assert.Nil(CheckRangeProofsBytes(hex.EncodeToString(buf), hexCommitments, tokenID))
+		assert.NotNil(CheckRangeProofsBytes(hex.EncodeToString(buf), hexCommitments, tokenID+2))
+		assert.NotNil(CheckRangeProofsBytes(hex.EncodeToString(buf), hexCommitments[:2], tokenID))
+
+		var other ristretto.Scalar
+		other.Rand()
+		hexCommitments[1] = hex.EncodeToString(generators.Commit(uint64ToScalar(values[1]), &other).Bytes())
+		assert.NotNil(CheckRangeProofsBytes(hex.EncodeToString(buf), hexCommitments, tokenID))
+	}
+
+	_, err := RangeProofFromBytes(make([]byte, 10*32))
+	assert.NotNil(err)
+}
+
+// The vectors are computed apart from this package, the generators with
+// curve25519-dalek and the sha3 and shake256 of python, the proof with dalek
+// points, python scalars and a merlin transcript checked against the merlin
+// test vector. The nonces follow the draw order of generateRangeProofs, not
+// the transcript rng of dalek bulletproofs, so libmobilecoin proofs differ.
+func TestRangeProofsVector(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("806024d8f41f25e280a0ca45fa2fa28c0529f7b0cbaf182dc195fa23cc58c82b", hex.EncodeToString(NewPedersenGens().B.Bytes()))
+	assert.Equal("6020c8e47c883cea03ffb8fe9206e488b944714ea4c4d150933d37dfb946f55f", hex.EncodeToString(NewPedersenGensForToken(1).B.Bytes()))
+	assert.Equal("8c9240b456a9e6dc65c377a1048d745f94a08cdb7f44cbcd7b46f34048871134", hex.EncodeToString(DefaultPedersenGens().BBlinding.Bytes()))
+	bp := BPGenerators()
+	assert.Equal("fc3b25801422672a6a8d3adb5d8457d4301fe92324b4fc56ae934c8713ddfe2d", hex.EncodeToString(bp.GVec[0][0].Bytes()))
+	assert.Equal("ba698f6dd08c501e32b55d2ee7259f6019d629fa2ba4d7039c5de157cba4df73", hex.EncodeToString(bp.HVec[0][0].Bytes()))
+	assert.Equal("0e03f8c88adc4c00eeedcab230661f3ab74955d28886dffc82f4dbd8434c7979", hex.EncodeToString(bp.GVec[1][63].Bytes()))
+	assert.Equal("5c7940f0ded93ecec045aff8c17de16eed310eaa5b906b6a24daacb386045805", hex.EncodeToString(bp.HVec[1][63].Bytes()))
+
+	values := []uint64{12 * MILLIMOB_TO_PICOMOB, MOB_MINIMUM_FEE}
+	blindings := []*ristretto.Scalar{
+		hexToScalar("b382b717fb6be850b61f3cedc4d47d9f2f172bf927cecbe5bbe2ac288e8ad308"),
+		hexToScalar("afc86afc480aadffe647b15a920058eada66260ba03e5755f20e2b30e75ef60c"),
+	}
+	nonces := &nonceReader{label: []byte("mobilecoin range proof nonce")}
+	proof, commitments, err := generateRangeProofs(nonces, values, blindings, NewPedersenGens())
+	assert.Nil(err)
+	assert.Equal("408b056b7de22dc4c2decabcc4d9ee974ede125b959ee98f58fc157368ce3e32", hex.EncodeToString(commitments[0].Bytes()))
+	assert.Equal("f6f83540e9769f875f75b84e4052dc99e25da3e6ea8996085b087080bbbfc06b", hex.EncodeToString(commitments[1].Bytes()))
+
+	rangeProofs := "" +
+		"283f01d14bb6d3deeea2b43ba963d62142362d8810d2026d7a73a807b3c7db69" +
+		"420fc37ae82f2e44c40fec4c24953fbac4253a92c5e23016720e36de74c49030" +
+		"0acefe0c172e2f29ff482595b2a468d396302dcb424b5dea2ec0d344653d395c" +
+		"906539fba5d190addcdfa4cd3b3153aa608dc61fb4a3f517510c1ee9de744b15" +
+		"f2cb9e72be34cef37d09b14e1dd0519976a9e3f91e60b70c0b2c8a39f0bc4b03" +
+		"c8343091a575c9f8c4b72e9a28682b48c8eb4c192561ae28c7f9dd1e36bf4b0f" +
+		"387369c86a2402df2789108d9da0174479e0401b3a14a9bef4b4387301ae0d0f" +
+		"b8979d249867d7661bfe8380bdd165c144edc40fb7a61e22ab9d50d43b546974" +
+		"ba5590562873262bd74cbc5affa7b81f9c764d5edb6bc0adb20e6211836e3a56" +
+		"64e440fab5f6f3994da25ec20ecc099eff02b5242c625592f99cd91f111c3d04" +
+		"90c6e1c9471b7e821855a61dc2e92dd2825f42894836b6be7d0e38d798c2d03f" +
+		"58c1d2305240d6d9ab3333d7e672e2422a7080ec4a1f04312867f54c0a434d4a" +
+		"b8396f07b9a2f1525c75d3c60ee35aedc4aa2a3efa86398387eda1deecf77f5c" +
+		"7ed5811459d43e125ae1b8f7417ca6410425c2d783fc1c70c57fd8521ef2730d" +
+		"62207a4325f6f93628601ed595767f2c4abe602ef4b1dbf3272db8889a64324c" +
+		"6adc6ed66793d4b2b722e6a118a439a4e233907de306e268079fa876c8edf847" +
+		"a0babf827423b5ccadbe2f28328f2c8eb06e307bfc6bc19bf2bbcbce5c6c3a41" +
+		"6668aa14e021002a9c276bdafd0807ed5739ef967a0ab95e3e4881843d58fe7f" +
+		"58e8e640e85da105dbbfa5efcece976712ff7f12024a39858192ef2e69f94e79" +
+		"da79db5395e8597565d2b1643226b1ecee7de64c1451d8f3da7286b5e017f73c" +
+		"18ab9b91fc14438db89ced5519a4ac3718cdfa9a264675412f8223dea874656e" +
+		"c9633429e96381e92fcfc0557c285761ebdae4c8f28943c21635fff149ad4709" +
+		"48bad49328f0fb9c9bb0c44b43bce80056bcd893eb837893d2aa08495b0d9b0b"
+	assert.Equal(rangeProofs, hex.EncodeToString(proof.Bytes()))
+	hexCommitments := []string{hex.EncodeToString(commitments[0].Bytes()), hex.EncodeToString(commitments[1].Bytes())}
+	assert.Nil(CheckRangeProofsBytes(rangeProofs, hexCommitments, 0))
+	assert.NotNil(CheckRangeProofsBytes(rangeProofs, []string{hexCommitments[1], hexCommitments[0]}, 0))
+}
+
+// TestRangeProofsC verifies the range proofs of the transactions built by
+// libmobilecoin, the range_proof_bytes before block version 3 and the
+// range_proofs of each token after, against their commitments.
+func TestRangeProofsC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	address, err := acc.B58Code(0)
+	require.Nil(err)
+
+	for _, version := range []uint{2, 3} {
+		inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{2 * MILLIMOB_TO_PICOMOB, 3 * MILLIMOB_TO_PICOMOB, 4 * MILLIMOB_TO_PICOMOB}, 0)
+		outlays := []*PaymentOutlay{{Address: address, Amount: MILLIMOB_TO_PICOMOB}}
+		output, err := TransactionBuilderBuildOutlays(context.Background(), inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, version, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+		require.Nil(err)
+		tx, err := decodeTestTx(output.RawTransaction)
+		require.Nil(err)
+
+		commitments := append([]string{}, tx.Signature.PseudoOutputCommitments...)
+		for _, out := range tx.Prefix.Outputs {
+			commitments = append(commitments, out.Amount.Commitment)
+		}
+		rangeProofs := tx.Signature.RangeProofs
+		if version >= 3 {
+			require.Empty(rangeProofs)
+			require.Len(tx.Signature.TokenRangeProofs, 1)
+			require.Equal([]uint64{0, 0, 0}, tx.Signature.PseudoOutputTokenIDs)
+			require.Equal([]uint64{0, 0}, tx.Signature.OutputTokenIDs)
+			rangeProofs = tx.Signature.TokenRangeProofs[0]
+		} else {
+			require.Empty(tx.Signature.TokenRangeProofs)
+		}
+
+		buf, err := hex.DecodeString(rangeProofs)
+		require.Nil(err)
+		proof, err := RangeProofFromBytes(buf)
+		require.Nil(err)
+		require.Equal(buf, proof.Bytes())
+		require.Len(proof.IPPProof.LVec, 9)
+		require.Nil(CheckRangeProofsBytes(rangeProofs, commitments, 0))
+		require.NotNil(CheckRangeProofsBytes(rangeProofs, commitments, 1))
+		commitments[0], commitments[1] = commitments[1], commitments[0]
+		require.NotNil(CheckRangeProofsBytes(rangeProofs, commitments, 0))
+	}
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+const (
+	COIN_SELECTION_LARGEST_FIRST = iota
+	COIN_SELECTION_SMALLEST_FIRST
+	COIN_SELECTION_BRANCH_AND_BOUND
+	COIN_SELECTION_RANDOM
+
+	coinSelectionBranchAndBoundTries = 100000
+	coinSelectionRandomTries         = 32
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrTooManyDustInputs = errors.New("too many dust inputs")
+)
+
+// FeePolicy is the fixed fee of a transaction, the change below MinimumChange
+// is added to the fee instead of creating a change output.
+type FeePolicy struct {
+	Fee           uint64
+	MinimumChange uint64
+}
+
+type CoinSelection struct {
+	Inputs []*UTXO
+	Amount uint64
+	Fee    uint64
+	Change uint64
+}
+
+// DefaultFeePolicy returns the policy of the tokens which have a known minimum
+// fee, the eUSD change below its fee costs more to spend than it is worth.
+func DefaultFeePolicy(tokenID uint) (*FeePolicy, error) {
+	switch tokenID {
+	case 0:
+		return &FeePolicy{Fee: MOB_MINIMUM_FEE, MinimumChange: MILLIMOB_TO_PICOMOB}, nil
+	case EUSD_TOKEN_ID:
+		return &FeePolicy{Fee: EUSD_MINIMUM_FEE, MinimumChange: EUSD_MINIMUM_FEE}, nil
+	default:
+		return nil, fmt.Errorf("no default fee policy for token %d", tokenID)
+	}
+}
+
+// checkChangeAmount rejects the change below the MinimumChange of the default
+// policy of the token, which SelectCoins would have added to the fee, and the
+// tokens without a default policy.
+func checkChangeAmount(changeAmount uint64, tokenID uint) error {
+	policy, err := DefaultFeePolicy(tokenID)
+	if err != nil {
+		return err
+	}
+	if changeAmount > 0 && changeAmount < policy.MinimumChange {
+		return fmt.Errorf("change amount %d of token %d below minimum %d", changeAmount, tokenID, policy.MinimumChange)
+	}
+	return nil
+}
+
+// SelectCoins picks at most MAX_INPUTS utxos of the token from pool to pay amount
+// and the fee, the utxos of other tokens are ignored. The pool total below amount
+// and fee is ErrInsufficientFunds, while a pool which can only pay with more than
+// MAX_INPUTS utxos is ErrTooManyDustInputs.
+func SelectCoins(pool []*UTXO, amount uint64, tokenID uint, policy *FeePolicy, strategy int) (*CoinSelection, error) {
+	if amount == 0 {
+		return nil, errors.New("invalid amount 0")
+	}
+	if policy == nil {
+		p, err := DefaultFeePolicy(tokenID)
+		if err != nil {
+			return nil, err
+		}
+		policy = p
+	}
+	target := amount + policy.Fee
+	if target < amount {
+		return nil, fmt.Errorf("invalid amount %d and fee %d", amount, policy.Fee)
+	}
+
+	pool = slices.DeleteFunc(slices.Clone(pool), func(utxo *UTXO) bool {
+		return utxo.TokenID != tokenID
+	})
+	var total uint64
+	for _, utxo := range pool {
+		total += utxo.Amount
+	}
+	if total < target {
+		return nil, fmt.Errorf("%w: %d, required %d", ErrInsufficientFunds, total, target)
+	}
+
+	sorted := slices.Clone(pool)
+	slices.SortStableFunc(sorted, func(a, b *UTXO) int {
+		switch {
+		case a.Amount > b.Amount:
+			return -1
+		case a.Amount < b.Amount:
+			return 1
+		}
+		return 0
+	})
+	limit := min(len(sorted), MAX_INPUTS)
+	var largest uint64
+	for _, utxo := range sorted[:limit] {
+		largest += utxo.Amount
+	}
+	if largest < target {
+		return nil, fmt.Errorf("%w: %d in %d inputs, required %d", ErrTooManyDustInputs, largest, MAX_INPUTS, target)
+	}
+
+	var inputs []*UTXO
+	switch strategy {
+	case COIN_SELECTION_LARGEST_FIRST:
+		inputs = selectLargestFirst(sorted, target)
+	case COIN_SELECTION_SMALLEST_FIRST:
+		inputs = selectSmallestFirst(sorted, target)
+	case COIN_SELECTION_BRANCH_AND_BOUND:
+		inputs = selectBranchAndBound(sorted, target, policy.MinimumChange)
+		if inputs == nil {
+			inputs = selectLargestFirst(sorted, target)
+		}
+	case COIN_SELECTION_RANDOM:
+		selected, err := selectRandom(pool, target)
+		if err != nil {
+			return nil, err
+		}
+		inputs = selected
+		if inputs == nil {
+			inputs = selectLargestFirst(sorted, target)
+		}
+	default:
+		return nil, fmt.Errorf("invalid coin selection strategy %d", strategy)
+	}
+	return newCoinSelection(inputs, amount, policy), nil
+}
+
+func newCoinSelection(inputs []*UTXO, amount uint64, policy *FeePolicy) *CoinSelection {
+	var total uint64
+	for _, utxo := range inputs {
+		total += utxo.Amount
+	}
+	fee := policy.Fee
+	change := total - amount - fee
+	if change < policy.MinimumChange {
+		fee += change
+		change = 0
+	}
+	return &CoinSelection{
+		Inputs: inputs,
+		Amount: amount,
+		Fee:    fee,
+		Change: change,
+	}
+}
+
+// sorted is in descending order
+func selectLargestFirst(sorted []*UTXO, target uint64) []*UTXO {
+	var sum uint64
+	for i, utxo := range sorted {
+		sum += utxo.Amount
+		if sum >= target {
+			return slices.Clone(sorted[:i+1])
+		}
+	}
+	return nil
+}
+
+// Spend the smallest utxos first, and slide the window to the larger ones
+// when it is full of MAX_INPUTS dust.
+func selectSmallestFirst(sorted []*UTXO, target uint64) []*UTXO {
+	ascending := slices.Clone(sorted)
+	slices.Reverse(ascending)
+
+	var sum uint64
+	start := 0
+	for i, utxo := range ascending {
+		sum += utxo.Amount
+		if i-start+1 > MAX_INPUTS {
+			sum -= ascending[start].Amount
+			start += 1
+		}
+		if sum >= target {
+			return slices.Clone(ascending[start : i+1])
+		}
+	}
+	return nil
+}
+
+// Search for the inputs which pay target without a change output, i.e. the
+// excess is below minimumChange and goes to the fee.
+func selectBranchAndBound(sorted []*UTXO, target, minimumChange uint64) []*UTXO {
+	upper := target + minimumChange
+	remaining := make([]uint64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Amount
+	}
+
+	tries := 0
+	var selected []int
+	var search func(i int, sum uint64) bool
+	search = func(i int, sum uint64) bool {
+		tries += 1
+		if tries > coinSelectionBranchAndBoundTries {
+			return false
+		}
+		if sum >= target {
+			return sum < upper
+		}
+		if i == len(sorted) || len(selected) == MAX_INPUTS || sum+remaining[i] < target {
+			return false
+		}
+		selected = append(selected, i)
+		if search(i+1, sum+sorted[i].Amount) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+		return search(i+1, sum)
+	}
+	if !search(0, 0) {
+		return nil
+	}
+
+	inputs := make([]*UTXO, len(selected))
+	for i, j := range selected {
+		inputs[i] = sorted[j]
+	}
+	return inputs
+}
+
+// Shuffle the pool with crypto/rand, so the chosen inputs do not reveal
+// the wallet ordering of the utxos.
+func selectRandom(pool []*UTXO, target uint64) ([]*UTXO, error) {
+	shuffled := slices.Clone(pool)
+	for try := 0; try < coinSelectionRandomTries; try++ {
+		for i := len(shuffled) - 1; i > 0; i-- {
+			j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+			if err != nil {
+				return nil, err
+			}
+			shuffled[i], shuffled[j.Int64()] = shuffled[j.Int64()], shuffled[i]
+		}
+		var sum uint64
+		for i, utxo := range shuffled[:min(len(shuffled), MAX_INPUTS)] {
+			sum += utxo.Amount
+			if sum >= target {
+				return slices.Clone(shuffled[:i+1]), nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectCoins(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := []*UTXO{
+		{Amount: 3 * MILLIMOB_TO_PICOMOB},
+		{Amount: 10 * MILLIMOB_TO_PICOMOB},
+		{Amount: 1 * MILLIMOB_TO_PICOMOB},
+		{Amount: 6 * MILLIMOB_TO_PICOMOB},
+	}
+	amount := uint64(4 * MILLIMOB_TO_PICOMOB)
+
+	selection, err := SelectCoins(pool, amount, 0, nil, COIN_SELECTION_LARGEST_FIRST)
+	assert.Nil(err)
+	assert.Len(selection.Inputs, 1)
+	assert.Equal(uint64(10*MILLIMOB_TO_PICOMOB), selection.Inputs[0].Amount)
+	assert.Equal(uint64(MOB_MINIMUM_FEE), selection.Fee)
+	assert.Equal(10*MILLIMOB_TO_PICOMOB-amount-MOB_MINIMUM_FEE, selection.Change)
+
+	selection, err = SelectCoins(pool, amount, 0, nil, COIN_SELECTION_SMALLEST_FIRST)
+	assert.Nil(err)
+	assert.Len(selection.Inputs, 3)
+	assert.Equal(uint64(1*MILLIMOB_TO_PICOMOB), selection.Inputs[0].Amount)
+
+	// 6 leaves 1.6 millimob below the minimum change, so no change output
+	policy := &FeePolicy{Fee: MOB_MINIMUM_FEE, MinimumChange: 2 * MILLIMOB_TO_PICOMOB}
+	selection, err = SelectCoins(pool, amount, 0, policy, COIN_SELECTION_BRANCH_AND_BOUND)
+	assert.Nil(err)
+	assert.Len(selection.Inputs, 1)
+	assert.Equal(uint64(6*MILLIMOB_TO_PICOMOB), selection.Inputs[0].Amount)
+	assert.Equal(uint64(0), selection.Change)
+	assert.Equal(6*MILLIMOB_TO_PICOMOB-amount, selection.Fee)
+
+	for i := 0; i < 10; i++ {
+		selection, err = SelectCoins(pool, amount, 0, nil, COIN_SELECTION_RANDOM)
+		assert.Nil(err)
+		var total uint64
+		for _, in := range selection.Inputs {
+			total += in.Amount
+		}
+		assert.Equal(total, selection.Amount+selection.Fee+selection.Change)
+	}
+
+	_, err = SelectCoins(pool, 20*MILLIMOB_TO_PICOMOB, 0, nil, COIN_SELECTION_LARGEST_FIRST)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+
+	dust := make([]*UTXO, MAX_INPUTS*2)
+	for i := range dust {
+		dust[i] = &UTXO{Amount: MILLIMOB_TO_PICOMOB}
+	}
+	_, err = SelectCoins(dust, 20*MILLIMOB_TO_PICOMOB, 0, nil, COIN_SELECTION_SMALLEST_FIRST)
+	assert.True(errors.Is(err, ErrTooManyDustInputs))
+	selection, err = SelectCoins(dust, 15*MILLIMOB_TO_PICOMOB, 0, nil, COIN_SELECTION_SMALLEST_FIRST)
+	assert.Nil(err)
+	assert.Len(selection.Inputs, MAX_INPUTS)
+
+	_, err = SelectCoins(pool, amount, 5, nil, COIN_SELECTION_LARGEST_FIRST)
+	assert.ErrorContains(err, "no default fee policy for token 5")
+
+	// the utxos of other tokens are not selected
+	_, err = SelectCoins(pool, 3*MINIMUM_EUSD, EUSD_TOKEN_ID, nil, COIN_SELECTION_LARGEST_FIRST)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+	pool = append(pool, &UTXO{Amount: 4 * MINIMUM_EUSD, TokenID: EUSD_TOKEN_ID}, &UTXO{Amount: 2 * MINIMUM_EUSD, TokenID: EUSD_TOKEN_ID})
+	selection, err = SelectCoins(pool, 3*MINIMUM_EUSD, EUSD_TOKEN_ID, nil, COIN_SELECTION_SMALLEST_FIRST)
+	assert.Nil(err)
+	assert.Len(selection.Inputs, 2)
+	for _, in := range selection.Inputs {
+		assert.Equal(uint(EUSD_TOKEN_ID), in.TokenID)
+	}
+	assert.Equal(uint64(EUSD_MINIMUM_FEE), selection.Fee)
+	assert.Equal(uint64(3*MINIMUM_EUSD-EUSD_MINIMUM_FEE), selection.Change)
+	selection, err = SelectCoins(pool, amount, 0, nil, COIN_SELECTION_SMALLEST_FIRST)
+	assert.Nil(err)
+	for _, in := range selection.Inputs {
+		assert.Equal(uint(0), in.TokenID)
+	}
+
+	// the eUSD change below the fee is added to the fee
+	selection, err = SelectCoins(pool, 6*MINIMUM_EUSD-EUSD_MINIMUM_FEE-1, EUSD_TOKEN_ID, nil, COIN_SELECTION_LARGEST_FIRST)
+	assert.Nil(err)
+	assert.Equal(uint64(0), selection.Change)
+	assert.Equal(uint64(EUSD_MINIMUM_FEE+1), selection.Fee)
+}
+
+func TestCheckChangeAmount(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(checkChangeAmount(0, 0))
+	assert.Nil(checkChangeAmount(MILLIMOB_TO_PICOMOB, 0))
+	assert.NotNil(checkChangeAmount(MILLIMOB_TO_PICOMOB-1, 0))
+	assert.Nil(checkChangeAmount(0, EUSD_TOKEN_ID))
+	assert.Nil(checkChangeAmount(EUSD_MINIMUM_FEE, EUSD_TOKEN_ID))
+	assert.ErrorContains(checkChangeAmount(1, EUSD_TOKEN_ID), "change amount 1 of token 1 below minimum 2560")
+	assert.ErrorContains(checkChangeAmount(0, 5), "no default fee policy for token 5")
+}
@@ -3,33 +3,106 @@ package api
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
 	"net/url"
+	"time"
 
 	"github.com/MixinNetwork/mobilecoin-account/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
+const (
+	FOG_INSECURE_SCHEME = "insecure-fog"
+	FOG_DEFAULT_PORT    = "443"
+)
+
+// FogReportFetcher provides the fog report of a fog report url, e.g.
+// fog://fog.prod.mobilecoinww.com, to the fog resolver.
+type FogReportFetcher interface {
+	FetchReports(ctx context.Context, fogReportUrl string) (*types.ReportResponse, error)
+}
+
+// GRPCFogReportFetcher dials the fog report server, the port in the url is
+// used when present. A nil RootCAs uses the system pool, and Timeout bounds
+// each fetch in addition to the deadline of ctx.
+type GRPCFogReportFetcher struct {
+	RootCAs *x509.CertPool
+	Timeout time.Duration
+}
+
+// StaticFogReportFetcher serves the reports fetched in advance, for the tests
+// and the signers without network access.
+type StaticFogReportFetcher struct {
+	Reports map[string]*types.ReportResponse
+}
+
+var DefaultFogReportFetcher FogReportFetcher = &GRPCFogReportFetcher{Timeout: 30 * time.Second}
+
 func GetFogReportResponse(address string) (*types.ReportResponse, error) {
-	uri, err := url.Parse(address)
+	return DefaultFogReportFetcher.FetchReports(context.Background(), address)
+}
+
+func NewGRPCFogReportFetcher(rootCAs *x509.CertPool, timeout time.Duration) *GRPCFogReportFetcher {
+	return &GRPCFogReportFetcher{RootCAs: rootCAs, Timeout: timeout}
+}
+
+func (f *GRPCFogReportFetcher) FetchReports(ctx context.Context, fogReportUrl string) (*types.ReportResponse, error) {
+	target, secure, err := fogReportTarget(fogReportUrl)
 	if err != nil {
 		return nil, err
 	}
 
-	// Use system RootCAs
-	creds := credentials.NewTLS(&tls.Config{})
-
-	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithTransportCredentials(creds))
-	conn, err := grpc.Dial(fmt.Sprintf("%s:443", uri.Host), opts...)
+	creds := insecure.NewCredentials()
+	if secure {
+		creds = credentials.NewTLS(&tls.Config{RootCAs: f.RootCAs})
+	}
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, err
 	}
-
 	defer conn.Close()
+
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
 	client := types.NewReportAPIClient(conn)
+	return client.GetReports(ctx, &types.ReportRequest{})
+}
+
+func NewStaticFogReportFetcher(reports map[string]*types.ReportResponse) *StaticFogReportFetcher {
+	return &StaticFogReportFetcher{Reports: reports}
+}
 
-	in := &types.ReportRequest{}
-	return client.GetReports(context.Background(), in)
+func (f *StaticFogReportFetcher) FetchReports(ctx context.Context, fogReportUrl string) (*types.ReportResponse, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+	report, found := f.Reports[fogReportUrl]
+	if !found {
+		return nil, fmt.Errorf("no fog report for %s", fogReportUrl)
+	}
+	return report, nil
+}
+
+func fogReportTarget(fogReportUrl string) (string, bool, error) {
+	uri, err := url.Parse(fogReportUrl)
+	if err != nil {
+		return "", false, err
+	}
+	if uri.Hostname() == "" {
+		return "", false, fmt.Errorf("invalid fog report url %s", fogReportUrl)
+	}
+	secure := uri.Scheme != FOG_INSECURE_SCHEME
+	port := uri.Port()
+	if port == "" {
+		port = FOG_DEFAULT_PORT
+	}
+	return net.JoinHostPort(uri.Hostname(), port), secure, nil
 }
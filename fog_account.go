@@ -0,0 +1,373 @@
+package api
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"slices"
+
+	"github.com/bwesterb/go-ristretto"
+)
+
+// FogRng is the search keys of an ingest invocation, FogRngC with
+// libmobilecoin.
+type FogRng interface {
+	Index() int64
+	Peek() ([]byte, error)
+	Advance() error
+	Free()
+}
+
+// FogKeys are the fog crypto of the fog subaddress of an account, the rngs
+// and the TxOut records are derived from its subaddress view private key.
+type FogKeys interface {
+	NewRng(pubkey *KexRngPubkey) (FogRng, error)
+	DecryptTxOutRecord(ciphertext []byte) (*FogTxOutRecord, error)
+}
+
+type fogKeysC struct {
+	subaddressViewPrivate *ristretto.Scalar
+}
+
+// NewFogKeys with the hex view private key of the fog subaddress, which is
+// the subaddress of the fog enabled public address, usually 0.
+func NewFogKeys(subaddressViewPrivateStr string) (FogKeys, error) {
+	private, err := decodeScalar(subaddressViewPrivateStr)
+	if err != nil {
+		return nil, err
+	}
+	return &fogKeysC{subaddressViewPrivate: private}, nil
+}
+
+func (k *fogKeysC) NewRng(pubkey *KexRngPubkey) (FogRng, error) {
+	return NewFogRngC(k.subaddressViewPrivate, pubkey)
+}
+
+func (k *fogKeysC) DecryptTxOutRecord(ciphertext []byte) (*FogTxOutRecord, error) {
+	plaintext, err := MCVersionedCryptoBoxDecrypt(k.subaddressViewPrivate, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	var record FogTxOutRecord
+	err = record.unmarshal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// TxOut of the record, the commitment is only in the records of the old fog
+// ingest versions, Sync reconstructs it for the TxOuts of the account.
+func (r *FogTxOutRecord) TxOut() *TxOut {
+	amount := &Amount{
+		Commitment:    hex.EncodeToString(r.TxOutAmountCommitmentData),
+		MaskedValue:   MaskedValue(r.TxOutAmountMaskedValue),
+		MaskedTokenID: hex.EncodeToString(r.TxOutAmountMaskedV1TokenID),
+		Version:       1,
+	}
+	if len(r.TxOutAmountMaskedV2TokenID) > 0 {
+		amount.MaskedTokenID = hex.EncodeToString(r.TxOutAmountMaskedV2TokenID)
+		amount.Version = 2
+	}
+	return &TxOut{
+		Amount:    amount,
+		TargetKey: hex.EncodeToString(r.TxOutTargetKeyData),
+		PublicKey: hex.EncodeToString(r.TxOutPublicKeyData),
+		EMemo:     hex.EncodeToString(r.TxOutEMemoData),
+	}
+}
+
+// checkCommitment reconstructs the commitment of the matched TxOut of the
+// record, which must be the commitment data of the old records and match the
+// crc32 of the record. The TxOut gets the commitment when it's missing.
+func (r *FogTxOutRecord) checkCommitment(m *ScanMatch, viewPrivate *ristretto.Scalar) error {
+	publicKey, err := decodePoint(m.TxOut.PublicKey)
+	if err != nil {
+		return err
+	}
+	secret := createSharedSecret(publicKey, viewPrivate)
+	blinding := GetBlinding(secret)
+	if m.TxOut.Amount.Version == 2 {
+		blinding, err = GetBlindingV2(ComputeAmountSharedSecretV2(secret))
+		if err != nil {
+			return err
+		}
+	}
+	commitment := NewPedersenGensForToken(m.TokenID).Commit(uint64ToScalar(m.Value), blinding).Bytes()
+	if len(r.TxOutAmountCommitmentData) > 0 && !bytes.Equal(r.TxOutAmountCommitmentData, commitment) {
+		return fmt.Errorf("fog txout %x commitment mismatch", r.TxOutPublicKeyData)
+	}
+	if sum := crc32.ChecksumIEEE(commitment); sum != r.TxOutAmountCommitmentCrc32 {
+		return fmt.Errorf("fog txout %x commitment crc32 %08x, expected %08x", r.TxOutPublicKeyData, sum, r.TxOutAmountCommitmentCrc32)
+	}
+	m.TxOut.Amount.Commitment = hex.EncodeToString(commitment)
+	return nil
+}
+
+// FogTxOut is a TxOut of the account found by fog, SpentAt is the block
+// index of the spending when Spent.
+type FogTxOut struct {
+	TxOut           *TxOut
+	GlobalIndex     uint64
+	BlockIndex      uint64
+	Timestamp       uint64
+	SubaddressIndex uint64
+	Value           uint64
+	TokenID         uint64
+	MemoPayload     []byte
+	KeyImage        string
+	Spent           bool
+	SpentAt         uint64
+}
+
+// FogAccount discovers the TxOuts of an account with fog, the rngs and the
+// user event id are kept between the syncs so only the new TxOuts are
+// searched. The key images are computed by signer and checked with the
+// ledger, a nil signer leaves the spent status unknown. It must be freed by
+// Free.
+type FogAccount struct {
+	keys    FogKeys
+	scanner *Scanner
+	signer  Signer
+
+	nextUserEventID int64
+	rngs            map[int64]FogRng
+	txOuts          map[string]*FogTxOut
+	blockCount      uint64
+	missedRanges    []*FogBlockRange
+}
+
+func NewFogAccount(keys FogKeys, scanner *Scanner, signer Signer) *FogAccount {
+	return &FogAccount{
+		keys:    keys,
+		scanner: scanner,
+		signer:  signer,
+		rngs:    make(map[int64]FogRng),
+		txOuts:  make(map[string]*FogTxOut),
+	}
+}
+
+func (a *FogAccount) Free() {
+	for _, rng := range a.rngs {
+		rng.Free()
+	}
+	a.rngs = make(map[int64]FogRng)
+}
+
+// Sync searches the new TxOuts with view, then checks the key images of the
+// unspent TxOuts with ledger when it's not nil.
+func (a *FogAccount) Sync(ctx context.Context, view *FogViewClient, ledger *FogLedgerClient) error {
+	aad := &FogQueryRequestAAD{StartFromUserEventID: a.nextUserEventID}
+	resp, err := view.Query(ctx, aad, &FogQueryRequest{})
+	if err != nil {
+		return err
+	}
+	for _, r := range resp.Rngs {
+		if a.rngs[r.IngestInvocationID] != nil {
+			continue
+		}
+		rng, err := a.keys.NewRng(r.Pubkey)
+		if err != nil {
+			return err
+		}
+		a.rngs[r.IngestInvocationID] = rng
+	}
+	for _, r := range resp.MissedBlockRanges {
+		missed := slices.ContainsFunc(a.missedRanges, func(m *FogBlockRange) bool {
+			return *m == *r
+		})
+		if !missed {
+			a.missedRanges = append(a.missedRanges, r)
+		}
+	}
+	if resp.NextStartFromUserEventID > a.nextUserEventID {
+		a.nextUserEventID = resp.NextStartFromUserEventID
+	}
+	a.blockCount = resp.HighestProcessedBlockCount
+
+	records, err := a.search(ctx, view, aad)
+	if err != nil {
+		return err
+	}
+	err = a.addRecords(ctx, records)
+	if err != nil {
+		return err
+	}
+	if ledger == nil {
+		return nil
+	}
+	return a.checkKeyImages(ctx, ledger)
+}
+
+// search queries the next output of each rng until none is found, the rng
+// advances on each found TxOut.
+func (a *FogAccount) search(ctx context.Context, view *FogViewClient, aad *FogQueryRequestAAD) ([]*FogTxOutRecord, error) {
+	var records []*FogTxOutRecord
+	active := make([]int64, 0, len(a.rngs))
+	for id := range a.rngs {
+		active = append(active, id)
+	}
+	slices.Sort(active)
+
+	for len(active) > 0 {
+		req := &FogQueryRequest{}
+		searchKeys := make(map[string]int64)
+		for _, id := range active {
+			key, err := a.rngs[id].Peek()
+			if err != nil {
+				return nil, err
+			}
+			req.GetTxos = append(req.GetTxos, key)
+			searchKeys[hex.EncodeToString(key)] = id
+		}
+		resp, err := view.Query(ctx, aad, req)
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := resp.SearchResults()
+		if err != nil {
+			return nil, err
+		}
+		active = active[:0]
+		for _, r := range results {
+			id, found := searchKeys[hex.EncodeToString(r.SearchKey)]
+			if !found {
+				return nil, fmt.Errorf("unknown fog search key %x", r.SearchKey)
+			}
+			switch r.ResultCode {
+			case FOG_TX_OUT_SEARCH_RESULT_FOUND:
+				record, err := a.keys.DecryptTxOutRecord(r.Ciphertext)
+				if err != nil {
+					return nil, err
+				}
+				err = a.rngs[id].Advance()
+				if err != nil {
+					return nil, err
+				}
+				records = append(records, record)
+				active = append(active, id)
+			case FOG_TX_OUT_SEARCH_RESULT_NOT_FOUND:
+			default:
+				return nil, fmt.Errorf("fog search key %x result %d", r.SearchKey, r.ResultCode)
+			}
+		}
+	}
+	return records, nil
+}
+
+// addRecords keeps the TxOuts of the records which belong to the account
+func (a *FogAccount) addRecords(ctx context.Context, records []*FogTxOutRecord) error {
+	txOuts := make([]*TxOut, len(records))
+	for i, r := range records {
+		txOuts[i] = r.TxOut()
+	}
+	matches, err := a.scanner.Scan(txOuts)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if a.txOuts[m.TxOut.PublicKey] != nil {
+			continue
+		}
+		r := records[m.Index]
+		err = r.checkCommitment(m, a.scanner.viewPrivate)
+		if err != nil {
+			return err
+		}
+		out := &FogTxOut{
+			TxOut:           m.TxOut,
+			GlobalIndex:     r.TxOutGlobalIndex,
+			BlockIndex:      r.BlockIndex,
+			Timestamp:       r.Timestamp,
+			SubaddressIndex: m.SubaddressIndex,
+			Value:           m.Value,
+			TokenID:         m.TokenID,
+			MemoPayload:     m.MemoPayload,
+		}
+		if a.signer != nil {
+			out.KeyImage, err = a.signer.KeyImage(ctx, m.TxOut, m.SubaddressIndex)
+			if err != nil {
+				return err
+			}
+		}
+		a.txOuts[m.TxOut.PublicKey] = out
+	}
+	return nil
+}
+
+func (a *FogAccount) checkKeyImages(ctx context.Context, ledger *FogLedgerClient) error {
+	req := &FogCheckKeyImagesRequest{}
+	unspent := make(map[string]*FogTxOut)
+	for _, out := range a.txOuts {
+		if out.Spent || out.KeyImage == "" {
+			continue
+		}
+		keyImage, err := hex.DecodeString(out.KeyImage)
+		if err != nil {
+			return err
+		}
+		req.Queries = append(req.Queries, &FogKeyImageQuery{KeyImage: keyImage, StartBlock: out.BlockIndex})
+		unspent[out.KeyImage] = out
+	}
+	if len(req.Queries) == 0 {
+		return nil
+	}
+
+	resp, err := ledger.CheckKeyImages(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, r := range resp.Results {
+		out := unspent[hex.EncodeToString(r.KeyImage)]
+		if out == nil {
+			return fmt.Errorf("unknown fog key image %x", r.KeyImage)
+		}
+		switch r.KeyImageResultCode {
+		case FOG_KEY_IMAGE_RESULT_SPENT:
+			out.Spent, out.SpentAt = true, r.SpentAt
+		case FOG_KEY_IMAGE_RESULT_NOT_SPENT:
+		default:
+			return fmt.Errorf("fog key image %x result %d", r.KeyImage, r.KeyImageResultCode)
+		}
+	}
+	return nil
+}
+
+// TxOuts found by the syncs, in the order of their global index
+func (a *FogAccount) TxOuts() []*FogTxOut {
+	txOuts := make([]*FogTxOut, 0, len(a.txOuts))
+	for _, out := range a.txOuts {
+		txOuts = append(txOuts, out)
+	}
+	slices.SortFunc(txOuts, func(x, y *FogTxOut) int {
+		return cmp.Compare(x.GlobalIndex, y.GlobalIndex)
+	})
+	return txOuts
+}
+
+// Balance of the unspent TxOuts of tokenID
+func (a *FogAccount) Balance(tokenID uint64) uint64 {
+	var balance uint64
+	for _, out := range a.txOuts {
+		if !out.Spent && out.TokenID == tokenID {
+			balance += out.Value
+		}
+	}
+	return balance
+}
+
+// BlockCount processed by fog view at the last sync
+func (a *FogAccount) BlockCount() uint64 {
+	return a.blockCount
+}
+
+// MissedBlockRanges reported by fog view in all the syncs, the TxOuts of
+// these blocks are not found by fog and the Balance is short of them until
+// the blocks are scanned from the ledger with Scanner.
+func (a *FogAccount) MissedBlockRanges() []*FogBlockRange {
+	return slices.Clone(a.missedRanges)
+}
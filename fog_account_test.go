@@ -0,0 +1,461 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"net"
+	"path/filepath"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestFogAccount(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+	var otherView, otherSpend ristretto.Scalar
+	otherView.Rand()
+	otherSpend.Rand()
+	other := &account.Account{ViewPrivateKey: &otherView, SpendPrivateKey: &otherSpend}
+
+	server := &testFogServer{records: make(map[string][]byte), spent: make(map[string]uint64)}
+	server.addRng(1)
+	server.addRng(2)
+	first := server.addTxOut(assert, 0, newScannerTestTxOut(assert, acc.PublicAddress(0), 100, 0, nil))
+	server.addTxOut(assert, 0, newScannerTestTxOut(assert, acc.PublicAddress(3), 200, 1, nil))
+	server.addTxOut(assert, 0, newScannerTestTxOut(assert, other.PublicAddress(0), 400, 0, nil))
+	server.addTxOut(assert, 1, newScannerTestTxOut(assert, acc.PublicAddress(CHANGE_SUBADDRESS_INDEX), 50, 0, nil))
+	keyImage, err := KeyImageForTxOut(first, &view, acc.SubaddressSpendPrivateKey(0))
+	assert.Nil(err)
+	server.spent[keyImage] = 9
+	server.missed = []*FogBlockRange{{Start: 3, End: 5}}
+
+	socket := filepath.Join(t.TempDir(), "fog.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(err)
+	grpcServer := grpc.NewServer(FogServerCodec())
+	RegisterFogViewServer(grpcServer, server)
+	RegisterFogLedgerServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(err)
+	newCipher := func() (FogCipher, error) { return &testFogCipher{}, nil }
+	viewClient := NewFogViewClient(conn, "fog.example.com:443", newCipher)
+	defer viewClient.Close()
+	ledgerClient := NewFogLedgerClient(conn, "fog.example.com:443", newCipher)
+
+	scanner, err := NewScanner(viewStr, hex.EncodeToString(account.PublicKey(&spend).Bytes()), 5)
+	assert.Nil(err)
+	signer, err := NewLocalSigner(viewStr, spendStr)
+	assert.Nil(err)
+	fogAccount := NewFogAccount(&testFogKeys{}, scanner, signer)
+	defer fogAccount.Free()
+
+	err = fogAccount.Sync(ctx, viewClient, ledgerClient)
+	assert.Nil(err)
+	assert.Equal(uint64(10), fogAccount.BlockCount())
+	assert.Equal([]*FogBlockRange{{Start: 3, End: 5}}, fogAccount.MissedBlockRanges())
+	txOuts := fogAccount.TxOuts()
+	assert.Len(txOuts, 3)
+	assert.Equal(first.PublicKey, txOuts[0].TxOut.PublicKey)
+	assert.Equal(first.Amount.Commitment, txOuts[0].TxOut.Amount.Commitment)
+	assert.Equal(uint64(0), txOuts[0].SubaddressIndex)
+	assert.Equal(uint64(100), txOuts[0].Value)
+	assert.Equal(keyImage, txOuts[0].KeyImage)
+	assert.True(txOuts[0].Spent)
+	assert.Equal(uint64(9), txOuts[0].SpentAt)
+	assert.Equal(uint64(3), txOuts[1].SubaddressIndex)
+	assert.Equal(uint64(1), txOuts[1].TokenID)
+	assert.Equal(uint64(CHANGE_SUBADDRESS_INDEX), txOuts[2].SubaddressIndex)
+	assert.False(txOuts[2].Spent)
+	assert.Equal(uint64(50), fogAccount.Balance(0))
+	assert.Equal(uint64(200), fogAccount.Balance(1))
+
+	server.addRng(3)
+	server.addTxOut(assert, 0, newScannerTestTxOut(assert, acc.PublicAddress(1), 300, 0, nil))
+	server.addTxOut(assert, 2, newScannerTestTxOut(assert, acc.PublicAddress(2), 700, 0, nil))
+	server.missed = []*FogBlockRange{{Start: 3, End: 5}, {Start: 8, End: 9}}
+	server.fixed = true
+	searches := server.searches
+	err = fogAccount.Sync(ctx, viewClient, ledgerClient)
+	assert.Nil(err)
+	assert.Len(fogAccount.TxOuts(), 5)
+	assert.Len(fogAccount.MissedBlockRanges(), 2)
+	assert.Equal(uint64(1050), fogAccount.Balance(0))
+	assert.Equal(5, server.searches-searches)
+	assert.Len(fogAccount.rngs, 3)
+
+	server.failed = true
+	err = fogAccount.Sync(ctx, viewClient, ledgerClient)
+	assert.NotNil(err)
+	server.failed = false
+	err = fogAccount.Sync(ctx, viewClient, ledgerClient)
+	assert.Nil(err)
+	assert.Len(fogAccount.TxOuts(), 5)
+
+	out := server.addTxOut(assert, 1, newScannerTestTxOut(assert, acc.PublicAddress(0), 800, 0, nil))
+	for key, data := range server.records {
+		var record FogTxOutRecord
+		assert.Nil(record.unmarshal(data))
+		if hex.EncodeToString(record.TxOutPublicKeyData) == out.PublicKey {
+			record.TxOutAmountCommitmentCrc32++
+			server.records[key] = record.marshal()
+		}
+	}
+	err = fogAccount.Sync(ctx, viewClient, ledgerClient)
+	assert.ErrorContains(err, "commitment crc32")
+	assert.Len(fogAccount.TxOuts(), 5)
+}
+
+func TestFogMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	record := &FogTxOutRecord{
+		TxOutAmountMaskedValue:     123,
+		TxOutTargetKeyData:         []byte{1, 2},
+		TxOutPublicKeyData:         []byte{3, 4},
+		TxOutGlobalIndex:           5,
+		BlockIndex:                 6,
+		TxOutAmountMaskedV2TokenID: []byte{7},
+	}
+	var decoded FogTxOutRecord
+	assert.Nil(decoded.unmarshal(record.marshal()))
+	assert.Equal(record, &decoded)
+	assert.Equal(2, int(decoded.TxOut().Amount.Version))
+
+	data := protowire.AppendTag(nil, 2, protowire.VarintType)
+	data = protowire.AppendVarint(data, FOG_TX_OUT_SEARCH_RESULT_NOT_FOUND)
+	data = protowire.AppendTag(data, 99, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte("unknown"))
+	var result FogTxOutSearchResult
+	assert.Nil(result.unmarshal(data))
+	assert.Equal(uint32(FOG_TX_OUT_SEARCH_RESULT_NOT_FOUND), result.ResultCode)
+
+	data = protowire.AppendTag(nil, 1, protowire.VarintType)
+	data = protowire.AppendVarint(data, 1)
+	assert.NotNil(result.unmarshal(data))
+	assert.NotNil(result.unmarshal([]byte{0x0a, 0x05}))
+}
+
+func TestFogMessagesGolden(t *testing.T) {
+	assert := assert.New(t)
+
+	// the bytes are encoded by hand from the fog view and ledger protos
+	record := &FogTxOutRecord{
+		TxOutAmountMaskedValue:     123,
+		TxOutTargetKeyData:         []byte{1},
+		TxOutPublicKeyData:         []byte{2},
+		TxOutGlobalIndex:           5,
+		BlockIndex:                 6,
+		Timestamp:                  7,
+		TxOutAmountCommitmentCrc32: 0x01020304,
+		TxOutEMemoData:             []byte{9},
+		TxOutAmountMaskedV2TokenID: []byte{7},
+	}
+	query := &FogQueryResponse{
+		HighestProcessedBlockCount:              10,
+		HighestProcessedBlockSignatureTimestamp: 5,
+		NextStartFromUserEventID:                2,
+		MissedBlockRanges:                       []*FogBlockRange{{Start: 3, End: 5}},
+		Rngs: []*FogRngRecord{{
+			IngestInvocationID: 1,
+			Pubkey:             &KexRngPubkey{PublicKey: []byte{1, 2}, Version: 1},
+			StartBlock:         7,
+		}},
+		DecommissionedIngestInvocations:  []*FogDecommissionedIngestInvocation{{IngestInvocationID: 4, LastIngestedBlock: 9}},
+		TxOutSearchResults:               []*FogTxOutSearchResult{{SearchKey: []byte{1, 2}, ResultCode: FOG_TX_OUT_SEARCH_RESULT_FOUND, Ciphertext: []byte{0xff}}},
+		LastKnownBlockCount:              11,
+		LastKnownBlockCumulativeTxoCount: 20,
+		FixedTxOutSearchResults: []*FogFixedTxOutSearchResult{{
+			SearchKey:     []byte{3, 4},
+			ResultCode:    FOG_TX_OUT_SEARCH_RESULT_FOUND,
+			Ciphertext:    []byte{0xff, 0, 0},
+			PayloadLength: 1,
+		}},
+	}
+	keyImages := &FogCheckKeyImagesResponse{
+		NumBlocks:          10,
+		GlobalTxoCount:     20,
+		Results:            []*FogKeyImageResult{{KeyImage: []byte{0xaa}, SpentAt: 9, KeyImageResultCode: FOG_KEY_IMAGE_RESULT_SPENT}},
+		LatestBlockVersion: 3,
+	}
+
+	for _, c := range []struct {
+		message fogMessage
+		decoded fogMessage
+		golden  string
+	}{
+		{&FogQueryRequestAAD{StartFromUserEventID: 3, StartFromBlockIndex: 300}, &FogQueryRequestAAD{}, "080310ac02"},
+		{&FogQueryRequest{GetTxos: [][]byte{{1, 2}, {0xaa, 0xbb, 0xcc}}}, &FogQueryRequest{}, "0a0201020a03aabbcc"},
+		{query, &FogQueryResponse{},
+			"080a" + "1005" + "1802" + "2204" + "08031005" +
+				"2a0c" + "0801" + "1206" + "0a020102" + "1001" + "1807" +
+				"3204" + "08041009" +
+				"3a0c" + "0a020102" + "1501000000" + "1a01ff" +
+				"400b" + "4814" +
+				"5213" + "0a020304" + "1501000000" + "1a03ff0000" + "2501000000"},
+		{record, &FogTxOutRecord{},
+			"117b000000000000001a010122010229050000000000000031060000000000000039070000000000000045040302014a01095a0107"},
+		{&FogCheckKeyImagesRequest{Queries: []*FogKeyImageQuery{{KeyImage: []byte{0xaa}, StartBlock: 2}}}, &FogCheckKeyImagesRequest{},
+			"0a0e0a030a01aa110200000000000000"},
+		{keyImages, &FogCheckKeyImagesResponse{},
+			"080a10141a130a030a01aa1109000000000000002d010000002003"},
+	} {
+		assert.Equal(c.golden, hex.EncodeToString(c.message.marshal()))
+		assert.Nil(c.decoded.unmarshal(account.HexToBytes(c.golden)))
+		assert.Equal(c.message, c.decoded)
+	}
+
+	results, err := query.SearchResults()
+	assert.Nil(err)
+	assert.Len(results, 2)
+	assert.Equal([]byte{0xff}, results[1].Ciphertext)
+	query.FixedTxOutSearchResults[0].SearchKey = []byte{1, 2}
+	results, err = query.SearchResults()
+	assert.Nil(err)
+	assert.Len(results, 1)
+	query.FixedTxOutSearchResults[0].SearchKey = []byte{3, 4}
+	query.FixedTxOutSearchResults[0].PayloadLength = 4
+	_, err = query.SearchResults()
+	assert.NotNil(err)
+
+	var blocks FogBlockRange
+	assert.NotNil(blocks.unmarshal(account.HexToBytes("08051003")))
+}
+
+// testFogServer stands in for the fog view and ledger enclaves, the channels
+// are sealed by testFogCipher and the search keys are from testFogRng.
+type testFogServer struct {
+	rngs     []*FogRngRecord
+	indices  []int64
+	records  map[string][]byte
+	spent    map[string]uint64
+	missed   []*FogBlockRange
+	fixed    bool
+	searches int
+	failed   bool
+}
+
+func (s *testFogServer) addRng(id int64) {
+	pubkey := make([]byte, 32)
+	rand.Read(pubkey)
+	s.rngs = append(s.rngs, &FogRngRecord{IngestInvocationID: id, Pubkey: &KexRngPubkey{PublicKey: pubkey, Version: 1}})
+	s.indices = append(s.indices, 0)
+}
+
+func (s *testFogServer) addTxOut(assert *assert.Assertions, rng int, out *TxOut) *TxOut {
+	rngRecord := &testFogRng{seed: s.rngs[rng].Pubkey.PublicKey, index: s.indices[rng]}
+	key, err := rngRecord.Peek()
+	assert.Nil(err)
+	s.indices[rng]++
+
+	masked, _ := hex.DecodeString(out.Amount.MaskedTokenID)
+	record := &FogTxOutRecord{
+		TxOutAmountMaskedValue:     uint64(out.Amount.MaskedValue),
+		TxOutTargetKeyData:         account.HexToBytes(out.TargetKey),
+		TxOutPublicKeyData:         account.HexToBytes(out.PublicKey),
+		TxOutGlobalIndex:           uint64(len(s.records)),
+		BlockIndex:                 uint64(len(s.records)),
+		TxOutAmountCommitmentCrc32: crc32.ChecksumIEEE(account.HexToBytes(out.Amount.Commitment)),
+		TxOutAmountMaskedV2TokenID: masked,
+	}
+	s.records[hex.EncodeToString(key)] = record.marshal()
+	return out
+}
+
+func (s *testFogServer) Auth(ctx context.Context, req *AttestAuthMessage) (*AttestAuthMessage, error) {
+	return &AttestAuthMessage{Data: req.Data}, nil
+}
+
+func (s *testFogServer) Query(ctx context.Context, req *AttestMessage) (*AttestMessage, error) {
+	if s.failed {
+		return nil, errors.New("fog view unavailable")
+	}
+	c := &testFogCipher{binding: req.ChannelID}
+	data, err := c.Decrypt(req.Aad, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	var aad FogQueryRequestAAD
+	err = aad.unmarshal(req.Aad)
+	if err != nil {
+		return nil, err
+	}
+	var query FogQueryRequest
+	err = query.unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &FogQueryResponse{
+		HighestProcessedBlockCount: 10,
+		NextStartFromUserEventID:   int64(len(s.rngs)),
+		MissedBlockRanges:          s.missed,
+		Rngs:                       s.rngs[aad.StartFromUserEventID:],
+	}
+	for _, key := range query.GetTxos {
+		s.searches++
+		result := &FogTxOutSearchResult{SearchKey: key, ResultCode: FOG_TX_OUT_SEARCH_RESULT_NOT_FOUND}
+		ciphertext, found := s.records[hex.EncodeToString(key)]
+		if found {
+			result.ResultCode, result.Ciphertext = FOG_TX_OUT_SEARCH_RESULT_FOUND, ciphertext
+		}
+		if !s.fixed {
+			resp.TxOutSearchResults = append(resp.TxOutSearchResults, result)
+			continue
+		}
+		resp.FixedTxOutSearchResults = append(resp.FixedTxOutSearchResults, &FogFixedTxOutSearchResult{
+			SearchKey:     result.SearchKey,
+			ResultCode:    result.ResultCode,
+			Ciphertext:    append(result.Ciphertext, make([]byte, 16)...),
+			PayloadLength: uint32(len(result.Ciphertext)),
+		})
+	}
+	data, err = c.Encrypt(req.Aad, resp.marshal())
+	if err != nil {
+		return nil, err
+	}
+	return &AttestMessage{Aad: req.Aad, ChannelID: req.ChannelID, Data: data}, nil
+}
+
+func (s *testFogServer) CheckKeyImages(ctx context.Context, req *AttestMessage) (*AttestMessage, error) {
+	c := &testFogCipher{binding: req.ChannelID}
+	data, err := c.Decrypt(req.Aad, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	var check FogCheckKeyImagesRequest
+	err = check.unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &FogCheckKeyImagesResponse{NumBlocks: 10}
+	for _, q := range check.Queries {
+		result := &FogKeyImageResult{KeyImage: q.KeyImage, KeyImageResultCode: FOG_KEY_IMAGE_RESULT_NOT_SPENT}
+		spentAt, found := s.spent[hex.EncodeToString(q.KeyImage)]
+		if found {
+			result.KeyImageResultCode, result.SpentAt = FOG_KEY_IMAGE_RESULT_SPENT, spentAt
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	data, err = c.Encrypt(req.Aad, resp.marshal())
+	if err != nil {
+		return nil, err
+	}
+	return &AttestMessage{Aad: req.Aad, ChannelID: req.ChannelID, Data: data}, nil
+}
+
+// testFogCipher seals with AES-GCM keyed by the binding
+type testFogCipher struct {
+	binding []byte
+}
+
+func (c *testFogCipher) AuthRequest(responderID string) ([]byte, error) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	sum := sha256.Sum256(append([]byte(responderID), nonce...))
+	c.binding = sum[:]
+	return c.binding, nil
+}
+
+func (c *testFogCipher) ProcessAuthResponse(data []byte) error {
+	if !bytes.Equal(data, c.binding) {
+		return errors.New("invalid auth response")
+	}
+	return nil
+}
+
+func (c *testFogCipher) Binding() ([]byte, error) {
+	return c.binding, nil
+}
+
+func (c *testFogCipher) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.binding)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (c *testFogCipher) Encrypt(aad, plaintext []byte) ([]byte, error) {
+	aead, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func (c *testFogCipher) Decrypt(aad, ciphertext []byte) ([]byte, error) {
+	aead, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("invalid ciphertext")
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], aad)
+}
+
+func (c *testFogCipher) Free() {}
+
+type testFogRng struct {
+	seed  []byte
+	index int64
+}
+
+func (r *testFogRng) Index() int64 {
+	return r.index
+}
+
+func (r *testFogRng) Peek() ([]byte, error) {
+	sum := sha256.Sum256(binary.LittleEndian.AppendUint64(bytes.Clone(r.seed), uint64(r.index)))
+	return sum[:16], nil
+}
+
+func (r *testFogRng) Advance() error {
+	r.index++
+	return nil
+}
+
+func (r *testFogRng) Free() {}
+
+// testFogKeys leaves the records in plaintext
+type testFogKeys struct{}
+
+func (k *testFogKeys) NewRng(pubkey *KexRngPubkey) (FogRng, error) {
+	return &testFogRng{seed: pubkey.PublicKey}, nil
+}
+
+func (k *testFogKeys) DecryptTxOutRecord(ciphertext []byte) (*FogTxOutRecord, error) {
+	var record FogTxOutRecord
+	err := record.unmarshal(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
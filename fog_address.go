@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+)
+
+// AccountFogInfo is the fog service of an account, e.g. the Signal fog
+// fog://fog.prod.mobilecoinww.com, AuthoritySpki is the DER subject public
+// key info of the fog report signer.
+type AccountFogInfo struct {
+	ReportUrl     string `json:"report_url"`
+	ReportId      string `json:"report_id"`
+	AuthoritySpki []byte `json:"authority_spki"`
+}
+
+// PublicAddressFromPrivateKeys of the subaddress index, the fog report url, id
+// and the authority signature of the subaddress are filled when fog is not nil.
+func PublicAddressFromPrivateKeys(viewPrivateStr, spendPrivateStr string, index uint64, fog *AccountFogInfo) (*account.PublicAddress, error) {
+	acc, err := account.NewAccountKey(viewPrivateStr, spendPrivateStr)
+	if err != nil {
+		return nil, err
+	}
+	if acc.SpendPrivateKey == nil {
+		return nil, errors.New("no spend private key")
+	}
+	address := acc.PublicAddress(index)
+	if fog == nil {
+		return address, nil
+	}
+
+	sig, err := MCAccountKeyGetPublicAddressFogAuthoritySig(viewPrivateStr, spendPrivateStr, fog, index)
+	if err != nil {
+		return nil, err
+	}
+	address.FogReportUrl = fog.ReportUrl
+	address.FogReportId = fog.ReportId
+	address.FogAuthoritySig = sig
+	return address, nil
+}
+
+// PublicAddressFromRootEntropy derives the private keys of the 32 bytes root
+// entropy, then the public address as PublicAddressFromPrivateKeys.
+func PublicAddressFromRootEntropy(rootEntropy []byte, index uint64, fog *AccountFogInfo) (*account.PublicAddress, error) {
+	viewPrivateStr, spendPrivateStr, err := MCAccountPrivateKeysFromRootEntropy(rootEntropy)
+	if err != nil {
+		return nil, err
+	}
+	return PublicAddressFromPrivateKeys(viewPrivateStr, spendPrivateStr, index, fog)
+}
+
+// B58CodeFromPrivateKeys is the b58 of PublicAddressFromPrivateKeys, the
+// deposit address to share with fog users.
+func B58CodeFromPrivateKeys(viewPrivateStr, spendPrivateStr string, index uint64, fog *AccountFogInfo) (string, error) {
+	address, err := PublicAddressFromPrivateKeys(viewPrivateStr, spendPrivateStr, index, fog)
+	if err != nil {
+		return "", err
+	}
+	return address.B58Code()
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicAddressFromPrivateKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+
+	address, err := PublicAddressFromPrivateKeys(viewStr, spendStr, 3, nil)
+	assert.Nil(err)
+	assert.Equal(acc.PublicAddress(3), address)
+	code, err := B58CodeFromPrivateKeys(viewStr, spendStr, 3, nil)
+	assert.Nil(err)
+	b58, err := acc.B58Code(3)
+	assert.Nil(err)
+	assert.Equal(b58, code)
+
+	_, err = PublicAddressFromPrivateKeys(viewStr, "", 3, nil)
+	assert.NotNil(err)
+	_, err = PublicAddressFromPrivateKeys(viewStr, spendStr, 3, &AccountFogInfo{ReportUrl: "fog://fog.prod.mobilecoinww.com"})
+	assert.NotNil(err)
+	_, err = PublicAddressFromRootEntropy(make([]byte, 16), 0, nil)
+	assert.NotNil(err)
+}
+
+func TestPublicAddressFromPrivateKeysC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+
+	viewPublic, spendPublic, err := MCAccountKeyGetPublicAddressPublicKeys(viewStr, spendStr, 3)
+	assert.Nil(err)
+	assert.Equal(acc.PublicAddress(3).ViewPublicKey, viewPublic)
+	assert.Equal(acc.PublicAddress(3).SpendPublicKey, spendPublic)
+
+	fog := &AccountFogInfo{
+		ReportUrl:     "fog://fog.prod.mobilecoinww.com",
+		AuthoritySpki: account.HexToBytes("30820122300d06092a864886f70d01010105000382010f003082010a0282010100c853a8724bc211cf5370ed4dbec8947c5573bed0ec47ae14211454977b41336061f0a040f77dbf529f3a46d8095676ec971b940ab4c9642578760779840a3f9b3b893b2f65006c544e9c16586d33649769b7c1c94552d7efa081a56ad612dec932812676ebec091f2aed69123604f4888a125e04ff85f5a727c286664378581cf34c7ee13eb01cc4faf3308ed3c07a9415f98e5fbfe073e6c357967244e46ba6ebbe391d8154e6e4a1c80524b1a6733eca46e37bfdd62d75816988a79aac6bdb62a06b1237a8ff5e5c848d01bbff684248cf06d92f301623c893eb0fba0f3faee2d197ea57ac428f89d6c000f76d58d5aacc3d70204781aca45bc02b1456b454231d2f2ed4ca6614e5242c7d7af0fe61e9af6ecfa76674ffbc29b858091cbfb4011538f0e894ce45d21d7fac04ba2ff57e9ff6db21e2afd9468ad785c262ec59d4a1a801c5ec2f95fc107dc9cb5f7869d70aa84450b8c350c2fa48bddef20752a1e43676b246c7f59f8f1f4aee43c1a15f36f7a36a9ec708320ea42089991551f2656ec62ea38233946b85616ff182cf17cd227e596329b546ea04d13b053be4cf3338de777b50bc6eca7a6185cf7a5022bc9be3749b1bb43e10ecc88a0c580f2b7373138ee49c7bafd8be6a64048887230480b0c85a045255494e04a9a81646369ce7a10e08da6fae27333ec0c16c8a74d93779a9e055395078d0b07286f9930203010001"),
+	}
+	address, err := PublicAddressFromPrivateKeys(viewStr, spendStr, 3, fog)
+	assert.Nil(err)
+	assert.Equal(fog.ReportUrl, address.FogReportUrl)
+	assert.Len(address.FogAuthoritySig, 128)
+	code, err := B58CodeFromPrivateKeys(viewStr, spendStr, 3, fog)
+	assert.Nil(err)
+	decoded, err := account.DecodeB58Code(code)
+	assert.Nil(err)
+	assert.Equal(address, decoded)
+
+	entropy := make([]byte, 32)
+	viewStr, spendStr, err = MCAccountPrivateKeysFromRootEntropy(entropy)
+	assert.Nil(err)
+	address, err = PublicAddressFromRootEntropy(entropy, 0, nil)
+	assert.Nil(err)
+	expected, err := PublicAddressFromPrivateKeys(viewStr, spendStr, 0, nil)
+	assert.Nil(err)
+	assert.Equal(expected, address)
+}
@@ -9,11 +9,10 @@ package api
 // #include "libmobilecoin.h"
 import "C"
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"net/url"
-	"strings"
 	"unsafe"
 
 	account "github.com/MixinNetwork/mobilecoin-account"
@@ -23,6 +22,12 @@ import (
 )
 
 func ValidateAddress(recipient string) error {
+	return ValidateAddressWithTrust(context.Background(), recipient, DefaultFogTrustConfig, DefaultFogReportFetcher)
+}
+
+// ValidateAddressWithTrust checks the fog report of recipient against the
+// enclaves and signers trusted by trust for its fog report url.
+func ValidateAddressWithTrust(ctx context.Context, recipient string, trust *FogTrustConfig, fetcher FogReportFetcher) error {
 	destination, err := account.DecodeB58Code(recipient)
 	if err != nil {
 		return err
@@ -30,15 +35,15 @@ func ValidateAddress(recipient string) error {
 	if destination.FogReportUrl == "" {
 		return nil
 	}
-	for _, enclave := range myenclaves {
-		err = ValidateFogAddressWithEnclave(destination, enclave)
-		if err != nil {
-			fmt.Printf("ValidateFogAddressWithEnclave recipient: %s enclave: %s error: %v \n", recipient, enclave, err)
-			continue
-		}
-		return nil
+	verifier, err := trust.Verifier([]string{destination.FogReportUrl})
+	if err != nil {
+		return err
+	}
+	err = validateFogAddressC(ctx, destination, verifier, fetcher)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %s: %v", recipient, err)
 	}
-	return fmt.Errorf("invalid recipient %s", recipient)
+	return nil
 }
 
 type FogFullyValidatedPubkey struct {
@@ -52,129 +57,44 @@ type FogFullyValidatedPubkey struct {
 	pubkey_expiry uint64
 }
 
-func ValidateFogAddressWithEnclave(recipient *account.PublicAddress, enclave string) error {
-	mr_enclave_hex, err := fetchValidFogEnclave(recipient.FogReportUrl, enclave)
-	if err != nil {
-		return err
-	}
-	// Construct a verifier object that is used to verify the report's attestation
-	mr_enclave_bytes, err := hex.DecodeString(mr_enclave_hex)
-	if err != nil {
-		return err
-	}
-
-	c_mr_enclave_bytes := C.CBytes(mr_enclave_bytes)
-	defer C.free(c_mr_enclave_bytes)
-
-	c_mr_enclave := C.McBuffer{
-		buffer: (*C.uchar)(c_mr_enclave_bytes),
-		len:    C.ulong(len(mr_enclave_bytes)),
-	}
-
-	mr_enclave_verifier, err := C.mc_mr_enclave_verifier_create(&c_mr_enclave)
-	if err != nil {
-		return err
-	}
-	if mr_enclave_verifier == nil {
-		return errors.New("mc_mr_enclave_verifier_create failed")
-	}
-	defer C.mc_mr_enclave_verifier_free(mr_enclave_verifier)
-
-	c_advisory_id := C.CString("INTEL-SA-00334")
-	defer C.free(unsafe.Pointer(c_advisory_id))
-	ret, err := C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id)
-	if err != nil {
-		return err
-	}
-	if ret == false {
-		return errors.New("mc_mr_enclave_verifier_allow_hardening_advisory INTEL-SA-00334 failed")
-	}
-
-	c_advisory_id_00615 := C.CString("INTEL-SA-00615")
-	defer C.free(unsafe.Pointer(c_advisory_id_00615))
-	ret, err = C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id_00615)
-	if err != nil {
-		return err
-	}
-	if ret == false {
-		return errors.New("mc_mr_enclave_verifier_allow_hardening_advisory INTEL-SA-00615 failed")
-	}
-
-	c_advisory_id_00657 := C.CString("INTEL-SA-00657")
-	defer C.free(unsafe.Pointer(c_advisory_id_00657))
-	ret, err = C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id_00657)
+// ValidateFogAddressWithEnclave checks the fog report of recipient provided by
+// fetcher, nil for DefaultFogReportFetcher, with a single enclave and the
+// advisories of DefaultFogTrustConfig.
+func ValidateFogAddressWithEnclave(ctx context.Context, recipient *account.PublicAddress, enclave string, fetcher FogReportFetcher) error {
+	trust, err := DefaultFogTrustConfig.HostTrust(recipient.FogReportUrl)
 	if err != nil {
 		return err
 	}
-	if ret == false {
-		return errors.New("mc_mr_enclave_verifier_allow_hardening_advisory INTEL-SA-00657 failed")
-	}
-
-	verifier, err := C.mc_verifier_create()
-	if err != nil {
-		return err
-	}
-	defer C.mc_verifier_free(verifier)
-
-	ret, err = C.mc_verifier_add_mr_enclave(verifier, mr_enclave_verifier)
-	if err != nil {
-		return err
-	}
-	if ret == false {
-		return errors.New("mc_verifier_add_mr_enclave failed")
-	}
+	verifier := NewFogVerifierWithEnclave(enclave, trust.HardeningAdvisories)
+	return validateFogAddressC(ctx, recipient, verifier, fetcher)
+}
 
-	// Create the FogResolver object that is used to perform report validation using the verifier constructed above
-	fog_resolver, err := C.mc_fog_resolver_create(verifier)
-	if err != nil {
-		return err
+func validateFogAddressC(ctx context.Context, recipient *account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) error {
+	if fetcher == nil {
+		fetcher = DefaultFogReportFetcher
 	}
-	defer C.mc_fog_resolver_free(fog_resolver)
 
 	// Connect to the fog report server and obtain a report
-	report, err := GetFogReportResponse(recipient.FogReportUrl)
+	report, err := fetcher.FetchReports(ctx, recipient.FogReportUrl)
 	if err != nil {
 		return err
 	}
+	return checkFogReportC(recipient, verifier.host(recipient.FogReportUrl), report)
+}
 
-	// Convert the report back to protobuf bytes so that it could be handed to libmobilecoin
-	reportBytes, err := proto.Marshal(report)
+// checkFogReportC gets the fog pubkey of recipient from report, which must be
+// attested by any enclave or signer of verifier
+func checkFogReportC(recipient *account.PublicAddress, verifier *FogVerifier, report *types.ReportResponse) error {
+	fog_resolver, err := newFogResolverC(verifier)
 	if err != nil {
 		return err
 	}
+	defer fog_resolver.free()
 
-	// Add the report bytes to the resolver
-	c_report_buf_bytes := C.CBytes(reportBytes)
-	defer C.free(c_report_buf_bytes)
-
-	report_buf := C.McBuffer{
-		buffer: (*C.uchar)(c_report_buf_bytes),
-		len:    C.ulong(len(reportBytes)),
-	}
-
-	c_address := C.CString(recipient.FogReportUrl)
-	defer C.free(unsafe.Pointer(c_address))
-
-	// Used for returning errors from libmobilecoin
-	var mc_error *C.McError
-	ret, err = C.mc_fog_resolver_add_report_response(
-		fog_resolver,
-		c_address,
-		&report_buf,
-		&mc_error,
-	)
+	err = fog_resolver.addReport(recipient.FogReportUrl, report)
 	if err != nil {
 		return err
 	}
-	if ret == false {
-		if mc_error == nil {
-			return errors.New("mc_fog_resolver_add_report_response failed")
-		} else {
-			err = fmt.Errorf("mc_fog_resolver_add_report_response failed: [%d] %s", mc_error.error_code, C.GoString(mc_error.error_description))
-			C.mc_error_free(mc_error)
-			return err
-		}
-	}
 
 	// Convert recipient from the Go representation to protobuf bytes
 	protobufRecipient, err := PublicAddressToProtobuf(recipient)
@@ -195,8 +115,9 @@ func ValidateFogAddressWithEnclave(recipient *account.PublicAddress, enclave str
 		buffer: (*C.uchar)(c_recipient_bytes),
 		len:    (C.ulong)(len(recipientProtobufBytes)),
 	}
+	var mc_error *C.McError
 	fully_validated_fog_pub_key, err := C.mc_fog_resolver_get_fog_pubkey_from_protobuf_public_address(
-		fog_resolver,
+		fog_resolver.resolver,
 		&c_recipient_buf,
 		&mc_error,
 	)
@@ -271,25 +192,235 @@ func PublicAddressToProtobuf(addr *account.PublicAddress) (*types.PublicAddress,
 	return protobufObject, nil
 }
 
-func fetchValidFogEnclave(host, enclave string) (string, error) {
-	fog_url_to_mr_enclave_hex := map[string]string{
-		"fog://fog.prod.mobilecoinww.com":            enclave,
-		"fog://service.fog.mob.production.namda.net": enclave,
-		"fog://fog-rpt-prd.namda.net":                enclave,
-		// "fog://fog.test.mobilecoin.com":              enclave,
-		// "fog://service.fog.mob.staging.namda.net":    "a4764346f91979b4906d4ce26102228efe3aba39216dec1e7d22e6b06f919f11",
+func (m *fogMeasurement) String() string {
+	if m.MrSigner != nil {
+		return fmt.Sprintf("signer %s product %d version %d", m.MrSigner.MrSigner, m.MrSigner.ProductID, m.MrSigner.SecurityVersion)
+	}
+	return m.MrEnclave
+}
+
+// fogResolverC owns the fog resolver and the verifiers it's created with
+type fogResolverC struct {
+	resolver *C.McFogResolver
+	frees    []func()
+}
+
+func (r *fogResolverC) free() {
+	for i := len(r.frees) - 1; i >= 0; i-- {
+		r.frees[i]()
 	}
+}
 
-	uri, err := url.Parse(host)
+// newFogResolverC creates a fog resolver with a McVerifier which accepts any
+// of the enclaves and signers of verifier
+func newFogResolverC(fogVerifier *FogVerifier) (*fogResolverC, error) {
+	r, verifier, err := newFogVerifierC(fogVerifier)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if uri.Port() != "" {
-		host = strings.ReplaceAll(host, ":"+uri.Port(), "")
+
+	// Create the FogResolver object that is used to perform report validation using the verifier constructed above
+	fog_resolver, err := C.mc_fog_resolver_create(verifier)
+	if err != nil {
+		r.free()
+		return nil, err
 	}
-	mr_enclave_hex, ok := fog_url_to_mr_enclave_hex[host]
-	if !ok {
-		return "", errors.New("No enclave hex for Address' fog url")
+	if fog_resolver == nil {
+		r.free()
+		return nil, errors.New("mc_fog_resolver_create failed")
+	}
+	r.resolver = fog_resolver
+	r.frees = append(r.frees, func() { C.mc_fog_resolver_free(fog_resolver) })
+	return r, nil
+}
+
+// newFogVerifierC creates the McVerifier of fogVerifier without a resolver,
+// the verifier and its measurements are freed with the returned fogResolverC
+func newFogVerifierC(fogVerifier *FogVerifier) (*fogResolverC, *C.McVerifier, error) {
+	if fogVerifier == nil || len(fogVerifier.measurements) == 0 {
+		return nil, nil, errors.New("no trusted fog enclave")
 	}
-	return mr_enclave_hex, nil
+	r := &fogResolverC{}
+
+	verifier, err := C.mc_verifier_create()
+	if err != nil {
+		return nil, nil, err
+	}
+	if verifier == nil {
+		return nil, nil, errors.New("mc_verifier_create failed")
+	}
+	r.frees = append(r.frees, func() { C.mc_verifier_free(verifier) })
+
+	for _, m := range fogVerifier.measurements {
+		err = r.addMeasurement(verifier, m)
+		if err != nil {
+			r.free()
+			return nil, nil, err
+		}
+	}
+	return r, verifier, nil
+}
+
+// newFogResolverWithReportsC fetches the fog report of each fog report url of
+// the recipients once, the report must be attested for every recipient by the
+// verifier of its own fog host. The reports are then added to a fog resolver
+// created with all the hosts of verifier.
+func newFogResolverWithReportsC(ctx context.Context, recipients []*account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) (*fogResolverC, error) {
+	if fetcher == nil {
+		fetcher = DefaultFogReportFetcher
+	}
+	reports := make(map[string]*types.ReportResponse)
+	for _, recipient := range recipients {
+		if recipient == nil || recipient.FogReportUrl == "" {
+			continue
+		}
+		report := reports[recipient.FogReportUrl]
+		if report == nil {
+			r, err := fetcher.FetchReports(ctx, recipient.FogReportUrl)
+			if err != nil {
+				return nil, err
+			}
+			report = r
+			reports[recipient.FogReportUrl] = report
+		}
+		err := checkFogReportC(recipient, verifier.host(recipient.FogReportUrl), report)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fog report %s: %v", recipient.FogReportUrl, err)
+		}
+	}
+
+	resolver, err := newFogResolverC(verifier)
+	if err != nil {
+		return nil, err
+	}
+	for _, fogReportUrl := range outlaysFogReportUrls(recipients) {
+		err = resolver.addReport(fogReportUrl, reports[fogReportUrl])
+		if err != nil {
+			resolver.free()
+			return nil, err
+		}
+	}
+	return resolver, nil
+}
+
+func (r *fogResolverC) addMeasurement(verifier *C.McVerifier, m *fogMeasurement) error {
+	hex_str := m.MrEnclave
+	if m.MrSigner != nil {
+		hex_str = m.MrSigner.MrSigner
+	}
+	measurement_bytes, err := hex.DecodeString(hex_str)
+	if err != nil {
+		return err
+	}
+	c_measurement_bytes := C.CBytes(measurement_bytes)
+	r.frees = append(r.frees, func() { C.free(c_measurement_bytes) })
+	c_measurement := C.McBuffer{
+		buffer: (*C.uchar)(c_measurement_bytes),
+		len:    C.ulong(len(measurement_bytes)),
+	}
+
+	if m.MrSigner != nil {
+		mr_signer_verifier, err := C.mc_mr_signer_verifier_create(&c_measurement, C.uint16_t(m.MrSigner.ProductID), C.uint16_t(m.MrSigner.SecurityVersion))
+		if err != nil {
+			return err
+		}
+		if mr_signer_verifier == nil {
+			return errors.New("mc_mr_signer_verifier_create failed")
+		}
+		r.frees = append(r.frees, func() { C.mc_mr_signer_verifier_free(mr_signer_verifier) })
+
+		for _, advisory := range m.HardeningAdvisories {
+			c_advisory_id := C.CString(advisory)
+			r.frees = append(r.frees, func() { C.free(unsafe.Pointer(c_advisory_id)) })
+			ret, err := C.mc_mr_signer_verifier_allow_hardening_advisory(mr_signer_verifier, c_advisory_id)
+			if err != nil {
+				return err
+			}
+			if ret == false {
+				return fmt.Errorf("mc_mr_signer_verifier_allow_hardening_advisory %s failed", advisory)
+			}
+		}
+
+		ret, err := C.mc_verifier_add_mr_signer(verifier, mr_signer_verifier)
+		if err != nil {
+			return err
+		}
+		if ret == false {
+			return errors.New("mc_verifier_add_mr_signer failed")
+		}
+		return nil
+	}
+
+	mr_enclave_verifier, err := C.mc_mr_enclave_verifier_create(&c_measurement)
+	if err != nil {
+		return err
+	}
+	if mr_enclave_verifier == nil {
+		return errors.New("mc_mr_enclave_verifier_create failed")
+	}
+	r.frees = append(r.frees, func() { C.mc_mr_enclave_verifier_free(mr_enclave_verifier) })
+
+	for _, advisory := range m.HardeningAdvisories {
+		c_advisory_id := C.CString(advisory)
+		r.frees = append(r.frees, func() { C.free(unsafe.Pointer(c_advisory_id)) })
+		ret, err := C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id)
+		if err != nil {
+			return err
+		}
+		if ret == false {
+			return fmt.Errorf("mc_mr_enclave_verifier_allow_hardening_advisory %s failed", advisory)
+		}
+	}
+
+	ret, err := C.mc_verifier_add_mr_enclave(verifier, mr_enclave_verifier)
+	if err != nil {
+		return err
+	}
+	if ret == false {
+		return errors.New("mc_verifier_add_mr_enclave failed")
+	}
+	return nil
+}
+
+// mc_fog_resolver_add_report_response
+func (r *fogResolverC) addReport(fogReportUrl string, report *types.ReportResponse) error {
+	// Convert the report back to protobuf bytes so that it could be handed to libmobilecoin
+	reportBytes, err := proto.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	// Add the report bytes to the resolver
+	c_report_buf_bytes := C.CBytes(reportBytes)
+	defer C.free(c_report_buf_bytes)
+
+	report_buf := C.McBuffer{
+		buffer: (*C.uchar)(c_report_buf_bytes),
+		len:    C.ulong(len(reportBytes)),
+	}
+
+	c_address := C.CString(fogReportUrl)
+	defer C.free(unsafe.Pointer(c_address))
+
+	// Used for returning errors from libmobilecoin
+	var mc_error *C.McError
+	ret, err := C.mc_fog_resolver_add_report_response(
+		r.resolver,
+		c_address,
+		&report_buf,
+		&mc_error,
+	)
+	if err != nil {
+		return err
+	}
+	if ret == false {
+		if mc_error == nil {
+			return errors.New("mc_fog_resolver_add_report_response failed")
+		} else {
+			err = fmt.Errorf("mc_fog_resolver_add_report_response failed: [%d] %s", mc_error.error_code, C.GoString(mc_error.error_description))
+			C.mc_error_free(mc_error)
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	FOG_VIEW_SERVICE_NAME   = "fog_view.FogViewAPI"
+	FOG_LEDGER_SERVICE_NAME = "fog_ledger.FogKeyImageAPI"
+	FOG_CODEC_NAME          = "proto"
+)
+
+// FogCipher is the attested channel with a fog enclave, AttestAke with
+// libmobilecoin.
+type FogCipher interface {
+	AuthRequest(responderID string) ([]byte, error)
+	ProcessAuthResponse(data []byte) error
+	Binding() ([]byte, error)
+	Encrypt(aad, plaintext []byte) ([]byte, error)
+	Decrypt(aad, ciphertext []byte) ([]byte, error)
+	Free()
+}
+
+// fogCodec encodes the fog messages on the wire as protobuf, the generated
+// protobuf messages are encoded as usual so it could be forced on a server
+// with other services.
+type fogCodec struct{}
+
+func (fogCodec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case fogMessage:
+		return m.marshal(), nil
+	case proto.Message:
+		return proto.Marshal(m)
+	}
+	return nil, fmt.Errorf("invalid fog message %T", v)
+}
+
+func (fogCodec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case fogMessage:
+		return m.unmarshal(data)
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	}
+	return fmt.Errorf("invalid fog message %T", v)
+}
+
+func (fogCodec) Name() string {
+	return FOG_CODEC_NAME
+}
+
+// FogServerCodec must be an option of the grpc servers registering the fog
+// view or ledger servers.
+func FogServerCodec() grpc.ServerOption {
+	return grpc.ForceServerCodec(fogCodec{})
+}
+
+// dialFogService dials the fog url, e.g. fog://fog.prod.mobilecoinww.com,
+// the responder id of the AKE is the host:port of the url.
+func dialFogService(fogUrl string, rootCAs *x509.CertPool) (*grpc.ClientConn, string, error) {
+	target, secure, err := fogReportTarget(fogUrl)
+	if err != nil {
+		return nil, "", err
+	}
+	creds := insecure.NewCredentials()
+	if secure {
+		creds = credentials.NewTLS(&tls.Config{RootCAs: rootCAs})
+	}
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, "", err
+	}
+	return conn, target, nil
+}
+
+// fogEnclaveClient calls the service of a fog enclave over the attested
+// channel, the channel is authenticated again after any failed call.
+type fogEnclaveClient struct {
+	conn        *grpc.ClientConn
+	service     string
+	responderID string
+	newCipher   func() (FogCipher, error)
+
+	mutex   sync.Mutex
+	cipher  FogCipher
+	binding []byte
+}
+
+func (c *fogEnclaveClient) auth(ctx context.Context) error {
+	if c.cipher != nil {
+		return nil
+	}
+	cipher, err := c.newCipher()
+	if err != nil {
+		return err
+	}
+	request, err := cipher.AuthRequest(c.responderID)
+	if err != nil {
+		cipher.Free()
+		return err
+	}
+	var resp AttestAuthMessage
+	err = c.conn.Invoke(ctx, "/"+c.service+"/Auth", &AttestAuthMessage{Data: request}, &resp, grpc.ForceCodec(fogCodec{}))
+	if err != nil {
+		cipher.Free()
+		return err
+	}
+	err = cipher.ProcessAuthResponse(resp.Data)
+	if err != nil {
+		cipher.Free()
+		return err
+	}
+	binding, err := cipher.Binding()
+	if err != nil {
+		cipher.Free()
+		return err
+	}
+	c.cipher, c.binding = cipher, binding
+	return nil
+}
+
+func (c *fogEnclaveClient) reset() {
+	if c.cipher != nil {
+		c.cipher.Free()
+	}
+	c.cipher, c.binding = nil, nil
+}
+
+// call encrypts req with the plaintext aad to the method of the service,
+// and decrypts the response to resp.
+func (c *fogEnclaveClient) call(ctx context.Context, method string, aad []byte, req, resp fogMessage) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.auth(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.invoke(ctx, method, aad, req, resp)
+	if err != nil {
+		c.reset()
+	}
+	return err
+}
+
+func (c *fogEnclaveClient) invoke(ctx context.Context, method string, aad []byte, req, resp fogMessage) error {
+	data, err := c.cipher.Encrypt(aad, req.marshal())
+	if err != nil {
+		return err
+	}
+	msg := &AttestMessage{Aad: aad, ChannelID: c.binding, Data: data}
+	var out AttestMessage
+	err = c.conn.Invoke(ctx, "/"+c.service+"/"+method, msg, &out, grpc.ForceCodec(fogCodec{}))
+	if err != nil {
+		return err
+	}
+	data, err = c.cipher.Decrypt(out.Aad, out.Data)
+	if err != nil {
+		return err
+	}
+	return resp.unmarshal(data)
+}
+
+func (c *fogEnclaveClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.reset()
+	return c.conn.Close()
+}
+
+// fogMethodDesc decodes the request of method to a new message of the type
+// of newReq, and passes it through the interceptor to handler.
+func fogMethodDesc(service, method string, newReq func() fogMessage, handler func(srv any, ctx context.Context, req fogMessage) (any, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := newReq()
+			err := dec(req)
+			if err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return handler(srv, ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + service + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return handler(srv, ctx, req.(fogMessage))
+			})
+		},
+	}
+}
+
+func newAttestAuthMessage() fogMessage { return &AttestAuthMessage{} }
+
+func newAttestMessage() fogMessage { return &AttestMessage{} }
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc"
+)
+
+// FogLedgerServer is the fog_ledger.FogKeyImageAPI service, CheckKeyImages
+// receives the FogCheckKeyImagesRequest sealed by the channel of Auth.
+type FogLedgerServer interface {
+	Auth(ctx context.Context, req *AttestAuthMessage) (*AttestAuthMessage, error)
+	CheckKeyImages(ctx context.Context, req *AttestMessage) (*AttestMessage, error)
+}
+
+// FogLedgerClient checks the key images in the fog ledger enclave
+type FogLedgerClient struct {
+	enclave *fogEnclaveClient
+}
+
+func NewFogLedgerClient(conn *grpc.ClientConn, responderID string, newCipher func() (FogCipher, error)) *FogLedgerClient {
+	return &FogLedgerClient{enclave: &fogEnclaveClient{
+		conn:        conn,
+		service:     FOG_LEDGER_SERVICE_NAME,
+		responderID: responderID,
+		newCipher:   newCipher,
+	}}
+}
+
+// DialFogLedgerClient attests the fog ledger enclave of fogUrl with verifier
+func DialFogLedgerClient(fogUrl string, verifier *FogVerifier, rootCAs *x509.CertPool) (*FogLedgerClient, error) {
+	conn, responderID, err := dialFogService(fogUrl, rootCAs)
+	if err != nil {
+		return nil, err
+	}
+	return NewFogLedgerClient(conn, responderID, func() (FogCipher, error) {
+		return NewAttestAke(verifier)
+	}), nil
+}
+
+func (c *FogLedgerClient) CheckKeyImages(ctx context.Context, req *FogCheckKeyImagesRequest) (*FogCheckKeyImagesResponse, error) {
+	var resp FogCheckKeyImagesResponse
+	err := c.enclave.call(ctx, "CheckKeyImages", nil, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *FogLedgerClient) Close() error {
+	return c.enclave.Close()
+}
+
+func RegisterFogLedgerServer(server *grpc.Server, srv FogLedgerServer) {
+	server.RegisterService(&fogLedgerServiceDesc, srv)
+}
+
+var fogLedgerServiceDesc = grpc.ServiceDesc{
+	ServiceName: FOG_LEDGER_SERVICE_NAME,
+	HandlerType: (*FogLedgerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		fogMethodDesc(FOG_LEDGER_SERVICE_NAME, "Auth", newAttestAuthMessage, func(srv any, ctx context.Context, req fogMessage) (any, error) {
+			return srv.(FogLedgerServer).Auth(ctx, req.(*AttestAuthMessage))
+		}),
+		fogMethodDesc(FOG_LEDGER_SERVICE_NAME, "CheckKeyImages", newAttestMessage, func(srv any, ctx context.Context, req fogMessage) (any, error) {
+			return srv.(FogLedgerServer).CheckKeyImages(ctx, req.(*AttestMessage))
+		}),
+	},
+}
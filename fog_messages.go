@@ -0,0 +1,750 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of the attest, kex_rng, fog_view and fog_ledger protos of
+// fog, encoded with protowire since their generated code is not in
+// mobilecoin-account. The numbers of the integer fields are decoded from any
+// of the varint, fixed32 and fixed64 wire types.
+
+const (
+	FOG_TX_OUT_SEARCH_RESULT_FOUND          = 1
+	FOG_TX_OUT_SEARCH_RESULT_NOT_FOUND      = 2
+	FOG_TX_OUT_SEARCH_RESULT_BAD_SEARCH_KEY = 3
+	FOG_TX_OUT_SEARCH_RESULT_INTERNAL_ERROR = 4
+	FOG_TX_OUT_SEARCH_RESULT_RATE_LIMITED   = 5
+
+	FOG_KEY_IMAGE_RESULT_SPENT     = 1
+	FOG_KEY_IMAGE_RESULT_NOT_SPENT = 2
+	FOG_KEY_IMAGE_RESULT_ERROR     = 3
+)
+
+// fogMessage is encoded by the fog codec
+type fogMessage interface {
+	marshal() []byte
+	unmarshal(data []byte) error
+}
+
+type AttestAuthMessage struct {
+	Data []byte
+}
+
+// AttestMessage is sealed by the AKE of the channel, Aad is in plaintext and
+// ChannelID is the binding of the channel.
+type AttestMessage struct {
+	Aad       []byte
+	ChannelID []byte
+	Data      []byte
+}
+
+type KexRngPubkey struct {
+	PublicKey []byte
+	Version   uint32
+}
+
+type FogQueryRequestAAD struct {
+	StartFromUserEventID int64
+	StartFromBlockIndex  uint64
+}
+
+// FogQueryRequest searches the fog view enclave for the TxOuts of the
+// search keys, which are the outputs of the fog rngs of the account.
+type FogQueryRequest struct {
+	GetTxos [][]byte
+}
+
+type FogQueryResponse struct {
+	HighestProcessedBlockCount              uint64
+	HighestProcessedBlockSignatureTimestamp uint64
+	NextStartFromUserEventID                int64
+	Rngs                                    []*FogRngRecord
+	DecommissionedIngestInvocations         []*FogDecommissionedIngestInvocation
+	MissedBlockRanges                       []*FogBlockRange
+	TxOutSearchResults                      []*FogTxOutSearchResult
+	FixedTxOutSearchResults                 []*FogFixedTxOutSearchResult
+	LastKnownBlockCount                     uint64
+	LastKnownBlockCumulativeTxoCount        uint64
+}
+
+// FogBlockRange is the blocks [Start, End) missed by fog ingest, the TxOuts
+// of them are only found by scanning the blocks from the ledger.
+type FogBlockRange struct {
+	Start uint64
+	End   uint64
+}
+
+type FogRngRecord struct {
+	IngestInvocationID int64
+	Pubkey             *KexRngPubkey
+	StartBlock         uint64
+}
+
+type FogDecommissionedIngestInvocation struct {
+	IngestInvocationID int64
+	LastIngestedBlock  uint64
+}
+
+type FogTxOutSearchResult struct {
+	SearchKey  []byte
+	ResultCode uint32
+	Ciphertext []byte
+}
+
+// FogFixedTxOutSearchResult has the ciphertext padded with zeros to a fixed
+// length, PayloadLength is the length of the ciphertext without padding.
+type FogFixedTxOutSearchResult struct {
+	SearchKey     []byte
+	ResultCode    uint32
+	Ciphertext    []byte
+	PayloadLength uint32
+}
+
+// FogTxOutRecord is the plaintext of a found FogTxOutSearchResult
+type FogTxOutRecord struct {
+	TxOutAmountMaskedValue     uint64
+	TxOutTargetKeyData         []byte
+	TxOutPublicKeyData         []byte
+	TxOutGlobalIndex           uint64
+	BlockIndex                 uint64
+	Timestamp                  uint64
+	TxOutAmountCommitmentCrc32 uint32
+	TxOutEMemoData             []byte
+	TxOutAmountMaskedV1TokenID []byte
+	TxOutAmountMaskedV2TokenID []byte
+	TxOutAmountCommitmentData  []byte
+}
+
+type FogCheckKeyImagesRequest struct {
+	Queries []*FogKeyImageQuery
+}
+
+type FogKeyImageQuery struct {
+	KeyImage   []byte
+	StartBlock uint64
+}
+
+type FogCheckKeyImagesResponse struct {
+	NumBlocks          uint64
+	GlobalTxoCount     uint64
+	Results            []*FogKeyImageResult
+	LatestBlockVersion uint32
+}
+
+type FogKeyImageResult struct {
+	KeyImage            []byte
+	SpentAt             uint64
+	Timestamp           uint64
+	TimestampResultCode uint32
+	KeyImageResultCode  uint32
+}
+
+// protoField is a decoded field, v is the integer of the varint and fixed
+// types and b the bytes of the length delimited type.
+type protoField struct {
+	num protowire.Number
+	typ protowire.Type
+	v   uint64
+	b   []byte
+}
+
+func parseProtoFields(data []byte) ([]*protoField, error) {
+	var fields []*protoField
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		f := &protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.v, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			f.v = uint64(v)
+		case protowire.Fixed64Type:
+			f.v, n = protowire.ConsumeFixed64(data)
+		case protowire.BytesType:
+			var b []byte
+			b, n = protowire.ConsumeBytes(data)
+			f.b = bytes.Clone(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func (f *protoField) bytes() ([]byte, error) {
+	if f.typ != protowire.BytesType {
+		return nil, fmt.Errorf("invalid field %d wire type %d", f.num, f.typ)
+	}
+	return f.b, nil
+}
+
+func (f *protoField) uint64() (uint64, error) {
+	switch f.typ {
+	case protowire.VarintType, protowire.Fixed32Type, protowire.Fixed64Type:
+		return f.v, nil
+	}
+	return 0, fmt.Errorf("invalid field %d wire type %d", f.num, f.typ)
+}
+
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendProtoVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendProtoFixed32(b []byte, num protowire.Number, v uint32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, v)
+}
+
+func appendProtoMessage(b []byte, num protowire.Number, m fogMessage) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.marshal())
+}
+
+// unmarshalProtoFields calls set with each field, unknown fields are ignored
+// by set returning nil.
+func unmarshalProtoFields(data []byte, set func(f *protoField) error) error {
+	fields, err := parseProtoFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		err = set(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *AttestAuthMessage) marshal() []byte {
+	return appendProtoBytes(nil, 1, m.Data)
+}
+
+func (m *AttestAuthMessage) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		if f.num == 1 {
+			m.Data, err = f.bytes()
+		}
+		return err
+	})
+}
+
+func (m *AttestMessage) marshal() []byte {
+	b := appendProtoBytes(nil, 1, m.Aad)
+	b = appendProtoBytes(b, 2, m.ChannelID)
+	return appendProtoBytes(b, 3, m.Data)
+}
+
+func (m *AttestMessage) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		switch f.num {
+		case 1:
+			m.Aad, err = f.bytes()
+		case 2:
+			m.ChannelID, err = f.bytes()
+		case 3:
+			m.Data, err = f.bytes()
+		}
+		return err
+	})
+}
+
+func (m *KexRngPubkey) marshal() []byte {
+	b := appendProtoBytes(nil, 1, m.PublicKey)
+	return appendProtoVarint(b, 2, uint64(m.Version))
+}
+
+func (m *KexRngPubkey) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		switch f.num {
+		case 1:
+			m.PublicKey, err = f.bytes()
+		case 2:
+			var v uint64
+			v, err = f.uint64()
+			m.Version = uint32(v)
+		}
+		return err
+	})
+}
+
+func (m *FogQueryRequestAAD) marshal() []byte {
+	b := appendProtoVarint(nil, 1, uint64(m.StartFromUserEventID))
+	return appendProtoVarint(b, 2, m.StartFromBlockIndex)
+}
+
+func (m *FogQueryRequestAAD) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var v uint64
+		switch f.num {
+		case 1:
+			v, err = f.uint64()
+			m.StartFromUserEventID = int64(v)
+		case 2:
+			m.StartFromBlockIndex, err = f.uint64()
+		}
+		return err
+	})
+}
+
+func (m *FogQueryRequest) marshal() []byte {
+	var b []byte
+	for _, key := range m.GetTxos {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, key)
+	}
+	return b
+}
+
+func (m *FogQueryRequest) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		key, err := f.bytes()
+		m.GetTxos = append(m.GetTxos, key)
+		return err
+	})
+}
+
+func (m *FogQueryResponse) marshal() []byte {
+	b := appendProtoVarint(nil, 1, m.HighestProcessedBlockCount)
+	b = appendProtoVarint(b, 2, m.HighestProcessedBlockSignatureTimestamp)
+	b = appendProtoVarint(b, 3, uint64(m.NextStartFromUserEventID))
+	for _, r := range m.MissedBlockRanges {
+		b = appendProtoMessage(b, 4, r)
+	}
+	for _, r := range m.Rngs {
+		b = appendProtoMessage(b, 5, r)
+	}
+	for _, d := range m.DecommissionedIngestInvocations {
+		b = appendProtoMessage(b, 6, d)
+	}
+	for _, r := range m.TxOutSearchResults {
+		b = appendProtoMessage(b, 7, r)
+	}
+	b = appendProtoVarint(b, 8, m.LastKnownBlockCount)
+	b = appendProtoVarint(b, 9, m.LastKnownBlockCumulativeTxoCount)
+	for _, r := range m.FixedTxOutSearchResults {
+		b = appendProtoMessage(b, 10, r)
+	}
+	return b
+}
+
+func (m *FogQueryResponse) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) error {
+		var v uint64
+		var b []byte
+		var err error
+		switch f.num {
+		case 1:
+			m.HighestProcessedBlockCount, err = f.uint64()
+		case 2:
+			m.HighestProcessedBlockSignatureTimestamp, err = f.uint64()
+		case 3:
+			v, err = f.uint64()
+			m.NextStartFromUserEventID = int64(v)
+		case 4:
+			b, err = f.bytes()
+			if err == nil {
+				r := &FogBlockRange{}
+				m.MissedBlockRanges = append(m.MissedBlockRanges, r)
+				err = r.unmarshal(b)
+			}
+		case 5:
+			b, err = f.bytes()
+			if err == nil {
+				r := &FogRngRecord{}
+				m.Rngs = append(m.Rngs, r)
+				err = r.unmarshal(b)
+			}
+		case 6:
+			b, err = f.bytes()
+			if err == nil {
+				d := &FogDecommissionedIngestInvocation{}
+				m.DecommissionedIngestInvocations = append(m.DecommissionedIngestInvocations, d)
+				err = d.unmarshal(b)
+			}
+		case 7:
+			b, err = f.bytes()
+			if err == nil {
+				r := &FogTxOutSearchResult{}
+				m.TxOutSearchResults = append(m.TxOutSearchResults, r)
+				err = r.unmarshal(b)
+			}
+		case 8:
+			m.LastKnownBlockCount, err = f.uint64()
+		case 9:
+			m.LastKnownBlockCumulativeTxoCount, err = f.uint64()
+		case 10:
+			b, err = f.bytes()
+			if err == nil {
+				r := &FogFixedTxOutSearchResult{}
+				m.FixedTxOutSearchResults = append(m.FixedTxOutSearchResults, r)
+				err = r.unmarshal(b)
+			}
+		}
+		return err
+	})
+}
+
+// SearchResults are the TxOutSearchResults and the FixedTxOutSearchResults
+// without padding, a search key in both is only kept once.
+func (m *FogQueryResponse) SearchResults() ([]*FogTxOutSearchResult, error) {
+	results := make([]*FogTxOutSearchResult, 0, len(m.TxOutSearchResults)+len(m.FixedTxOutSearchResults))
+	keys := make(map[string]bool)
+	for _, r := range m.TxOutSearchResults {
+		if keys[string(r.SearchKey)] {
+			continue
+		}
+		keys[string(r.SearchKey)] = true
+		results = append(results, r)
+	}
+	for _, r := range m.FixedTxOutSearchResults {
+		if keys[string(r.SearchKey)] {
+			continue
+		}
+		if int(r.PayloadLength) > len(r.Ciphertext) {
+			return nil, fmt.Errorf("fog search key %x payload length %d of ciphertext %d", r.SearchKey, r.PayloadLength, len(r.Ciphertext))
+		}
+		keys[string(r.SearchKey)] = true
+		results = append(results, &FogTxOutSearchResult{
+			SearchKey:  r.SearchKey,
+			ResultCode: r.ResultCode,
+			Ciphertext: r.Ciphertext[:r.PayloadLength],
+		})
+	}
+	return results, nil
+}
+
+func (m *FogBlockRange) marshal() []byte {
+	b := appendProtoVarint(nil, 1, m.Start)
+	return appendProtoVarint(b, 2, m.End)
+}
+
+func (m *FogBlockRange) unmarshal(data []byte) error {
+	err := unmarshalProtoFields(data, func(f *protoField) (err error) {
+		switch f.num {
+		case 1:
+			m.Start, err = f.uint64()
+		case 2:
+			m.End, err = f.uint64()
+		}
+		return err
+	})
+	if err == nil && m.End < m.Start {
+		return fmt.Errorf("invalid fog block range %d-%d", m.Start, m.End)
+	}
+	return err
+}
+
+func (m *FogRngRecord) marshal() []byte {
+	b := appendProtoVarint(nil, 1, uint64(m.IngestInvocationID))
+	if m.Pubkey != nil {
+		b = appendProtoMessage(b, 2, m.Pubkey)
+	}
+	return appendProtoVarint(b, 3, m.StartBlock)
+}
+
+func (m *FogRngRecord) unmarshal(data []byte) error {
+	err := unmarshalProtoFields(data, func(f *protoField) error {
+		var v uint64
+		var b []byte
+		var err error
+		switch f.num {
+		case 1:
+			v, err = f.uint64()
+			m.IngestInvocationID = int64(v)
+		case 2:
+			b, err = f.bytes()
+			if err == nil {
+				m.Pubkey = &KexRngPubkey{}
+				err = m.Pubkey.unmarshal(b)
+			}
+		case 3:
+			m.StartBlock, err = f.uint64()
+		}
+		return err
+	})
+	if err == nil && m.Pubkey == nil {
+		return errors.New("empty fog rng pubkey")
+	}
+	return err
+}
+
+func (m *FogDecommissionedIngestInvocation) marshal() []byte {
+	b := appendProtoVarint(nil, 1, uint64(m.IngestInvocationID))
+	return appendProtoVarint(b, 2, m.LastIngestedBlock)
+}
+
+func (m *FogDecommissionedIngestInvocation) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var v uint64
+		switch f.num {
+		case 1:
+			v, err = f.uint64()
+			m.IngestInvocationID = int64(v)
+		case 2:
+			m.LastIngestedBlock, err = f.uint64()
+		}
+		return err
+	})
+}
+
+func (m *FogTxOutSearchResult) marshal() []byte {
+	b := appendProtoBytes(nil, 1, m.SearchKey)
+	b = appendProtoFixed32(b, 2, m.ResultCode)
+	return appendProtoBytes(b, 3, m.Ciphertext)
+}
+
+func (m *FogTxOutSearchResult) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var v uint64
+		switch f.num {
+		case 1:
+			m.SearchKey, err = f.bytes()
+		case 2:
+			v, err = f.uint64()
+			m.ResultCode = uint32(v)
+		case 3:
+			m.Ciphertext, err = f.bytes()
+		}
+		return err
+	})
+}
+
+func (m *FogFixedTxOutSearchResult) marshal() []byte {
+	b := appendProtoBytes(nil, 1, m.SearchKey)
+	b = appendProtoFixed32(b, 2, m.ResultCode)
+	b = appendProtoBytes(b, 3, m.Ciphertext)
+	return appendProtoFixed32(b, 4, m.PayloadLength)
+}
+
+func (m *FogFixedTxOutSearchResult) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var v uint64
+		switch f.num {
+		case 1:
+			m.SearchKey, err = f.bytes()
+		case 2:
+			v, err = f.uint64()
+			m.ResultCode = uint32(v)
+		case 3:
+			m.Ciphertext, err = f.bytes()
+		case 4:
+			v, err = f.uint64()
+			m.PayloadLength = uint32(v)
+		}
+		return err
+	})
+}
+
+func (m *FogTxOutRecord) marshal() []byte {
+	b := appendProtoBytes(nil, 1, m.TxOutAmountCommitmentData)
+	b = appendProtoFixed64(b, 2, m.TxOutAmountMaskedValue)
+	b = appendProtoBytes(b, 3, m.TxOutTargetKeyData)
+	b = appendProtoBytes(b, 4, m.TxOutPublicKeyData)
+	b = appendProtoFixed64(b, 5, m.TxOutGlobalIndex)
+	b = appendProtoFixed64(b, 6, m.BlockIndex)
+	b = appendProtoFixed64(b, 7, m.Timestamp)
+	b = appendProtoFixed32(b, 8, m.TxOutAmountCommitmentCrc32)
+	b = appendProtoBytes(b, 9, m.TxOutEMemoData)
+	b = appendProtoBytes(b, 10, m.TxOutAmountMaskedV1TokenID)
+	return appendProtoBytes(b, 11, m.TxOutAmountMaskedV2TokenID)
+}
+
+func (m *FogTxOutRecord) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var v uint64
+		switch f.num {
+		case 1:
+			m.TxOutAmountCommitmentData, err = f.bytes()
+		case 2:
+			m.TxOutAmountMaskedValue, err = f.uint64()
+		case 3:
+			m.TxOutTargetKeyData, err = f.bytes()
+		case 4:
+			m.TxOutPublicKeyData, err = f.bytes()
+		case 5:
+			m.TxOutGlobalIndex, err = f.uint64()
+		case 6:
+			m.BlockIndex, err = f.uint64()
+		case 7:
+			m.Timestamp, err = f.uint64()
+		case 8:
+			v, err = f.uint64()
+			m.TxOutAmountCommitmentCrc32 = uint32(v)
+		case 9:
+			m.TxOutEMemoData, err = f.bytes()
+		case 10:
+			m.TxOutAmountMaskedV1TokenID, err = f.bytes()
+		case 11:
+			m.TxOutAmountMaskedV2TokenID, err = f.bytes()
+		}
+		return err
+	})
+}
+
+// the key image is the external.KeyImage message
+func (m *FogKeyImageQuery) marshal() []byte {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, appendProtoBytes(nil, 1, m.KeyImage))
+	return appendProtoFixed64(b, 2, m.StartBlock)
+}
+
+func (m *FogKeyImageQuery) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var b []byte
+		switch f.num {
+		case 1:
+			b, err = f.bytes()
+			if err == nil {
+				m.KeyImage, err = unmarshalKeyImage(b)
+			}
+		case 2:
+			m.StartBlock, err = f.uint64()
+		}
+		return err
+	})
+}
+
+func (m *FogCheckKeyImagesRequest) marshal() []byte {
+	var b []byte
+	for _, q := range m.Queries {
+		b = appendProtoMessage(b, 1, q)
+	}
+	return b
+}
+
+func (m *FogCheckKeyImagesRequest) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		b, err := f.bytes()
+		if err != nil {
+			return err
+		}
+		q := &FogKeyImageQuery{}
+		m.Queries = append(m.Queries, q)
+		return q.unmarshal(b)
+	})
+}
+
+func (m *FogCheckKeyImagesResponse) marshal() []byte {
+	b := appendProtoVarint(nil, 1, m.NumBlocks)
+	b = appendProtoVarint(b, 2, m.GlobalTxoCount)
+	for _, r := range m.Results {
+		b = appendProtoMessage(b, 3, r)
+	}
+	return appendProtoVarint(b, 4, uint64(m.LatestBlockVersion))
+}
+
+func (m *FogCheckKeyImagesResponse) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) error {
+		var v uint64
+		var b []byte
+		var err error
+		switch f.num {
+		case 1:
+			m.NumBlocks, err = f.uint64()
+		case 2:
+			m.GlobalTxoCount, err = f.uint64()
+		case 3:
+			b, err = f.bytes()
+			if err == nil {
+				r := &FogKeyImageResult{}
+				m.Results = append(m.Results, r)
+				err = r.unmarshal(b)
+			}
+		case 4:
+			v, err = f.uint64()
+			m.LatestBlockVersion = uint32(v)
+		}
+		return err
+	})
+}
+
+func (m *FogKeyImageResult) marshal() []byte {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, appendProtoBytes(nil, 1, m.KeyImage))
+	b = appendProtoFixed64(b, 2, m.SpentAt)
+	b = appendProtoFixed64(b, 3, m.Timestamp)
+	b = appendProtoFixed32(b, 4, m.TimestampResultCode)
+	return appendProtoFixed32(b, 5, m.KeyImageResultCode)
+}
+
+func (m *FogKeyImageResult) unmarshal(data []byte) error {
+	return unmarshalProtoFields(data, func(f *protoField) (err error) {
+		var v uint64
+		var b []byte
+		switch f.num {
+		case 1:
+			b, err = f.bytes()
+			if err == nil {
+				m.KeyImage, err = unmarshalKeyImage(b)
+			}
+		case 2:
+			m.SpentAt, err = f.uint64()
+		case 3:
+			m.Timestamp, err = f.uint64()
+		case 4:
+			v, err = f.uint64()
+			m.TimestampResultCode = uint32(v)
+		case 5:
+			v, err = f.uint64()
+			m.KeyImageResultCode = uint32(v)
+		}
+		return err
+	})
+}
+
+func unmarshalKeyImage(data []byte) ([]byte, error) {
+	var keyImage []byte
+	err := unmarshalProtoFields(data, func(f *protoField) (err error) {
+		if f.num == 1 {
+			keyImage, err = f.bytes()
+		}
+		return err
+	})
+	return keyImage, err
+}
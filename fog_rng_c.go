@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/bwesterb/go-ristretto"
+)
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo darwin LDFLAGS: ${SRCDIR}/include/libmobilecoin.a -framework Security -framework Foundation
+// #cgo linux LDFLAGS: ${SRCDIR}/include/libmobilecoin_linux.a -lm -ldl
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <errno.h>
+// #include "libmobilecoin.h"
+import "C"
+
+// FogRngC is the kex rng of an ingest invocation, its outputs are the search
+// keys of the TxOuts received by the subaddress. It must be freed by Free.
+type FogRngC struct {
+	rng *C.McFogRng
+}
+
+func NewFogRngC(subaddressViewPrivate *ristretto.Scalar, pubkey *KexRngPubkey) (*FogRngC, error) {
+	subaddress_view_private_key, free_subaddress_view_private_key := newBufferC(subaddressViewPrivate.Bytes())
+	defer free_subaddress_view_private_key()
+	rng_public_key, free_rng_public_key := newBufferC(pubkey.PublicKey)
+	defer free_rng_public_key()
+
+	var out_error *C.McError
+	fog_rng := C.mc_fog_rng_create(subaddress_view_private_key, rng_public_key, C.uint32_t(pubkey.Version), &out_error)
+	if fog_rng == nil {
+		return nil, mcError("mc_fog_rng_create", out_error)
+	}
+	return &FogRngC{rng: fog_rng}, nil
+}
+
+// UnmarshalFogRngC restores the rng serialized by Marshal
+func UnmarshalFogRngC(data []byte) (*FogRngC, error) {
+	fog_rng_proto_bytes, free_fog_rng_proto_bytes := newBufferC(data)
+	defer free_fog_rng_proto_bytes()
+
+	var out_error *C.McError
+	fog_rng := C.mc_fog_rng_deserialize_proto(fog_rng_proto_bytes, &out_error)
+	if fog_rng == nil {
+		return nil, mcError("mc_fog_rng_deserialize_proto", out_error)
+	}
+	return &FogRngC{rng: fog_rng}, nil
+}
+
+func (r *FogRngC) Free() {
+	C.mc_fog_rng_free(r.rng)
+	r.rng = nil
+}
+
+func (r *FogRngC) Index() int64 {
+	return int64(C.mc_fog_rng_index(r.rng))
+}
+
+// Peek is the next output without advancing the rng
+func (r *FogRngC) Peek() ([]byte, error) {
+	output_len := C.mc_fog_rng_get_output_len(r.rng)
+	if output_len <= 0 {
+		return nil, errors.New("mc_fog_rng_get_output_len failure")
+	}
+	out_output, free_out_output := newMutableBufferC(int(output_len))
+	defer free_out_output()
+	b := C.mc_fog_rng_peek(r.rng, out_output)
+	if !b {
+		return nil, errors.New("mc_fog_rng_peek failure")
+	}
+	return mutableBufferBytes(out_output), nil
+}
+
+func (r *FogRngC) Advance() error {
+	var out_output *C.McMutableBuffer
+	b := C.mc_fog_rng_advance(r.rng, out_output)
+	if !b {
+		return errors.New("mc_fog_rng_advance failure")
+	}
+	return nil
+}
+
+// Marshal, the first call with a nil buffer gets the size
+func (r *FogRngC) Marshal() ([]byte, error) {
+	var out_size *C.McMutableBuffer
+	proto_size := C.mc_fog_rng_serialize_proto(r.rng, out_size)
+	if proto_size < 0 {
+		return nil, errors.New("mc_fog_rng_serialize_proto failure")
+	}
+	out_fog_rng_proto_bytes, free_out_fog_rng_proto_bytes := newMutableBufferC(int(proto_size))
+	defer free_out_fog_rng_proto_bytes()
+	proto_size = C.mc_fog_rng_serialize_proto(r.rng, out_fog_rng_proto_bytes)
+	if proto_size < 0 {
+		return nil, errors.New("mc_fog_rng_serialize_proto failure")
+	}
+	return C.GoBytes(unsafe.Pointer(out_fog_rng_proto_bytes.buffer), C.int(proto_size)), nil
+}
+
+// MCVersionedCryptoBoxDecrypt opens the ciphertext sealed to the public key
+// of private, e.g. the TxOut records of fog.
+func MCVersionedCryptoBoxDecrypt(private *ristretto.Scalar, ciphertext []byte) ([]byte, error) {
+	private_key, free_private_key := newBufferC(private.Bytes())
+	defer free_private_key()
+	c_ciphertext, free_ciphertext := newBufferC(ciphertext)
+	defer free_ciphertext()
+	out_plaintext, free_out_plaintext := newMutableBufferC(len(ciphertext))
+	defer free_out_plaintext()
+
+	var out_error *C.McError
+	plaintext_size := C.mc_versioned_crypto_box_decrypt(private_key, c_ciphertext, out_plaintext, &out_error)
+	if plaintext_size < 0 {
+		return nil, mcError("mc_versioned_crypto_box_decrypt", out_error)
+	}
+	return C.GoBytes(unsafe.Pointer(out_plaintext.buffer), C.int(plaintext_size)), nil
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/MixinNetwork/mobilecoin-account/types"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 )
 
 // Since there's some compile bugs for libmobilecoind in OSX, the tests can't pass in MAC
@@ -24,12 +29,69 @@ func TestFogAddress(t *testing.T) {
 	err = ValidateAddress("PioPgoqXcixip4A59Sqncq3PbUAZ47CRT5AtGWYzy4KD9PnqdxGnrUx6pPHvKp7hZaSQui6bdfKVpgfe325y4zbte2a1fsoerAGsjaY4fsK9j1SsywvUN2smRbY3vGDHxyaCkvvqAhQNHLS9hNgzUxXZaBwtHX1oC2BWGE67oh3gXzbiY1G1gYatwt8pfRNjuGcyJUJgD7Q4mkx7DafKAQLAKT9JVmzQpdaPEZvEDzDcEGLn")
 	assert.NotNil(err)
 
-	// testnet address, should uncommit fetchValidFogEnclave,
-	// and uncommit myenclaves first
-	// err = ValidateAddress("3QotDchXAPpHLKELeERNRNZPRzud8kLm9FUed5rsf1zVn7dYgBxbQii7AUt5TR2tFK7TAcTEzEHYGYBRrJM8kbZ9nsmE4E5v68BViA9LBt2ogJ8aNFG7XhGpVEDUCd4j1s1NfVWPTTj68afWrYBRQrJnW5ZhAhMuujf7RgBbE4G9jfRjRgHDM2JWACbKnSgzpBK1skwqdgnE1hejdTSbcHuSYvZgUEdcFuPmcMgFZ6YkheFvf")
+	// testnet address
+	// err = ValidateAddressWithTrust(context.Background(), "3QotDchXAPpHLKELeERNRNZPRzud8kLm9FUed5rsf1zVn7dYgBxbQii7AUt5TR2tFK7TAcTEzEHYGYBRrJM8kbZ9nsmE4E5v68BViA9LBt2ogJ8aNFG7XhGpVEDUCd4j1s1NfVWPTTj68afWrYBRQrJnW5ZhAhMuujf7RgBbE4G9jfRjRgHDM2JWACbKnSgzpBK1skwqdgnE1hejdTSbcHuSYvZgUEdcFuPmcMgFZ6YkheFvf", TestnetFogTrustConfig(), DefaultFogReportFetcher)
 	// assert.Nil(err)
 
-	// testnet address, should un commit fetchValidFogEnclave
-	// err = ValidateAddress("4oD5TztGGtEQDgnenFQALLAtBYFT6CNXYezKVqPtpt7ypSGJLCuJKdS3ZiKNTnrNx25AF7kMFFbNki1bdYod9Thv6WBfUYZZdh51XyYKhqf1thG5zFynikXTRzczZETYWhg2ARJckiFvkVtrRczvcuS9egux6or2WA9J7dTZVV3523ghZVZ1BcJ63A3P7RUzmH1FhHnwCPrgk1teC8dkhaNUpyvP5KwELSPveXGpSrgi9L1Ym")
+	// testnet address
+	// err = ValidateAddressWithTrust(context.Background(), "4oD5TztGGtEQDgnenFQALLAtBYFT6CNXYezKVqPtpt7ypSGJLCuJKdS3ZiKNTnrNx25AF7kMFFbNki1bdYod9Thv6WBfUYZZdh51XyYKhqf1thG5zFynikXTRzczZETYWhg2ARJckiFvkVtrRczvcuS9egux6or2WA9J7dTZVV3523ghZVZ1BcJ63A3P7RUzmH1FhHnwCPrgk1teC8dkhaNUpyvP5KwELSPveXGpSrgi9L1Ym", TestnetFogTrustConfig(), DefaultFogReportFetcher)
 	// assert.Nil(err)
 }
+
+type testReportServer struct {
+	types.UnimplementedReportAPIServer
+	delay time.Duration
+}
+
+func (s *testReportServer) GetReports(ctx context.Context, in *types.ReportRequest) (*types.ReportResponse, error) {
+	time.Sleep(s.delay)
+	return &types.ReportResponse{Signature: []byte("signature")}, nil
+}
+
+func TestFogReportFetcher(t *testing.T) {
+	assert := assert.New(t)
+
+	target, secure, err := fogReportTarget("fog://fog.prod.mobilecoinww.com")
+	assert.Nil(err)
+	assert.True(secure)
+	assert.Equal("fog.prod.mobilecoinww.com:443", target)
+	target, secure, err = fogReportTarget("insecure-fog://localhost:3225")
+	assert.Nil(err)
+	assert.False(secure)
+	assert.Equal("localhost:3225", target)
+	_, _, err = fogReportTarget("fog://")
+	assert.NotNil(err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	server := grpc.NewServer()
+	report := &testReportServer{}
+	types.RegisterReportAPIServer(server, report)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	fogReportUrl := "insecure-fog://" + listener.Addr().String()
+	fetcher := NewGRPCFogReportFetcher(nil, time.Second)
+	res, err := fetcher.FetchReports(context.Background(), fogReportUrl)
+	assert.Nil(err)
+	assert.Equal([]byte("signature"), res.Signature)
+
+	report.delay = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.FetchReports(ctx, fogReportUrl)
+	assert.NotNil(err)
+	_, err = NewGRPCFogReportFetcher(nil, 50*time.Millisecond).FetchReports(context.Background(), fogReportUrl)
+	assert.NotNil(err)
+
+	static := NewStaticFogReportFetcher(map[string]*types.ReportResponse{fogReportUrl: res})
+	cached, err := static.FetchReports(context.Background(), fogReportUrl)
+	assert.Nil(err)
+	assert.Equal(res, cached)
+	_, err = static.FetchReports(context.Background(), "fog://fog.prod.mobilecoinww.com")
+	assert.NotNil(err)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = static.FetchReports(canceled, fogReportUrl)
+	assert.NotNil(err)
+}
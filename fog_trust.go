@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FogMrSigner trusts all the enclaves signed by MrSigner with the product id
+// and at least the security version.
+type FogMrSigner struct {
+	MrSigner        string `json:"mr_signer" yaml:"mr_signer"`
+	ProductID       uint16 `json:"product_id" yaml:"product_id"`
+	SecurityVersion uint16 `json:"security_version" yaml:"security_version"`
+}
+
+type FogHostTrust struct {
+	MrEnclaves          []string       `json:"mr_enclaves" yaml:"mr_enclaves"`
+	MrSigners           []*FogMrSigner `json:"mr_signers" yaml:"mr_signers"`
+	HardeningAdvisories []string       `json:"hardening_advisories" yaml:"hardening_advisories"`
+}
+
+// FogTrustConfig maps the fog report urls without port, e.g.
+// fog://fog.prod.mobilecoinww.com, to the attestation they must pass.
+type FogTrustConfig struct {
+	Hosts map[string]*FogHostTrust `json:"hosts" yaml:"hosts"`
+}
+
+// fogMeasurement is a single trusted enclave or signer with its advisories
+type fogMeasurement struct {
+	MrEnclave           string
+	MrSigner            *FogMrSigner
+	HardeningAdvisories []string
+}
+
+// FogVerifier accepts the fog report of each fog host attested by any of the
+// enclaves or signers trusted for that host, a verifier without hosts applies
+// its measurements to all of them. It could be reused for all the
+// transactions to the same fog report urls.
+type FogVerifier struct {
+	measurements []*fogMeasurement
+	hosts        map[string][]*fogMeasurement
+}
+
+var fogMainnetHardeningAdvisories = []string{"INTEL-SA-00334", "INTEL-SA-00615", "INTEL-SA-00657"}
+
+var fogMainnetEnclaves = []string{
+	"7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b", // v5.0.0
+	"a8af815564569aae3558d8e4e4be14d1bcec896623166a10494b4eaea3e1c48c", // v4.0.0
+	"3370f131b41e5a49ed97c4188f7a976461ac6127f8d222a37929ac46b46d560e", // v3.0.0
+	"3e9bf61f3191add7b054f0e591b62f832854606f6594fd63faef1e2aedec4021", // lower than v3.0.0
+}
+
+var DefaultFogTrustConfig = MainnetFogTrustConfig()
+
+func MainnetFogTrustConfig() *FogTrustConfig {
+	hosts := []string{
+		"fog://fog.prod.mobilecoinww.com",
+		"fog://service.fog.mob.production.namda.net",
+		"fog://fog-rpt-prd.namda.net",
+	}
+	config := &FogTrustConfig{Hosts: make(map[string]*FogHostTrust)}
+	for _, host := range hosts {
+		config.Hosts[host] = &FogHostTrust{
+			MrEnclaves:          slices.Clone(fogMainnetEnclaves),
+			HardeningAdvisories: slices.Clone(fogMainnetHardeningAdvisories),
+		}
+	}
+	return config
+}
+
+func TestnetFogTrustConfig() *FogTrustConfig {
+	return &FogTrustConfig{Hosts: map[string]*FogHostTrust{
+		"fog://fog.test.mobilecoin.com": {
+			MrEnclaves:          []string{"248356aa0d3431abc45da1773cfd6191a4f2989a4a99da31f450bd7c461e312b"}, // v5.0.0 testnet
+			HardeningAdvisories: slices.Clone(fogMainnetHardeningAdvisories),
+		},
+		"fog://service.fog.mob.staging.namda.net": {
+			MrEnclaves:          []string{"a4764346f91979b4906d4ce26102228efe3aba39216dec1e7d22e6b06f919f11"},
+			HardeningAdvisories: slices.Clone(fogMainnetHardeningAdvisories),
+		},
+	}}
+}
+
+// LoadFogTrustConfig reads the config from a .json, .yaml or .yml file
+func LoadFogTrustConfig(path string) (*FogTrustConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".json":
+		return ParseFogTrustConfigJSON(data)
+	case ".yaml", ".yml":
+		return ParseFogTrustConfigYAML(data)
+	default:
+		return nil, fmt.Errorf("unknown fog trust config format %s", path)
+	}
+}
+
+func ParseFogTrustConfigJSON(data []byte) (*FogTrustConfig, error) {
+	var config FogTrustConfig
+	err := json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, config.Validate()
+}
+
+func ParseFogTrustConfigYAML(data []byte) (*FogTrustConfig, error) {
+	var config FogTrustConfig
+	err := yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, config.Validate()
+}
+
+func (c *FogTrustConfig) Validate() error {
+	if len(c.Hosts) == 0 {
+		return fmt.Errorf("empty fog trust config")
+	}
+	for host, trust := range c.Hosts {
+		if trust == nil || len(trust.MrEnclaves)+len(trust.MrSigners) == 0 {
+			return fmt.Errorf("no enclave or signer for fog host %s", host)
+		}
+		if fogHostKey(host) != host {
+			return fmt.Errorf("invalid fog host %s", host)
+		}
+		for _, enclave := range trust.MrEnclaves {
+			err := validateMeasurementHex(enclave)
+			if err != nil {
+				return fmt.Errorf("invalid fog host %s mr enclave %s: %v", host, enclave, err)
+			}
+		}
+		for _, signer := range trust.MrSigners {
+			if signer == nil {
+				return fmt.Errorf("invalid fog host %s mr signer", host)
+			}
+			err := validateMeasurementHex(signer.MrSigner)
+			if err != nil {
+				return fmt.Errorf("invalid fog host %s mr signer %s: %v", host, signer.MrSigner, err)
+			}
+		}
+	}
+	return nil
+}
+
+// HostTrust finds the trust of a fog report url, the port is ignored.
+func (c *FogTrustConfig) HostTrust(fogReportUrl string) (*FogHostTrust, error) {
+	trust := c.Hosts[fogHostKey(fogReportUrl)]
+	if trust == nil {
+		return nil, fmt.Errorf("No enclave hex for Address' fog url %s", fogReportUrl)
+	}
+	return trust, nil
+}
+
+// Verifier trusts for each of the fog report urls only the enclaves and
+// signers of its own host, with the advisories of that host.
+func (c *FogTrustConfig) Verifier(fogReportUrls []string) (*FogVerifier, error) {
+	return c.verifier(fogReportUrls, func(trust *FogHostTrust) []*fogMeasurement {
+		return trust.measurements()
+	})
+}
+
+// EnclaveVerifier trusts only enclave for all the fog report urls, with the
+// advisories of each host.
+func (c *FogTrustConfig) EnclaveVerifier(enclave string, fogReportUrls []string) (*FogVerifier, error) {
+	return c.verifier(fogReportUrls, func(trust *FogHostTrust) []*fogMeasurement {
+		m := &fogMeasurement{
+			MrEnclave:           enclave,
+			HardeningAdvisories: slices.Clone(trust.HardeningAdvisories),
+		}
+		return []*fogMeasurement{m}
+	})
+}
+
+func (c *FogTrustConfig) verifier(fogReportUrls []string, measurements func(*FogHostTrust) []*fogMeasurement) (*FogVerifier, error) {
+	verifier := &FogVerifier{hosts: make(map[string][]*fogMeasurement)}
+	for _, fogReportUrl := range fogReportUrls {
+		trust, err := c.HostTrust(fogReportUrl)
+		if err != nil {
+			return nil, err
+		}
+		host := fogHostKey(fogReportUrl)
+		if verifier.hosts[host] != nil {
+			continue
+		}
+		hostMeasurements := measurements(trust)
+		verifier.hosts[host] = hostMeasurements
+		verifier.measurements = append(verifier.measurements, hostMeasurements...)
+	}
+	if len(verifier.measurements) == 0 {
+		return nil, errors.New("no trusted fog enclave")
+	}
+	return verifier, nil
+}
+
+func NewFogVerifierWithEnclave(enclave string, advisories []string) *FogVerifier {
+	m := &fogMeasurement{
+		MrEnclave:           enclave,
+		HardeningAdvisories: slices.Clone(advisories),
+	}
+	return &FogVerifier{measurements: []*fogMeasurement{m}}
+}
+
+// host is the verifier of the fog host of fogReportUrl alone, nil when the
+// host isn't trusted.
+func (v *FogVerifier) host(fogReportUrl string) *FogVerifier {
+	if v == nil || v.hosts == nil {
+		return v
+	}
+	measurements := v.hosts[fogHostKey(fogReportUrl)]
+	if len(measurements) == 0 {
+		return nil
+	}
+	return &FogVerifier{measurements: measurements}
+}
+
+// measurements lists the enclaves and then the signers of the host, each with
+// the advisories of the host.
+func (trust *FogHostTrust) measurements() []*fogMeasurement {
+	var measurements []*fogMeasurement
+	for _, enclave := range trust.MrEnclaves {
+		measurements = append(measurements, &fogMeasurement{
+			MrEnclave:           enclave,
+			HardeningAdvisories: slices.Clone(trust.HardeningAdvisories),
+		})
+	}
+	for _, signer := range trust.MrSigners {
+		measurements = append(measurements, &fogMeasurement{
+			MrSigner:            signer,
+			HardeningAdvisories: slices.Clone(trust.HardeningAdvisories),
+		})
+	}
+	return measurements
+}
+
+func fogHostKey(fogReportUrl string) string {
+	uri, err := url.Parse(fogReportUrl)
+	if err != nil || uri.Scheme == "" || uri.Hostname() == "" {
+		return ""
+	}
+	return uri.Scheme + "://" + uri.Hostname()
+}
+
+func validateMeasurementHex(h string) error {
+	buf, err := hex.DecodeString(h)
+	if err != nil {
+		return err
+	}
+	if len(buf) != 32 {
+		return fmt.Errorf("invalid length %d", len(buf))
+	}
+	return nil
+}
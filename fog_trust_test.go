@@ -0,0 +1,89 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFogTrustConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	mainnet := MainnetFogTrustConfig()
+	assert.Nil(mainnet.Validate())
+	assert.Nil(TestnetFogTrustConfig().Validate())
+
+	trust, err := mainnet.HostTrust("fog://fog.prod.mobilecoinww.com:443")
+	assert.Nil(err)
+	assert.Len(trust.MrEnclaves, 4)
+	_, err = mainnet.HostTrust("fog://fog.test.mobilecoin.com")
+	assert.NotNil(err)
+
+	yamlConfig := `
+hosts:
+  fog://fog.example.com:
+    mr_enclaves:
+      - 7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b
+    mr_signers:
+      - mr_signer: 2c1a561c4ab64cbc04bfa445cdf7bed9b2ad6f6b04d38d3137f3622b29fdb30e
+        product_id: 4
+        security_version: 1
+    hardening_advisories: [INTEL-SA-00334]
+  fog://fog2.example.com:
+    mr_enclaves:
+      - 7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b
+    hardening_advisories: [INTEL-SA-00615]
+`
+	path := filepath.Join(t.TempDir(), "trust.yaml")
+	assert.Nil(os.WriteFile(path, []byte(yamlConfig), 0600))
+	config, err := LoadFogTrustConfig(path)
+	assert.Nil(err)
+	trust, err = config.HostTrust("fog://fog.example.com")
+	assert.Nil(err)
+	assert.Equal(uint16(4), trust.MrSigners[0].ProductID)
+
+	verifier, err := config.Verifier([]string{"fog://fog.example.com:443", "fog://fog2.example.com", "fog://fog.example.com"})
+	assert.Nil(err)
+	assert.Len(verifier.measurements, 3)
+	host := verifier.host("fog://fog.example.com:443")
+	assert.Len(host.measurements, 2)
+	assert.Equal("7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b", host.measurements[0].MrEnclave)
+	assert.Equal([]string{"INTEL-SA-00334"}, host.measurements[0].HardeningAdvisories)
+	assert.NotNil(host.measurements[1].MrSigner)
+	assert.Equal([]string{"INTEL-SA-00334"}, host.measurements[1].HardeningAdvisories)
+	host = verifier.host("fog://fog2.example.com")
+	assert.Len(host.measurements, 1)
+	assert.Equal("7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b", host.measurements[0].MrEnclave)
+	assert.Equal([]string{"INTEL-SA-00615"}, host.measurements[0].HardeningAdvisories)
+	assert.Nil(verifier.host("fog://fog3.example.com"))
+
+	verifier, err = config.EnclaveVerifier("a8af815564569aae3558d8e4e4be14d1bcec896623166a10494b4eaea3e1c48c", []string{"fog://fog.example.com", "fog://fog2.example.com"})
+	assert.Nil(err)
+	assert.Len(verifier.measurements, 2)
+	host = verifier.host("fog://fog2.example.com")
+	assert.Len(host.measurements, 1)
+	assert.Equal("a8af815564569aae3558d8e4e4be14d1bcec896623166a10494b4eaea3e1c48c", host.measurements[0].MrEnclave)
+	assert.Equal([]string{"INTEL-SA-00615"}, host.measurements[0].HardeningAdvisories)
+
+	verifier, err = mainnet.Verifier([]string{"fog://fog.prod.mobilecoinww.com", "fog://fog-rpt-prd.namda.net"})
+	assert.Nil(err)
+	assert.Len(verifier.measurements, 8)
+	assert.Len(verifier.host("fog://fog-rpt-prd.namda.net").measurements, 4)
+	_, err = mainnet.Verifier([]string{"fog://fog.prod.mobilecoinww.com", "fog://fog.test.mobilecoin.com"})
+	assert.NotNil(err)
+	_, err = mainnet.Verifier(nil)
+	assert.NotNil(err)
+
+	single := NewFogVerifierWithEnclave("a8af815564569aae3558d8e4e4be14d1bcec896623166a10494b4eaea3e1c48c", nil)
+	assert.Equal(single, single.host("fog://fog.example.com"))
+
+	_, err = ParseFogTrustConfigJSON([]byte(`{"hosts":{"fog://fog.example.com":{"mr_enclaves":["7d10"]}}}`))
+	assert.NotNil(err)
+	_, err = ParseFogTrustConfigJSON([]byte(`{"hosts":{"fog://fog.example.com:443":{"mr_enclaves":["7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b"]}}}`))
+	assert.NotNil(err)
+	config, err = ParseFogTrustConfigJSON([]byte(`{"hosts":{"fog://fog.example.com":{"mr_enclaves":["7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b"]}}}`))
+	assert.Nil(err)
+	assert.Len(config.Hosts, 1)
+}
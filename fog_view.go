@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc"
+)
+
+// FogViewServer is the fog_view.FogViewAPI service, Query receives the
+// FogQueryRequestAAD in plaintext and the FogQueryRequest sealed by the
+// channel of Auth.
+type FogViewServer interface {
+	Auth(ctx context.Context, req *AttestAuthMessage) (*AttestAuthMessage, error)
+	Query(ctx context.Context, req *AttestMessage) (*AttestMessage, error)
+}
+
+// FogViewClient searches the TxOuts in the fog view enclave
+type FogViewClient struct {
+	enclave *fogEnclaveClient
+}
+
+// NewFogViewClient with the channels created by newCipher, the responder id
+// is the host:port of the fog view service.
+func NewFogViewClient(conn *grpc.ClientConn, responderID string, newCipher func() (FogCipher, error)) *FogViewClient {
+	return &FogViewClient{enclave: &fogEnclaveClient{
+		conn:        conn,
+		service:     FOG_VIEW_SERVICE_NAME,
+		responderID: responderID,
+		newCipher:   newCipher,
+	}}
+}
+
+// DialFogViewClient attests the fog view enclave of fogUrl with verifier,
+// which must trust the view enclave instead of the ingest enclave of the fog
+// reports. A nil rootCAs uses the system pool.
+func DialFogViewClient(fogUrl string, verifier *FogVerifier, rootCAs *x509.CertPool) (*FogViewClient, error) {
+	conn, responderID, err := dialFogService(fogUrl, rootCAs)
+	if err != nil {
+		return nil, err
+	}
+	return NewFogViewClient(conn, responderID, func() (FogCipher, error) {
+		return NewAttestAke(verifier)
+	}), nil
+}
+
+func (c *FogViewClient) Query(ctx context.Context, aad *FogQueryRequestAAD, req *FogQueryRequest) (*FogQueryResponse, error) {
+	var resp FogQueryResponse
+	err := c.enclave.call(ctx, "Query", aad.marshal(), req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *FogViewClient) Close() error {
+	return c.enclave.Close()
+}
+
+func RegisterFogViewServer(server *grpc.Server, srv FogViewServer) {
+	server.RegisterService(&fogViewServiceDesc, srv)
+}
+
+var fogViewServiceDesc = grpc.ServiceDesc{
+	ServiceName: FOG_VIEW_SERVICE_NAME,
+	HandlerType: (*FogViewServer)(nil),
+	Methods: []grpc.MethodDesc{
+		fogMethodDesc(FOG_VIEW_SERVICE_NAME, "Auth", newAttestAuthMessage, func(srv any, ctx context.Context, req fogMessage) (any, error) {
+			return srv.(FogViewServer).Auth(ctx, req.(*AttestAuthMessage))
+		}),
+		fogMethodDesc(FOG_VIEW_SERVICE_NAME, "Query", newAttestMessage, func(srv any, ctx context.Context, req fogMessage) (any, error) {
+			return srv.(FogViewServer).Query(ctx, req.(*AttestMessage))
+		}),
+	},
+}
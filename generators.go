@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 
 	"github.com/bwesterb/go-ristretto"
+	"github.com/dchest/blake2b"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -22,6 +23,27 @@ func NewPedersenGens() *PedersenGens {
 	}
 }
 
+// NewPedersenGensForToken returns the amount generators of token, the
+// token 0 generators are the same as NewPedersenGens.
+func NewPedersenGensForToken(tokenID uint64) *PedersenGens {
+	var base ristretto.Point
+	base.SetBase()
+
+	hash := blake2b.New512()
+	hash.Write([]byte(HASH_TO_POINT_DOMAIN_TAG))
+	hash.Write(base.Bytes())
+	if tokenID != 0 {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], tokenID)
+		hash.Write(buf[:])
+	}
+
+	return &PedersenGens{
+		B:         pointFromUniformBytes(hash.Sum(nil)),
+		BBlinding: &base,
+	}
+}
+
 func DefaultPedersenGens() *PedersenGens {
 	var base ristretto.Point
 	base.SetBase()
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+)
+
+const (
+	GIFT_CODE_SUBADDRESS_INDEX = math.MaxUint64 - 2
+
+	GIFT_CODE_FUNDING_NOTE_MAX_LENGTH = 54
+	GIFT_CODE_SENDER_NOTE_MAX_LENGTH  = 58
+)
+
+// GiftCode is a one time gift account, its root entropy and the public key of
+// the funded TxOut are shared as the b58 Code.
+type GiftCode struct {
+	Code           string
+	RootEntropy    string
+	TxOutPublicKey string
+	Note           string
+}
+
+// FundGiftCode sends amount to a new gift account, the amount should cover the
+// fee to claim or cancel the gift. The change below the minimum change of the
+// token is rejected like TransactionBuilderBuildOutlaysWithMemo, and the note
+// is at most GIFT_CODE_FUNDING_NOTE_MAX_LENGTH bytes.
+func FundGiftCode(ctx context.Context, inputs []*UTXO, proofs *Proofs, amount, fee, tombstone uint64, tokenID, version uint, note, changeStr string, trust *FogTrustConfig, fetcher FogReportFetcher) (*GiftCode, *Output, error) {
+	err := checkGiftCodeNote(note, GIFT_CODE_FUNDING_NOTE_MAX_LENGTH)
+	if err != nil {
+		return nil, nil, err
+	}
+	change, err := account.DecodeB58Code(changeStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	var totalAmount uint64
+	for _, input := range inputs {
+		totalAmount += input.Amount
+	}
+	if totalAmount < amount+fee {
+		return nil, nil, fmt.Errorf("%w: %d, required %d", ErrInsufficientFunds, totalAmount, amount+fee)
+	}
+	changeAmount := totalAmount - amount - fee
+	err = checkChangeAmount(changeAmount, tokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	inputCs, err := BuildRingElements(inputs, proofs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entropy := make([]byte, 32)
+	_, err = rand.Read(entropy)
+	if err != nil {
+		return nil, nil, err
+	}
+	gift, err := giftCodeAccount(entropy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var verifier *FogVerifier
+	if changeAmount > 0 && change.FogReportUrl != "" {
+		verifier, err = trust.Verifier([]string{change.FogReportUrl})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	txC, err := MCTransactionBuilderFundGiftCodeC(ctx, inputCs, gift.ViewPrivateKey, gift.SpendPrivateKey, amount, changeAmount, fee, tombstone, tokenID, version, note, change, verifier, fetcher)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	giftCode := &GiftCode{
+		RootEntropy:    hex.EncodeToString(entropy),
+		TxOutPublicKey: hex.EncodeToString(txC.TxOut.PublicKey.GetData()),
+		Note:           note,
+	}
+	giftCode.Code, err = encodeGiftCode(entropy, txC.TxOut.PublicKey.GetData(), note)
+	if err != nil {
+		return nil, nil, err
+	}
+	giftAddress, err := gift.B58Code(GIFT_CODE_SUBADDRESS_INDEX)
+	if err != nil {
+		return nil, nil, err
+	}
+	output := giftCodeOutput(txC, giftAddress, amount, fee, tokenID)
+	output.ChangeAmount = changeAmount
+	if changeAmount > 0 {
+		output.ChangeIndex = int64(len(output.Outlays))
+		output.ChangeHash = hex.EncodeToString(txC.TxOutChange.PublicKey.GetData())
+		output.Outlays = append(output.Outlays, &OutlayOutput{
+			Address:            changeStr,
+			Amount:             changeAmount,
+			TokenID:            tokenID,
+			OutputHash:         output.ChangeHash,
+			SharedSecret:       hex.EncodeToString(txC.ShareSecretChange),
+			ConfirmationNumber: hex.EncodeToString(txC.ConfirmationChange),
+		})
+	}
+	return giftCode, output, nil
+}
+
+// ClaimGiftCode sends the gift TxOut of amount, less the fee, to recipientStr.
+// The proofs have the gift TxOut in the single ring, and the note is at most
+// GIFT_CODE_SENDER_NOTE_MAX_LENGTH bytes.
+func ClaimGiftCode(ctx context.Context, code string, amount uint64, proofs *Proofs, fee, tombstone uint64, tokenID, version uint, note, recipientStr string, trust *FogTrustConfig, fetcher FogReportFetcher) (*Output, error) {
+	err := checkGiftCodeNote(note, GIFT_CODE_SENDER_NOTE_MAX_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= fee {
+		return nil, fmt.Errorf("%w: %d, required %d", ErrInsufficientFunds, amount, fee)
+	}
+	input, err := giftCodeInput(code, amount, proofs)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := account.DecodeB58Code(recipientStr)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := giftCodeVerifier(recipient, trust)
+	if err != nil {
+		return nil, err
+	}
+	txC, err := MCTransactionBuilderClaimGiftCodeC(ctx, input, amount-fee, fee, tombstone, tokenID, version, note, recipient, verifier, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return giftCodeOutput(txC, recipientStr, amount-fee, fee, tokenID), nil
+}
+
+// CancelGiftCode sends the gift TxOut back to changeStr, the memo references
+// the global index of the gift TxOut in proofs.
+func CancelGiftCode(ctx context.Context, code string, amount uint64, proofs *Proofs, fee, tombstone uint64, tokenID, version uint, changeStr string, trust *FogTrustConfig, fetcher FogReportFetcher) (*Output, error) {
+	if amount <= fee {
+		return nil, fmt.Errorf("%w: %d, required %d", ErrInsufficientFunds, amount, fee)
+	}
+	globalIndex, err := giftCodeGlobalIndex(proofs)
+	if err != nil {
+		return nil, err
+	}
+	input, err := giftCodeInput(code, amount, proofs)
+	if err != nil {
+		return nil, err
+	}
+	change, err := account.DecodeB58Code(changeStr)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := giftCodeVerifier(change, trust)
+	if err != nil {
+		return nil, err
+	}
+	txC, err := MCTransactionBuilderCancelGiftCodeC(ctx, input, globalIndex, amount-fee, fee, tombstone, tokenID, version, change, verifier, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return giftCodeOutput(txC, changeStr, amount-fee, fee, tokenID), nil
+}
+
+// DecodeGiftCode parses the b58 TransferPayload of a gift code
+func DecodeGiftCode(code string) (*GiftCode, error) {
+	wrapper, err := decodePrintableWrapper(code)
+	if err != nil {
+		return nil, err
+	}
+	payload := wrapper.GetTransferPayload()
+	if payload == nil || len(payload.GetRootEntropy()) != 32 {
+		return nil, fmt.Errorf("Invalid gift code %s", code)
+	}
+	return &GiftCode{
+		Code:           code,
+		RootEntropy:    hex.EncodeToString(payload.GetRootEntropy()),
+		TxOutPublicKey: hex.EncodeToString(payload.GetTxOutPublicKey().GetData()),
+		Note:           payload.GetMemo(),
+	}, nil
+}
+
+func encodeGiftCode(entropy, txOutPublicKey []byte, note string) (string, error) {
+	payload := &types.TransferPayload{
+		RootEntropy:    entropy,
+		TxOutPublicKey: &types.CompressedRistretto{Data: txOutPublicKey},
+		Memo:           note,
+	}
+	wrapper := &types.PrintableWrapper_TransferPayload{TransferPayload: payload}
+	return encodePrintableWrapper(&types.PrintableWrapper{Wrapper: wrapper})
+}
+
+func giftCodeAccount(entropy []byte) (*account.Account, error) {
+	view, spend, err := MCAccountPrivateKeysFromRootEntropy(entropy)
+	if err != nil {
+		return nil, err
+	}
+	return account.NewAccountKey(view, spend)
+}
+
+// giftCodeInput spends the gift TxOut from the gift code subaddress
+func giftCodeInput(code string, amount uint64, proofs *Proofs) (*InputC, error) {
+	giftCode, err := DecodeGiftCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if len(proofs.Ring) != 1 {
+		return nil, fmt.Errorf("invalid gift code proofs ring len %d", len(proofs.Ring))
+	}
+	if proofs.Ring[0].TxOut.PublicKey != giftCode.TxOutPublicKey {
+		return nil, fmt.Errorf("gift TxOut %s not found", giftCode.TxOutPublicKey)
+	}
+	gift, err := giftCodeAccount(account.HexToBytes(giftCode.RootEntropy))
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := json.Marshal(proofs.Ring[0].TxOut)
+	if err != nil {
+		return nil, err
+	}
+	utxo := &UTXO{
+		Amount:       amount,
+		ScriptPubKey: hex.EncodeToString(script),
+		Account: &SpendAccount{
+			ViewPrivateKey:  hex.EncodeToString(gift.ViewPrivateKey.Bytes()),
+			SpendPrivateKey: hex.EncodeToString(gift.SpendPrivateKey.Bytes()),
+			SubaddressIndex: GIFT_CODE_SUBADDRESS_INDEX,
+		},
+	}
+	inputCs, err := BuildRingElements([]*UTXO{utxo}, proofs)
+	if err != nil {
+		return nil, err
+	}
+	return inputCs[0], nil
+}
+
+// giftCodeGlobalIndex is the global index of the gift TxOut, the single ring
+// of proofs
+func giftCodeGlobalIndex(proofs *Proofs) (uint64, error) {
+	if proofs == nil || len(proofs.Ring) != 1 || proofs.Ring[0].Proof == nil {
+		return 0, errors.New("no membership proof of the gift TxOut")
+	}
+	return strconv.ParseUint(proofs.Ring[0].Proof.Index, 10, 64)
+}
+
+func giftCodeVerifier(recipient *account.PublicAddress, trust *FogTrustConfig) (*FogVerifier, error) {
+	if recipient.FogReportUrl == "" {
+		return nil, nil
+	}
+	return trust.Verifier([]string{recipient.FogReportUrl})
+}
+
+// checkGiftCodeNote rejects the notes longer than the note of the gift code
+// memo, before libmobilecoin fails on them
+func checkGiftCodeNote(note string, maxLength int) error {
+	if len(note) > maxLength {
+		return fmt.Errorf("gift code note length %d exceeds %d bytes", len(note), maxLength)
+	}
+	return nil
+}
+
+// giftCodeOutput is the Output of the single payee of a gift code transaction,
+// the funding change is appended by FundGiftCode.
+func giftCodeOutput(txC *TxC, address string, amount, fee uint64, tokenID uint) *Output {
+	hash := hex.EncodeToString(txC.TxOut.PublicKey.GetData())
+	return &Output{
+		TransactionHash: hash,
+		RawTransaction:  hex.EncodeToString(txC.Tx),
+		SharedSecret:    hex.EncodeToString(txC.ShareSecretOut),
+		Fee:             fee,
+		OutputIndex:     0,
+		OutputHash:      hash,
+		ChangeIndex:     -1,
+		Outlays: []*OutlayOutput{{
+			Address:            address,
+			Amount:             amount,
+			TokenID:            tokenID,
+			OutputHash:         hash,
+			SharedSecret:       hex.EncodeToString(txC.ShareSecretOut),
+			ConfirmationNumber: hex.EncodeToString(txC.ConfirmationOut),
+		}},
+	}
+}
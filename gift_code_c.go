@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+)
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo darwin LDFLAGS: ${SRCDIR}/include/libmobilecoin.a -framework Security -framework Foundation
+// #cgo linux LDFLAGS: ${SRCDIR}/include/libmobilecoin_linux.a -lm -ldl
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <errno.h>
+// #include "libmobilecoin.h"
+import "C"
+
+// mc_transaction_builder_fund_gift_code_output, the amount is sent to the gift
+// code subaddress of the gift account, with the funding note in the memo.
+func MCTransactionBuilderFundGiftCodeC(ctx context.Context, inputCs []*InputC, giftViewPrivate, giftSpendPrivate *ristretto.Scalar, amount, changeAmount, fee, tombstone uint64, tokenID, version uint, note string, change *account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	if len(inputCs) == 0 {
+		return nil, errors.New("no inputs")
+	}
+
+	var fog_resolver *C.McFogResolver
+	if changeAmount > 0 && change.FogReportUrl != "" {
+		resolver, err := newFogResolverWithReportsC(ctx, []*account.PublicAddress{change}, verifier, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		defer resolver.free()
+		fog_resolver = resolver.resolver
+	}
+
+	memo_builder, err := newMemoBuilderC(&MemoOptions{Builder: MEMO_BUILDER_GIFT_CODE_FUNDING, GiftCodeNote: note}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer C.mc_memo_builder_free(memo_builder)
+
+	var build_error *C.McError
+	transaction_builder, err := C.mc_transaction_builder_create(C.uint64_t(fee), C.uint64_t(tokenID), C.uint64_t(tombstone), fog_resolver, memo_builder, C.uint32_t(version), &build_error)
+	if err != nil {
+		return nil, err
+	}
+	if transaction_builder == nil {
+		return nil, mcError("mc_transaction_builder_create", build_error)
+	}
+	defer C.mc_transaction_builder_free(transaction_builder)
+
+	for _, input := range inputCs {
+		err = addInputC(transaction_builder, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gift_account_key, free_gift_account_key := newAccountKeyC(giftViewPrivate, giftSpendPrivate)
+	defer free_gift_account_key()
+	out_tx_out_confirmation_number, free_confirmation_number := newMutableBufferC(32)
+	defer free_confirmation_number()
+
+	var rng_callback *C.McRngCallback
+	var out_error *C.McError
+	mcDataOut, err := C.mc_transaction_builder_fund_gift_code_output(gift_account_key, transaction_builder, C.uint64_t(amount), C.uint64_t(tokenID), rng_callback, out_tx_out_confirmation_number, &out_error)
+	if err != nil {
+		return nil, err
+	}
+	if mcDataOut == nil {
+		return nil, mcError("mc_transaction_builder_fund_gift_code_output", out_error)
+	}
+	defer C.mc_data_free(mcDataOut)
+	txOut, err := txOutFromMcData(mcDataOut)
+	if err != nil {
+		return nil, err
+	}
+	giftOutput := &TxOutC{
+		TxOut:        txOut,
+		Confirmation: mutableBufferBytes(out_tx_out_confirmation_number),
+	}
+
+	changeOutput := &TxOutC{}
+	if changeAmount > 0 {
+		changeOutput, err = addOutputC(transaction_builder, changeAmount, change)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mcData, err := C.mc_transaction_builder_build(transaction_builder, rng_callback, &out_error)
+	if err != nil {
+		return nil, err
+	}
+	if mcData == nil {
+		return nil, mcError("mc_transaction_builder_build", out_error)
+	}
+	defer C.mc_data_free(mcData)
+
+	return &TxC{
+		Tx:                 mcDataBytes(mcData),
+		TxOut:              giftOutput.TxOut,
+		ConfirmationOut:    giftOutput.Confirmation,
+		TxOutChange:        changeOutput.TxOut,
+		ShareSecretChange:  changeOutput.SharedSecret,
+		ConfirmationChange: changeOutput.Confirmation,
+		Outputs:            []*TxOutC{giftOutput},
+	}, nil
+}
+
+// mc_memo_builder_gift_code_sender_create, the gift is sent to the recipient
+// with the sender note in the memo.
+func MCTransactionBuilderClaimGiftCodeC(ctx context.Context, input *InputC, amount, fee, tombstone uint64, tokenID, version uint, note string, recipient *account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	memo_builder, err := newMemoBuilderC(&MemoOptions{Builder: MEMO_BUILDER_GIFT_CODE_SENDER, GiftCodeNote: note}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer C.mc_memo_builder_free(memo_builder)
+	return spendGiftCodeC(ctx, input, memo_builder, amount, fee, tombstone, tokenID, version, recipient, verifier, fetcher)
+}
+
+// mc_memo_builder_gift_code_cancellation_create, the gift is sent back with
+// the global index of the gift TxOut in the memo.
+func MCTransactionBuilderCancelGiftCodeC(ctx context.Context, input *InputC, globalIndex, amount, fee, tombstone uint64, tokenID, version uint, change *account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	memo_builder, err := newMemoBuilderC(&MemoOptions{Builder: MEMO_BUILDER_GIFT_CODE_CANCELLATION, GiftCodeGlobalIndex: globalIndex}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer C.mc_memo_builder_free(memo_builder)
+	return spendGiftCodeC(ctx, input, memo_builder, amount, fee, tombstone, tokenID, version, change, verifier, fetcher)
+}
+
+func spendGiftCodeC(ctx context.Context, input *InputC, memo_builder *C.McTxOutMemoBuilder, amount, fee, tombstone uint64, tokenID, version uint, recipient *account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	var fog_resolver *C.McFogResolver
+	if recipient.FogReportUrl != "" {
+		resolver, err := newFogResolverWithReportsC(ctx, []*account.PublicAddress{recipient}, verifier, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		defer resolver.free()
+		fog_resolver = resolver.resolver
+	}
+
+	var build_error *C.McError
+	transaction_builder, err := C.mc_transaction_builder_create(C.uint64_t(fee), C.uint64_t(tokenID), C.uint64_t(tombstone), fog_resolver, memo_builder, C.uint32_t(version), &build_error)
+	if err != nil {
+		return nil, err
+	}
+	if transaction_builder == nil {
+		return nil, mcError("mc_transaction_builder_create", build_error)
+	}
+	defer C.mc_transaction_builder_free(transaction_builder)
+
+	err = addInputC(transaction_builder, input)
+	if err != nil {
+		return nil, err
+	}
+	output, err := addOutputC(transaction_builder, amount, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	var rng_callback *C.McRngCallback
+	var out_error *C.McError
+	mcData, err := C.mc_transaction_builder_build(transaction_builder, rng_callback, &out_error)
+	if err != nil {
+		return nil, err
+	}
+	if mcData == nil {
+		return nil, mcError("mc_transaction_builder_build", out_error)
+	}
+	defer C.mc_data_free(mcData)
+
+	return &TxC{
+		Tx:              mcDataBytes(mcData),
+		TxOut:           output.TxOut,
+		ShareSecretOut:  output.SharedSecret,
+		ConfirmationOut: output.Confirmation,
+		Outputs:         []*TxOutC{output},
+	}, nil
+}
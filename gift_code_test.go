@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGiftCode(t *testing.T) {
+	assert := assert.New(t)
+
+	entropy := bytes.Repeat([]byte{7}, 32)
+	publicKey := bytes.Repeat([]byte{9}, 32)
+	code, err := encodeGiftCode(entropy, publicKey, "happy birthday")
+	assert.Nil(err)
+
+	gift, err := DecodeGiftCode(code)
+	assert.Nil(err)
+	assert.Equal(code, gift.Code)
+	assert.Equal(hex.EncodeToString(entropy), gift.RootEntropy)
+	assert.Equal(hex.EncodeToString(publicKey), gift.TxOutPublicKey)
+	assert.Equal("happy birthday", gift.Note)
+
+	_, err = DecodeGiftCode(code[:len(code)-1] + "1")
+	assert.NotNil(err)
+	_, err = DecodeGiftCode("8VWJpZDdmeb4WqNp2P1ZF4bTbMMcH4QAcbDKqJGjLgLrNHzN")
+	assert.NotNil(err)
+
+	code, err = encodeGiftCode(entropy[:16], publicKey, "")
+	assert.Nil(err)
+	_, err = DecodeGiftCode(code)
+	assert.NotNil(err)
+}
+
+func TestGiftCodeAmounts(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	change, err := acc.B58Code(0)
+	assert.Nil(err)
+	code, err := encodeGiftCode(bytes.Repeat([]byte{7}, 32), bytes.Repeat([]byte{9}, 32), "")
+	assert.Nil(err)
+	ctx := context.Background()
+
+	inputs := []*UTXO{{Amount: 10 * MILLIMOB_TO_PICOMOB}}
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, 10*MILLIMOB_TO_PICOMOB, MOB_MINIMUM_FEE, 100, 0, 3, "", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, 10*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE-1, MOB_MINIMUM_FEE, 100, 0, 3, "", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "change amount 1 of token 0 below minimum")
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, 10*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE, MOB_MINIMUM_FEE, 100, 0, 3, "", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "Invalid proofs")
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, 10*MILLIMOB_TO_PICOMOB-EUSD_MINIMUM_FEE-1, EUSD_MINIMUM_FEE, 100, EUSD_TOKEN_ID, 3, "", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "change amount 1 of token 1 below minimum 2560")
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, 10*MILLIMOB_TO_PICOMOB-EUSD_MINIMUM_FEE-1, EUSD_MINIMUM_FEE, 100, 5, 3, "", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "no default fee policy for token 5")
+
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, MILLIMOB_TO_PICOMOB, MOB_MINIMUM_FEE, 100, 0, 3, strings.Repeat("a", 55), change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "gift code note length 55 exceeds 54 bytes")
+	_, _, err = FundGiftCode(ctx, inputs, &Proofs{}, 10*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE, MOB_MINIMUM_FEE, 100, 0, 3, strings.Repeat("a", 54), change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "Invalid proofs")
+
+	_, err = ClaimGiftCode(ctx, code, MOB_MINIMUM_FEE, &Proofs{}, MOB_MINIMUM_FEE, 100, 0, 3, "", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+	_, err = ClaimGiftCode(ctx, code, MILLIMOB_TO_PICOMOB, &Proofs{}, MOB_MINIMUM_FEE, 100, 0, 3, strings.Repeat("a", 59), change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "gift code note length 59 exceeds 58 bytes")
+	_, err = ClaimGiftCode(ctx, code, MILLIMOB_TO_PICOMOB, &Proofs{}, MOB_MINIMUM_FEE, 100, 0, 3, strings.Repeat("a", 58), change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "invalid gift code proofs ring len 0")
+	_, err = CancelGiftCode(ctx, code, MOB_MINIMUM_FEE, &Proofs{}, MOB_MINIMUM_FEE, 100, 0, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+	_, err = CancelGiftCode(ctx, code, MILLIMOB_TO_PICOMOB, &Proofs{}, MOB_MINIMUM_FEE, 100, 0, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "no membership proof of the gift TxOut")
+
+	_, err = giftCodeGlobalIndex(nil)
+	assert.NotNil(err)
+	proofs := &Proofs{Ring: []*TxOutWithProof{{TxOut: &TxOut{}}}}
+	_, err = giftCodeGlobalIndex(proofs)
+	assert.NotNil(err)
+	proofs.Ring[0].Proof = &TxOutMembershipProof{Index: "4242"}
+	index, err := giftCodeGlobalIndex(proofs)
+	assert.Nil(err)
+	assert.Equal(uint64(4242), index)
+	_, err = giftCodeGlobalIndex(&Proofs{Ring: append(proofs.Ring, proofs.Ring[0])})
+	assert.NotNil(err)
+	proofs.Ring[0].Proof.Index = "-1"
+	_, err = giftCodeGlobalIndex(proofs)
+	assert.NotNil(err)
+}
+
+func TestGiftCodeC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	change, err := acc.B58Code(0)
+	require.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	recipientAddress, err := recipient.B58Code(0)
+	require.Nil(err)
+	ctx := context.Background()
+
+	amount := uint64(5 * MILLIMOB_TO_PICOMOB)
+	inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{10 * MILLIMOB_TO_PICOMOB}, 0)
+	gift, output, err := FundGiftCode(ctx, inputs, proofs, amount, MOB_MINIMUM_FEE, 100, 0, 3, "happy birthday", change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	require.Equal(uint64(MOB_MINIMUM_FEE), output.Fee)
+	require.Equal(uint64(5*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE), output.ChangeAmount)
+	require.Len(output.Outlays, 2)
+	require.Equal(int64(1), output.ChangeIndex)
+	require.Equal(amount, output.Outlays[0].Amount)
+	require.Equal(gift.TxOutPublicKey, output.Outlays[0].OutputHash)
+	require.Equal(change, output.Outlays[1].Address)
+	require.Equal(output.ChangeHash, output.Outlays[1].OutputHash)
+	tx, err := decodeTestTx(output.RawTransaction)
+	require.Nil(err)
+	require.Equal(uint64(MOB_MINIMUM_FEE), tx.Prefix.Fee)
+	require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3}))
+
+	giftAccount, err := giftCodeAccount(account.HexToBytes(gift.RootEntropy))
+	require.Nil(err)
+	scanner, err := NewScanner(hex.EncodeToString(giftAccount.ViewPrivateKey.Bytes()), hex.EncodeToString(account.PublicKey(giftAccount.SpendPrivateKey).Bytes()), 1)
+	require.Nil(err)
+	matches, err := scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(gift.TxOutPublicKey, matches[0].TxOut.PublicKey)
+	require.Equal(uint64(GIFT_CODE_SUBADDRESS_INDEX), matches[0].SubaddressIndex)
+	require.Equal(amount, matches[0].Value)
+	giftOut := matches[0].TxOut
+
+	scanner, err = NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(account.PublicKey(&spend).Bytes()), 1)
+	require.Nil(err)
+	matches, err = scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(output.ChangeHash, matches[0].TxOut.PublicKey)
+	require.Equal(output.ChangeAmount, matches[0].Value)
+
+	real, ring := newTestRing(assert.New(t), giftOut, 4242, amount, 0)
+	giftProofs := &Proofs{Ring: []*TxOutWithProof{real}, Rings: [][]*TxOutWithProof{ring}}
+
+	output, err = ClaimGiftCode(ctx, gift.Code, amount, giftProofs, MOB_MINIMUM_FEE, 100, 0, 3, "thanks", recipientAddress, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	require.Equal(uint64(MOB_MINIMUM_FEE), output.Fee)
+	require.Equal(int64(-1), output.ChangeIndex)
+	require.Equal([]*OutlayOutput{{
+		Address:            recipientAddress,
+		Amount:             amount - MOB_MINIMUM_FEE,
+		OutputHash:         output.OutputHash,
+		SharedSecret:       output.SharedSecret,
+		ConfirmationNumber: output.Outlays[0].ConfirmationNumber,
+	}}, output.Outlays)
+	tx, err = decodeTestTx(output.RawTransaction)
+	require.Nil(err)
+	require.Equal(uint64(MOB_MINIMUM_FEE), tx.Prefix.Fee)
+	require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3}))
+	scanner, err = NewScanner(hex.EncodeToString(recipientView.Bytes()), hex.EncodeToString(account.PublicKey(&recipientSpend).Bytes()), 1)
+	require.Nil(err)
+	matches, err = scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(output.OutputHash, matches[0].TxOut.PublicKey)
+	require.Equal(amount-MOB_MINIMUM_FEE, matches[0].Value)
+	memo, err := DecodeMemoPayload(matches[0].MemoPayload)
+	require.Nil(err)
+	require.Equal(&GiftCodeSenderMemo{Note: "thanks", Fee: MOB_MINIMUM_FEE}, memo)
+
+	output, err = CancelGiftCode(ctx, gift.Code, amount, giftProofs, MOB_MINIMUM_FEE, 100, 0, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	require.Equal(uint64(MOB_MINIMUM_FEE), output.Fee)
+	tx, err = decodeTestTx(output.RawTransaction)
+	require.Nil(err)
+	require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3}))
+	scanner, err = NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(account.PublicKey(&spend).Bytes()), 1)
+	require.Nil(err)
+	matches, err = scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(amount-MOB_MINIMUM_FEE, matches[0].Value)
+	memo, err = DecodeMemoPayload(matches[0].MemoPayload)
+	require.Nil(err)
+	require.Equal(&GiftCodeCancellationMemo{GiftCodeTxOutIndex: 4242, Fee: MOB_MINIMUM_FEE}, memo)
+}
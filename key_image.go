@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bwesterb/go-ristretto"
+)
+
+// OnetimePrivateKey is Hs(a * R) + d, the private key of the target key of a
+// TxOut received by the subaddress with the spend private key d.
+func OnetimePrivateKey(txOut *TxOut, viewPrivate, subaddressSpendPrivate *ristretto.Scalar) (*ristretto.Scalar, error) {
+	publicKey, err := decodePoint(txOut.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	targetKey, err := decodePoint(txOut.TargetKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var private ristretto.Scalar
+	private.Add(hashToScalar(publicKey, viewPrivate), subaddressSpendPrivate)
+	var p ristretto.Point
+	if !p.ScalarMultBase(&private).Equals(targetKey) {
+		return nil, fmt.Errorf("txout %s not owned by the subaddress", txOut.PublicKey)
+	}
+	return &private, nil
+}
+
+// KeyImageForTxOut returns the hex key image which is revealed on chain when
+// the TxOut is spent.
+func KeyImageForTxOut(txOut *TxOut, viewPrivate, subaddressSpendPrivate *ristretto.Scalar) (string, error) {
+	private, err := OnetimePrivateKey(txOut, viewPrivate, subaddressSpendPrivate)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(KeyImageFromPrivate(private).Bytes()), nil
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyImageForTxOut(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	out := newScannerTestTxOut(assert, acc.PublicAddress(5), 100, 0, nil)
+
+	subaddressSpend := acc.SubaddressSpendPrivateKey(5)
+	private, err := OnetimePrivateKey(out, &view, subaddressSpend)
+	assert.Nil(err)
+	keyImage, err := KeyImageForTxOut(out, &view, subaddressSpend)
+	assert.Nil(err)
+	assert.Equal(hex.EncodeToString(KeyImageFromPrivate(private).Bytes()), keyImage)
+
+	_, err = KeyImageForTxOut(out, &view, acc.SubaddressSpendPrivateKey(0))
+	assert.NotNil(err)
+}
+
+func TestKeyImageVector(t *testing.T) {
+	assert := assert.New(t)
+
+	// the view, spend and TxOut private keys are the sha512 of "view", "spend"
+	// and "txout" reduced modulo the group order, the key image is computed
+	// apart from this package with curve25519-dalek
+	view, err := decodeScalar("a353338ca698a9a38f8f360b242c3ab22aac1c8d6e54e15ee9e5b25fb0740e0e")
+	assert.Nil(err)
+	spend, err := decodeScalar("bb8e6e15dba5969198fe2001dbed972425ba19a9c8477e0472de3191846def0c")
+	assert.Nil(err)
+	out := &TxOut{
+		TargetKey: "289c32660e0bdef2533795fd52f964827f8722bf4ad1efb6525624709af8b917",
+		PublicKey: "2c9f353dfa349207749ff63070b1cbb9984e211d8693e9f70e889726dc82c142",
+	}
+	private, err := OnetimePrivateKey(out, view, spend)
+	assert.Nil(err)
+	assert.Equal("eda3f2524bb780d206885947acfd9c2c634739031a31a345e11c28f5a9396f06", hex.EncodeToString(private.Bytes()))
+	keyImage, err := KeyImageForTxOut(out, view, spend)
+	assert.Nil(err)
+	assert.Equal("c4dacdce1cb43b2697fa2239031ed04253db00a45214f2841483bfd9baecc62f", keyImage)
+}
+
+func TestKeyImageForTxOutC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	out := newScannerTestTxOut(assert, acc.PublicAddress(5), 100, 0, nil)
+
+	subaddressSpend := acc.SubaddressSpendPrivateKey(5)
+	keyImage, err := KeyImageForTxOut(out, &view, subaddressSpend)
+	assert.Nil(err)
+	c, err := MCTxOutGetKeyImage(out.TargetKey, out.PublicKey, hex.EncodeToString(view.Bytes()), hex.EncodeToString(subaddressSpend.Bytes()))
+	assert.Nil(err)
+	assert.Equal(keyImage, c)
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha512"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"io"
 
 	"golang.org/x/crypto/hkdf"
@@ -67,3 +69,122 @@ func DecryptMemo(text string, public, private string) ([]byte, error) {
 	stream.XORKeyStream(plaintext, data)
 	return plaintext, nil
 }
+
+const (
+	MEMO_TYPE_UNUSED                           = 0x0000
+	MEMO_TYPE_GIFT_CODE_SENDER                 = 0x0002
+	MEMO_TYPE_SENDER                           = 0x0100
+	MEMO_TYPE_SENDER_WITH_PAYMENT_REQUEST      = 0x0101
+	MEMO_TYPE_SENDER_WITH_PAYMENT_INTENT       = 0x0102
+	MEMO_TYPE_DESTINATION                      = 0x0200
+	MEMO_TYPE_GIFT_CODE_FUNDING                = 0x0201
+	MEMO_TYPE_GIFT_CODE_CANCELLATION           = 0x0202
+	MEMO_TYPE_DESTINATION_WITH_PAYMENT_REQUEST = 0x0203
+	MEMO_TYPE_DESTINATION_WITH_PAYMENT_INTENT  = 0x0204
+
+	MEMO_PAYLOAD_LENGTH = 66
+	MEMO_DATA_LENGTH    = 64
+)
+
+// Memo is a decrypted memo payload, the two type bytes of the payload select
+// the concrete type.
+type Memo interface {
+	MemoType() uint16
+}
+
+type UnusedMemo struct{}
+
+type SenderMemo struct {
+	AddressHash string
+}
+
+type SenderWithPaymentRequestMemo struct {
+	AddressHash      string
+	PaymentRequestID uint64
+}
+
+type SenderWithPaymentIntentMemo struct {
+	AddressHash     string
+	PaymentIntentID uint64
+}
+
+type DestinationMemo struct {
+	AddressHash        string
+	NumberOfRecipients uint8
+	Fee                uint64
+	TotalOutlay        uint64
+}
+
+type DestinationWithPaymentRequestMemo struct {
+	AddressHash        string
+	NumberOfRecipients uint8
+	Fee                uint64
+	TotalOutlay        uint64
+	PaymentRequestID   uint64
+}
+
+type DestinationWithPaymentIntentMemo struct {
+	AddressHash        string
+	NumberOfRecipients uint8
+	Fee                uint64
+	TotalOutlay        uint64
+	PaymentIntentID    uint64
+}
+
+type GiftCodeFundingMemo struct {
+	Note string
+	Fee  uint64
+}
+
+type GiftCodeSenderMemo struct {
+	Note string
+	Fee  uint64
+}
+
+type GiftCodeCancellationMemo struct {
+	GiftCodeTxOutIndex uint64
+	Fee                uint64
+}
+
+func (m *UnusedMemo) MemoType() uint16 { return MEMO_TYPE_UNUSED }
+
+func (m *SenderMemo) MemoType() uint16 { return MEMO_TYPE_SENDER }
+
+func (m *SenderWithPaymentRequestMemo) MemoType() uint16 {
+	return MEMO_TYPE_SENDER_WITH_PAYMENT_REQUEST
+}
+
+func (m *SenderWithPaymentIntentMemo) MemoType() uint16 {
+	return MEMO_TYPE_SENDER_WITH_PAYMENT_INTENT
+}
+
+func (m *DestinationMemo) MemoType() uint16 { return MEMO_TYPE_DESTINATION }
+
+func (m *DestinationWithPaymentRequestMemo) MemoType() uint16 {
+	return MEMO_TYPE_DESTINATION_WITH_PAYMENT_REQUEST
+}
+
+func (m *DestinationWithPaymentIntentMemo) MemoType() uint16 {
+	return MEMO_TYPE_DESTINATION_WITH_PAYMENT_INTENT
+}
+
+func (m *GiftCodeFundingMemo) MemoType() uint16 { return MEMO_TYPE_GIFT_CODE_FUNDING }
+
+func (m *GiftCodeSenderMemo) MemoType() uint16 { return MEMO_TYPE_GIFT_CODE_SENDER }
+
+func (m *GiftCodeCancellationMemo) MemoType() uint16 {
+	return MEMO_TYPE_GIFT_CODE_CANCELLATION
+}
+
+// splitMemoPayload returns the type and the 64 bytes data of a memo payload
+func splitMemoPayload(payload []byte) (uint16, []byte, error) {
+	if len(payload) != MEMO_PAYLOAD_LENGTH {
+		return 0, nil, fmt.Errorf("invalid memo payload length %d", len(payload))
+	}
+	return binary.BigEndian.Uint16(payload[:2]), payload[2:], nil
+}
+
+func joinMemoPayload(memoType uint16, data []byte) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, memoType)
+	return append(payload, data...)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	MEMO_BUILDER_SENDER_PAYMENT_REQUEST_AND_DESTINATION = iota
+	MEMO_BUILDER_DEFAULT
+	MEMO_BUILDER_SENDER_AND_DESTINATION
+	MEMO_BUILDER_SENDER_PAYMENT_INTENT_AND_DESTINATION
+	MEMO_BUILDER_GIFT_CODE_FUNDING
+	MEMO_BUILDER_GIFT_CODE_SENDER
+	MEMO_BUILDER_GIFT_CODE_CANCELLATION
+)
+
+// MemoOptions selects the memo builder of a transaction. The zero value, as
+// well as nil, is the sender with payment request and destination builder
+// with the payment request id 0. MEMO_BUILDER_DEFAULT writes unused memos.
+type MemoOptions struct {
+	Builder             int
+	PaymentRequestID    uint64
+	PaymentIntentID     uint64
+	GiftCodeNote        string
+	GiftCodeGlobalIndex uint64
+}
+
+func PaymentRequestMemoOptions(paymentRequestID uint64) *MemoOptions {
+	return &MemoOptions{
+		Builder:          MEMO_BUILDER_SENDER_PAYMENT_REQUEST_AND_DESTINATION,
+		PaymentRequestID: paymentRequestID,
+	}
+}
+
+func PaymentIntentMemoOptions(paymentIntentID uint64) *MemoOptions {
+	return &MemoOptions{
+		Builder:         MEMO_BUILDER_SENDER_PAYMENT_INTENT_AND_DESTINATION,
+		PaymentIntentID: paymentIntentID,
+	}
+}
+
+func (opts *MemoOptions) validate() error {
+	if opts == nil {
+		return nil
+	}
+	switch opts.Builder {
+	case MEMO_BUILDER_SENDER_PAYMENT_REQUEST_AND_DESTINATION,
+		MEMO_BUILDER_SENDER_PAYMENT_INTENT_AND_DESTINATION,
+		MEMO_BUILDER_DEFAULT,
+		MEMO_BUILDER_SENDER_AND_DESTINATION,
+		MEMO_BUILDER_GIFT_CODE_SENDER,
+		MEMO_BUILDER_GIFT_CODE_CANCELLATION:
+		return nil
+	case MEMO_BUILDER_GIFT_CODE_FUNDING:
+		return errors.New("gift code funding memo builder is only for FundGiftCode")
+	}
+	return fmt.Errorf("invalid memo builder %d", opts.Builder)
+}
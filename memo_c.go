@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"unsafe"
 
 	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
 )
 
 // #cgo CFLAGS: -I${SRCDIR}/include
@@ -81,3 +83,415 @@ func DecryptEMemoPayload(encryptedMemoStr, txOutPublicKey, viewPrivateKeyStr, sp
 
 	return hex.EncodeToString(C.GoBytes(out_memo_bytes, 66)), nil
 }
+
+// DecryptMemoPayload decrypts and decodes the e_memo of a TxOut
+func DecryptMemoPayload(encryptedMemoStr, txOutPublicKey, viewPrivateKeyStr, spendPrivateKeyStr string) (Memo, error) {
+	payload, err := DecryptEMemoPayload(encryptedMemoStr, txOutPublicKey, viewPrivateKeyStr, spendPrivateKeyStr)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMemoPayload(account.HexToBytes(payload))
+}
+
+// DecodeMemoPayload decodes the 66 bytes memo payload by its type bytes
+func DecodeMemoPayload(payload []byte) (Memo, error) {
+	memoType, data, err := splitMemoPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	memo_data, free_memo_data := newBufferC(data)
+	defer free_memo_data()
+
+	switch memoType {
+	case MEMO_TYPE_UNUSED:
+		return &UnusedMemo{}, nil
+	case MEMO_TYPE_SENDER:
+		memo := &SenderMemo{}
+		memo.AddressHash, err = memoAddressHashC("mc_memo_sender_memo_get_address_hash", func(out *C.McMutableBuffer, out_error **C.McError) bool {
+			return bool(C.mc_memo_sender_memo_get_address_hash(memo_data, out, out_error))
+		})
+		return memo, err
+	case MEMO_TYPE_SENDER_WITH_PAYMENT_REQUEST:
+		memo := &SenderWithPaymentRequestMemo{}
+		memo.AddressHash, err = memoAddressHashC("mc_memo_sender_with_payment_request_memo_get_address_hash", func(out *C.McMutableBuffer, out_error **C.McError) bool {
+			return bool(C.mc_memo_sender_with_payment_request_memo_get_address_hash(memo_data, out, out_error))
+		})
+		if err != nil {
+			return nil, err
+		}
+		memo.PaymentRequestID, err = memoUint64C("mc_memo_sender_with_payment_request_memo_get_payment_request_id", func(out *C.uint64_t, out_error **C.McError) bool {
+			return bool(C.mc_memo_sender_with_payment_request_memo_get_payment_request_id(memo_data, out, out_error))
+		})
+		return memo, err
+	case MEMO_TYPE_SENDER_WITH_PAYMENT_INTENT:
+		memo := &SenderWithPaymentIntentMemo{}
+		memo.AddressHash, err = memoAddressHashC("mc_memo_sender_with_payment_intent_memo_get_address_hash", func(out *C.McMutableBuffer, out_error **C.McError) bool {
+			return bool(C.mc_memo_sender_with_payment_intent_memo_get_address_hash(memo_data, out, out_error))
+		})
+		if err != nil {
+			return nil, err
+		}
+		memo.PaymentIntentID, err = memoUint64C("mc_memo_sender_with_payment_intent_memo_get_payment_intent_id", func(out *C.uint64_t, out_error **C.McError) bool {
+			return bool(C.mc_memo_sender_with_payment_intent_memo_get_payment_intent_id(memo_data, out, out_error))
+		})
+		return memo, err
+	case MEMO_TYPE_DESTINATION:
+		return decodeDestinationMemoC(memo_data)
+	case MEMO_TYPE_DESTINATION_WITH_PAYMENT_REQUEST:
+		return decodeDestinationWithPaymentRequestMemoC(memo_data)
+	case MEMO_TYPE_DESTINATION_WITH_PAYMENT_INTENT:
+		return decodeDestinationWithPaymentIntentMemoC(memo_data)
+	case MEMO_TYPE_GIFT_CODE_FUNDING:
+		memo := &GiftCodeFundingMemo{}
+		c_note := C.mc_memo_gift_code_funding_memo_get_note(memo_data)
+		if c_note == nil {
+			return nil, errors.New("mc_memo_gift_code_funding_memo_get_note failure")
+		}
+		memo.Note = C.GoString(c_note)
+		C.mc_string_free(c_note)
+		memo.Fee, err = memoUint64C("mc_memo_gift_code_funding_memo_get_fee", func(out *C.uint64_t, out_error **C.McError) bool {
+			return bool(C.mc_memo_gift_code_funding_memo_get_fee(memo_data, out, out_error))
+		})
+		return memo, err
+	case MEMO_TYPE_GIFT_CODE_SENDER:
+		memo := &GiftCodeSenderMemo{}
+		c_note := C.mc_memo_gift_code_sender_memo_get_note(memo_data)
+		if c_note == nil {
+			return nil, errors.New("mc_memo_gift_code_sender_memo_get_note failure")
+		}
+		memo.Note = C.GoString(c_note)
+		C.mc_string_free(c_note)
+		memo.Fee, err = memoUint64C("mc_memo_get_gift_code_sender_memo_get_fee", func(out *C.uint64_t, out_error **C.McError) bool {
+			return bool(C.mc_memo_get_gift_code_sender_memo_get_fee(memo_data, out, out_error))
+		})
+		return memo, err
+	case MEMO_TYPE_GIFT_CODE_CANCELLATION:
+		memo := &GiftCodeCancellationMemo{}
+		memo.GiftCodeTxOutIndex, err = memoUint64C("mc_memo_gift_code_cancellation_memo_get_gift_code_tx_out_index", func(out *C.uint64_t, out_error **C.McError) bool {
+			return bool(C.mc_memo_gift_code_cancellation_memo_get_gift_code_tx_out_index(memo_data, out, out_error))
+		})
+		if err != nil {
+			return nil, err
+		}
+		memo.Fee, err = memoUint64C("mc_memo_gift_code_cancellation_memo_get_fee", func(out *C.uint64_t, out_error **C.McError) bool {
+			return bool(C.mc_memo_gift_code_cancellation_memo_get_fee(memo_data, out, out_error))
+		})
+		return memo, err
+	default:
+		return nil, fmt.Errorf("unknown memo type %04x", memoType)
+	}
+}
+
+func decodeDestinationMemoC(memo_data *C.McBuffer) (Memo, error) {
+	var err error
+	memo := &DestinationMemo{}
+	memo.AddressHash, err = memoAddressHashC("mc_memo_destination_memo_get_address_hash", func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_memo_get_address_hash(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.NumberOfRecipients, err = memoUint8C("mc_memo_destination_memo_get_number_of_recipients", func(out *C.uint8_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_memo_get_number_of_recipients(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.Fee, err = memoUint64C("mc_memo_destination_memo_get_fee", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_memo_get_fee(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.TotalOutlay, err = memoUint64C("mc_memo_destination_memo_get_total_outlay", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_memo_get_total_outlay(memo_data, out, out_error))
+	})
+	return memo, err
+}
+
+func decodeDestinationWithPaymentRequestMemoC(memo_data *C.McBuffer) (Memo, error) {
+	var err error
+	memo := &DestinationWithPaymentRequestMemo{}
+	memo.AddressHash, err = memoAddressHashC("mc_memo_destination_with_payment_request_memo_get_address_hash", func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_request_memo_get_address_hash(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.NumberOfRecipients, err = memoUint8C("mc_memo_destination_with_payment_request_memo_get_number_of_recipients", func(out *C.uint8_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_request_memo_get_number_of_recipients(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.Fee, err = memoUint64C("mc_memo_destination_with_payment_request_memo_get_fee", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_request_memo_get_fee(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.TotalOutlay, err = memoUint64C("mc_memo_destination_with_payment_request_memo_get_total_outlay", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_request_memo_get_total_outlay(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.PaymentRequestID, err = memoUint64C("mc_memo_destination_with_payment_request_memo_get_payment_request_id", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_request_memo_get_payment_request_id(memo_data, out, out_error))
+	})
+	return memo, err
+}
+
+func decodeDestinationWithPaymentIntentMemoC(memo_data *C.McBuffer) (Memo, error) {
+	var err error
+	memo := &DestinationWithPaymentIntentMemo{}
+	memo.AddressHash, err = memoAddressHashC("mc_memo_destination_with_payment_intent_memo_get_address_hash", func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_intent_memo_get_address_hash(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.NumberOfRecipients, err = memoUint8C("mc_memo_destination_with_payment_intent_memo_get_number_of_recipients", func(out *C.uint8_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_intent_memo_get_number_of_recipients(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.Fee, err = memoUint64C("mc_memo_destination_with_payment_intent_memo_get_fee", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_intent_memo_get_fee(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.TotalOutlay, err = memoUint64C("mc_memo_destination_with_payment_intent_memo_get_total_outlay", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_intent_memo_get_total_outlay(memo_data, out, out_error))
+	})
+	if err != nil {
+		return nil, err
+	}
+	memo.PaymentIntentID, err = memoUint64C("mc_memo_destination_with_payment_intent_memo_get_payment_intent_id", func(out *C.uint64_t, out_error **C.McError) bool {
+		return bool(C.mc_memo_destination_with_payment_intent_memo_get_payment_intent_id(memo_data, out, out_error))
+	})
+	return memo, err
+}
+
+// mc_memo_sender_memo_create
+func MCSenderMemoCreate(senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr string) ([]byte, error) {
+	return memoSenderCreateC(MEMO_TYPE_SENDER, senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr, 0)
+}
+
+// mc_memo_sender_with_payment_request_memo_create
+func MCSenderWithPaymentRequestMemoCreate(senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr string, paymentRequestID uint64) ([]byte, error) {
+	return memoSenderCreateC(MEMO_TYPE_SENDER_WITH_PAYMENT_REQUEST, senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr, paymentRequestID)
+}
+
+// mc_memo_sender_with_payment_intent_memo_create
+func MCSenderWithPaymentIntentMemoCreate(senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr string, paymentIntentID uint64) ([]byte, error) {
+	return memoSenderCreateC(MEMO_TYPE_SENDER_WITH_PAYMENT_INTENT, senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr, paymentIntentID)
+}
+
+func memoSenderCreateC(memoType uint16, senderViewPrivateKeyStr, senderSpendPrivateKeyStr, recipientSubaddressViewPublicKeyStr, txOutPublicKeyStr string, id uint64) ([]byte, error) {
+	account_key, free_account_key := newAccountKeyC(account.HexToScalar(senderViewPrivateKeyStr), account.HexToScalar(senderSpendPrivateKeyStr))
+	defer free_account_key()
+	recipient_view_public_key, free_recipient_view_public_key := newBufferC(account.HexToBytes(recipientSubaddressViewPublicKeyStr))
+	defer free_recipient_view_public_key()
+	tx_out_public_key, free_tx_out_public_key := newBufferC(account.HexToBytes(txOutPublicKeyStr))
+	defer free_tx_out_public_key()
+
+	return memoCreateC(memoType, func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		switch memoType {
+		case MEMO_TYPE_SENDER_WITH_PAYMENT_REQUEST:
+			return bool(C.mc_memo_sender_with_payment_request_memo_create(account_key, recipient_view_public_key, tx_out_public_key, C.uint64_t(id), out, out_error))
+		case MEMO_TYPE_SENDER_WITH_PAYMENT_INTENT:
+			return bool(C.mc_memo_sender_with_payment_intent_memo_create(account_key, recipient_view_public_key, tx_out_public_key, C.uint64_t(id), out, out_error))
+		default:
+			return bool(C.mc_memo_sender_memo_create(account_key, recipient_view_public_key, tx_out_public_key, out, out_error))
+		}
+	})
+}
+
+// mc_memo_destination_memo_create
+func MCDestinationMemoCreate(destination *account.PublicAddress, numberOfRecipients uint8, fee, totalOutlay uint64) ([]byte, error) {
+	return memoDestinationCreateC(MEMO_TYPE_DESTINATION, destination, numberOfRecipients, fee, totalOutlay)
+}
+
+// mc_memo_destination_with_payment_request_memo_create, the payment request id
+// is not a parameter of libmobilecoin and left zero
+func MCDestinationWithPaymentRequestMemoCreate(destination *account.PublicAddress, numberOfRecipients uint8, fee, totalOutlay uint64) ([]byte, error) {
+	return memoDestinationCreateC(MEMO_TYPE_DESTINATION_WITH_PAYMENT_REQUEST, destination, numberOfRecipients, fee, totalOutlay)
+}
+
+// mc_memo_destination_with_payment_intent_memo_create, the payment intent id
+// is not a parameter of libmobilecoin and left zero
+func MCDestinationWithPaymentIntentMemoCreate(destination *account.PublicAddress, numberOfRecipients uint8, fee, totalOutlay uint64) ([]byte, error) {
+	return memoDestinationCreateC(MEMO_TYPE_DESTINATION_WITH_PAYMENT_INTENT, destination, numberOfRecipients, fee, totalOutlay)
+}
+
+func memoDestinationCreateC(memoType uint16, destination *account.PublicAddress, numberOfRecipients uint8, fee, totalOutlay uint64) ([]byte, error) {
+	if numberOfRecipients == 0 {
+		return nil, errors.New("invalid number of recipients 0")
+	}
+	destination_address, free_destination_address := newPublicAddressC(destination)
+	defer free_destination_address()
+
+	return memoCreateC(memoType, func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		switch memoType {
+		case MEMO_TYPE_DESTINATION_WITH_PAYMENT_REQUEST:
+			return bool(C.mc_memo_destination_with_payment_request_memo_create(destination_address, C.uint8_t(numberOfRecipients), C.uint64_t(fee), C.uint64_t(totalOutlay), out, out_error))
+		case MEMO_TYPE_DESTINATION_WITH_PAYMENT_INTENT:
+			return bool(C.mc_memo_destination_with_payment_intent_memo_create(destination_address, C.uint8_t(numberOfRecipients), C.uint64_t(fee), C.uint64_t(totalOutlay), out, out_error))
+		default:
+			return bool(C.mc_memo_destination_memo_create(destination_address, C.uint8_t(numberOfRecipients), C.uint64_t(fee), C.uint64_t(totalOutlay), out, out_error))
+		}
+	})
+}
+
+// mc_memo_gift_code_funding_memo_create
+func MCGiftCodeFundingMemoCreate(txOutPublicKeyStr string, fee uint64, note string) ([]byte, error) {
+	tx_out_public_key, free_tx_out_public_key := newBufferC(account.HexToBytes(txOutPublicKeyStr))
+	defer free_tx_out_public_key()
+	c_note := C.CString(note)
+	defer C.free(unsafe.Pointer(c_note))
+
+	return memoCreateC(MEMO_TYPE_GIFT_CODE_FUNDING, func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		return bool(C.mc_memo_gift_code_funding_memo_create(tx_out_public_key, C.uint64_t(fee), c_note, out, out_error))
+	})
+}
+
+// mc_memo_gift_code_sender_memo_create
+func MCGiftCodeSenderMemoCreate(fee uint64, note string) ([]byte, error) {
+	c_note := C.CString(note)
+	defer C.free(unsafe.Pointer(c_note))
+
+	return memoCreateC(MEMO_TYPE_GIFT_CODE_SENDER, func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		return bool(C.mc_memo_gift_code_sender_memo_create(C.uint64_t(fee), c_note, out, out_error))
+	})
+}
+
+// mc_memo_gift_code_cancellation_memo_create
+func MCGiftCodeCancellationMemoCreate(fee, globalIndex uint64) ([]byte, error) {
+	return memoCreateC(MEMO_TYPE_GIFT_CODE_CANCELLATION, func(out *C.McMutableBuffer, out_error **C.McError) bool {
+		return bool(C.mc_memo_gift_code_cancellation_memo_create(C.uint64_t(fee), C.uint64_t(globalIndex), out, out_error))
+	})
+}
+
+// memoCreateC returns the memo payload with the type bytes and the 64 bytes
+// data written by create
+func memoCreateC(memoType uint16, create func(*C.McMutableBuffer, **C.McError) bool) ([]byte, error) {
+	out_memo_data, free_out_memo_data := newMutableBufferC(MEMO_DATA_LENGTH)
+	defer free_out_memo_data()
+
+	var out_error *C.McError
+	if !create(out_memo_data, &out_error) {
+		return nil, mcError(fmt.Sprintf("memo %04x create", memoType), out_error)
+	}
+	return joinMemoPayload(memoType, mutableBufferBytes(out_memo_data)), nil
+}
+
+func memoAddressHashC(name string, get func(*C.McMutableBuffer, **C.McError) bool) (string, error) {
+	out_short_address_hash, free_out_short_address_hash := newMutableBufferC(16)
+	defer free_out_short_address_hash()
+
+	var out_error *C.McError
+	if !get(out_short_address_hash, &out_error) {
+		return "", mcError(name, out_error)
+	}
+	return hex.EncodeToString(mutableBufferBytes(out_short_address_hash)), nil
+}
+
+func memoUint64C(name string, get func(*C.uint64_t, **C.McError) bool) (uint64, error) {
+	var out C.uint64_t
+	var out_error *C.McError
+	if !get(&out, &out_error) {
+		return 0, mcError(name, out_error)
+	}
+	return uint64(out), nil
+}
+
+func memoUint8C(name string, get func(*C.uint8_t, **C.McError) bool) (uint8, error) {
+	var out C.uint8_t
+	var out_error *C.McError
+	if !get(&out, &out_error) {
+		return 0, mcError(name, out_error)
+	}
+	return uint8(out), nil
+}
+
+// mc_memo_sender_memo_is_valid checks the HMAC of the sender memo payload,
+// or its payment request and intent variants, against the sender address and
+// the view private key of the receiving subaddress
+func MCSenderMemoIsValid(payload []byte, sender *account.PublicAddress, receivingSubaddressViewPrivate *ristretto.Scalar, txOutPublicKeyStr string) (bool, error) {
+	memoType, data, err := splitMemoPayload(payload)
+	if err != nil {
+		return false, err
+	}
+	memo_data, free_memo_data := newBufferC(data)
+	defer free_memo_data()
+	sender_address, free_sender_address := newPublicAddressC(sender)
+	defer free_sender_address()
+	view_private_key, free_view_private_key := newBufferC(receivingSubaddressViewPrivate.Bytes())
+	defer free_view_private_key()
+	tx_out_public_key, free_tx_out_public_key := newBufferC(account.HexToBytes(txOutPublicKeyStr))
+	defer free_tx_out_public_key()
+
+	var result bool
+	var out_error *C.McError
+	var b C.bool
+	switch memoType {
+	case MEMO_TYPE_SENDER:
+		b, err = C.mc_memo_sender_memo_is_valid(memo_data, sender_address, view_private_key, tx_out_public_key, (*C.bool)(&result), &out_error)
+	case MEMO_TYPE_SENDER_WITH_PAYMENT_REQUEST:
+		b, err = C.mc_memo_sender_with_payment_request_memo_is_valid(memo_data, sender_address, view_private_key, tx_out_public_key, (*C.bool)(&result), &out_error)
+	case MEMO_TYPE_SENDER_WITH_PAYMENT_INTENT:
+		b, err = C.mc_memo_sender_with_payment_intent_memo_is_valid(memo_data, sender_address, view_private_key, tx_out_public_key, (*C.bool)(&result), &out_error)
+	default:
+		return false, fmt.Errorf("memo type %04x is not a sender memo", memoType)
+	}
+	if err != nil {
+		return false, err
+	}
+	if !b {
+		return false, mcError(fmt.Sprintf("memo %04x is valid", memoType), out_error)
+	}
+	return result, nil
+}
+
+// newMemoBuilderC must be freed by mc_memo_builder_free, the account key of
+// the sender is required by the sender memo builders
+func newMemoBuilderC(opts *MemoOptions, account_key *C.McAccountKey) (*C.McTxOutMemoBuilder, error) {
+	if opts == nil {
+		opts = &MemoOptions{}
+	}
+
+	var memo_builder *C.McTxOutMemoBuilder
+	var err error
+	switch opts.Builder {
+	case MEMO_BUILDER_SENDER_PAYMENT_REQUEST_AND_DESTINATION:
+		memo_builder, err = C.mc_memo_builder_sender_payment_request_and_destination_create(C.uint64_t(opts.PaymentRequestID), account_key)
+	case MEMO_BUILDER_SENDER_PAYMENT_INTENT_AND_DESTINATION:
+		memo_builder, err = C.mc_memo_builder_sender_payment_intent_and_destination_create(C.uint64_t(opts.PaymentIntentID), account_key)
+	case MEMO_BUILDER_SENDER_AND_DESTINATION:
+		memo_builder, err = C.mc_memo_builder_sender_and_destination_create(account_key)
+	case MEMO_BUILDER_DEFAULT:
+		memo_builder, err = C.mc_memo_builder_default_create()
+	case MEMO_BUILDER_GIFT_CODE_FUNDING:
+		c_note := C.CString(opts.GiftCodeNote)
+		defer C.free(unsafe.Pointer(c_note))
+		memo_builder, err = C.mc_memo_builder_gift_code_funding_create(c_note)
+	case MEMO_BUILDER_GIFT_CODE_SENDER:
+		c_note := C.CString(opts.GiftCodeNote)
+		defer C.free(unsafe.Pointer(c_note))
+		memo_builder, err = C.mc_memo_builder_gift_code_sender_create(c_note)
+	case MEMO_BUILDER_GIFT_CODE_CANCELLATION:
+		memo_builder, err = C.mc_memo_builder_gift_code_cancellation_create(C.uint64_t(opts.GiftCodeGlobalIndex))
+	default:
+		return nil, fmt.Errorf("invalid memo builder %d", opts.Builder)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if memo_builder == nil {
+		return nil, fmt.Errorf("memo builder %d create failure", opts.Builder)
+	}
+	return memo_builder, nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+)
+
+var (
+	ErrInvalidSenderMemo = errors.New("invalid sender memo")
+	ErrUnknownSender     = errors.New("unknown sender")
+)
+
+// ContactBook maps the short address hash of the known addresses to their b58
+// codes, so that the sender memos of deposits could be attributed to them
+type ContactBook map[string]string
+
+func NewContactBook(addresses []string) (ContactBook, error) {
+	book := make(ContactBook)
+	for _, addressStr := range addresses {
+		address, err := account.DecodeB58Code(addressStr)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := MCAccountKeyGetShortAddressHash(address)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := book[hash]; found {
+			return nil, fmt.Errorf("duplicated address hash %s of %s", hash, addressStr)
+		}
+		book[hash] = addressStr
+	}
+	return book, nil
+}
+
+// AuthenticateSenderMemo decrypts the sender memo of a txOut received by the
+// subaddressIndex of acc, and returns it only if its HMAC proves that it was
+// written by senderStr.
+func AuthenticateSenderMemo(txOut *TxOut, acc *account.Account, subaddressIndex uint64, senderStr string) (Memo, error) {
+	memo, payload, err := decryptSenderMemo(txOut, acc)
+	if err != nil {
+		return nil, err
+	}
+	err = authenticateSenderMemo(txOut, acc, subaddressIndex, memo, payload, senderStr)
+	if err != nil {
+		return nil, err
+	}
+	return memo, nil
+}
+
+// Authenticate looks up the sender of the txOut memo in the book, and returns
+// its b58 code with the authenticated memo
+func (book ContactBook) Authenticate(txOut *TxOut, acc *account.Account, subaddressIndex uint64) (string, Memo, error) {
+	memo, payload, err := decryptSenderMemo(txOut, acc)
+	if err != nil {
+		return "", nil, err
+	}
+	hash := senderMemoAddressHash(memo)
+	senderStr, found := book[hash]
+	if !found {
+		return "", nil, fmt.Errorf("%w: address hash %s", ErrUnknownSender, hash)
+	}
+	err = authenticateSenderMemo(txOut, acc, subaddressIndex, memo, payload, senderStr)
+	if err != nil {
+		return "", nil, err
+	}
+	return senderStr, memo, nil
+}
+
+// authenticateSenderMemo checks the address hash and the HMAC of the decrypted
+// sender memo against senderStr, with the view private key of the receiving
+// subaddress
+func authenticateSenderMemo(txOut *TxOut, acc *account.Account, subaddressIndex uint64, memo Memo, payload []byte, senderStr string) error {
+	if acc.SpendPrivateKey == nil {
+		return errors.New("no spend private key")
+	}
+	sender, err := account.DecodeB58Code(senderStr)
+	if err != nil {
+		return err
+	}
+	hash, err := MCAccountKeyGetShortAddressHash(sender)
+	if err != nil {
+		return err
+	}
+	if senderMemoAddressHash(memo) != hash {
+		return fmt.Errorf("%w: address hash mismatch %s", ErrInvalidSenderMemo, senderStr)
+	}
+
+	view := acc.SubaddressViewPrivateKey(acc.SubaddressSpendPrivateKey(subaddressIndex))
+	valid, err := MCSenderMemoIsValid(payload, sender, view, txOut.PublicKey)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("%w: %s", ErrInvalidSenderMemo, senderStr)
+	}
+	return nil
+}
+
+func decryptSenderMemo(txOut *TxOut, acc *account.Account) (Memo, []byte, error) {
+	if txOut.EMemo == "" {
+		return nil, nil, fmt.Errorf("%w: no memo", ErrInvalidSenderMemo)
+	}
+	var spend string
+	if acc.SpendPrivateKey != nil {
+		spend = hex.EncodeToString(acc.SpendPrivateKey.Bytes())
+	}
+	payloadStr, err := DecryptEMemoPayload(txOut.EMemo, txOut.PublicKey, hex.EncodeToString(acc.ViewPrivateKey.Bytes()), spend)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload := account.HexToBytes(payloadStr)
+	memo, err := DecodeMemoPayload(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if senderMemoAddressHash(memo) == "" {
+		return nil, nil, fmt.Errorf("%w: memo type %04x", ErrInvalidSenderMemo, memo.MemoType())
+	}
+	return memo, payload, nil
+}
+
+func senderMemoAddressHash(memo Memo) string {
+	switch m := memo.(type) {
+	case *SenderMemo:
+		return m.AddressHash
+	case *SenderWithPaymentRequestMemo:
+		return m.AddressHash
+	case *SenderWithPaymentIntentMemo:
+		return m.AddressHash
+	}
+	return ""
+}
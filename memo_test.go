@@ -2,12 +2,17 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"testing"
 
+	account "github.com/MixinNetwork/mobilecoin-account"
 	"github.com/bwesterb/go-ristretto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMemo(t *testing.T) {
@@ -28,3 +33,233 @@ func TestMemo(t *testing.T) {
 	assert.Nil(err)
 	assert.True(bytes.Compare(data, plain) == 0)
 }
+
+func TestMemoPayload(t *testing.T) {
+	assert := assert.New(t)
+
+	data := make([]byte, MEMO_DATA_LENGTH)
+	payload := joinMemoPayload(MEMO_TYPE_GIFT_CODE_CANCELLATION, data)
+	assert.Len(payload, MEMO_PAYLOAD_LENGTH)
+	assert.Equal([]byte{0x02, 0x02}, payload[:2])
+	for typ, raw := range map[uint16][]byte{
+		MEMO_TYPE_GIFT_CODE_SENDER:       {0x00, 0x02},
+		MEMO_TYPE_GIFT_CODE_FUNDING:      {0x02, 0x01},
+		MEMO_TYPE_GIFT_CODE_CANCELLATION: {0x02, 0x02},
+	} {
+		memoType, _, err := splitMemoPayload(append(raw, data...))
+		assert.Nil(err)
+		assert.Equal(typ, memoType)
+	}
+	memoType, plain, err := splitMemoPayload(payload)
+	assert.Nil(err)
+	assert.Equal(uint16(MEMO_TYPE_GIFT_CODE_CANCELLATION), memoType)
+	assert.Equal(data, plain)
+	_, _, err = splitMemoPayload(payload[1:])
+	assert.NotNil(err)
+
+	memo, err := DecodeMemoPayload(joinMemoPayload(MEMO_TYPE_UNUSED, data))
+	assert.Nil(err)
+	assert.Equal(uint16(MEMO_TYPE_UNUSED), memo.MemoType())
+	_, err = DecodeMemoPayload(joinMemoPayload(0x0300, data))
+	assert.NotNil(err)
+}
+
+func TestSenderMemoAddressHash(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("01", senderMemoAddressHash(&SenderMemo{AddressHash: "01"}))
+	assert.Equal("02", senderMemoAddressHash(&SenderWithPaymentRequestMemo{AddressHash: "02", PaymentRequestID: 1}))
+	assert.Equal("03", senderMemoAddressHash(&SenderWithPaymentIntentMemo{AddressHash: "03", PaymentIntentID: 1}))
+	assert.Equal("", senderMemoAddressHash(&DestinationMemo{AddressHash: "04"}))
+
+	_, _, err := decryptSenderMemo(&TxOut{}, nil)
+	assert.True(errors.Is(err, ErrInvalidSenderMemo))
+	_, _, err = ContactBook{}.Authenticate(&TxOut{}, nil, 0)
+	assert.True(errors.Is(err, ErrInvalidSenderMemo))
+}
+
+func TestMemoOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	var opts *MemoOptions
+	assert.Nil(opts.validate())
+	assert.Nil((&MemoOptions{}).validate())
+	assert.Nil(PaymentRequestMemoOptions(7).validate())
+	assert.Equal(uint64(9), PaymentIntentMemoOptions(9).PaymentIntentID)
+	assert.Nil((&MemoOptions{Builder: MEMO_BUILDER_DEFAULT}).validate())
+	assert.NotNil((&MemoOptions{Builder: MEMO_BUILDER_GIFT_CODE_FUNDING}).validate())
+	assert.NotNil((&MemoOptions{Builder: 100}).validate())
+}
+
+func TestMemoRoundTripC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	sender := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	senderHash, err := MCAccountKeyGetShortAddressHash(sender.PublicAddress(0))
+	require.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	recipientAddress := recipient.PublicAddress(0)
+	recipientHash, err := MCAccountKeyGetShortAddressHash(recipientAddress)
+	require.Nil(err)
+	var txOutPublic ristretto.Point
+	txOutPublic.Rand()
+	txOutPublicKey := hex.EncodeToString(txOutPublic.Bytes())
+	senderView := hex.EncodeToString(view.Bytes())
+	senderSpend := hex.EncodeToString(spend.Bytes())
+
+	payload, err := MCSenderMemoCreate(senderView, senderSpend, recipientAddress.ViewPublicKey, txOutPublicKey)
+	require.Nil(err)
+	require.Len(payload, MEMO_PAYLOAD_LENGTH)
+	require.Equal([]byte{0x01, 0x00}, payload[:2])
+	require.Equal(senderHash, hex.EncodeToString(payload[2:18]))
+	require.Equal(make([]byte, 32), payload[18:50])
+	memo, err := DecodeMemoPayload(payload)
+	require.Nil(err)
+	require.Equal(&SenderMemo{AddressHash: senderHash}, memo)
+
+	payload, err = MCSenderWithPaymentRequestMemoCreate(senderView, senderSpend, recipientAddress.ViewPublicKey, txOutPublicKey, 0x0102030405060708)
+	require.Nil(err)
+	require.Equal([]byte{0x01, 0x01}, payload[:2])
+	require.Equal(senderHash, hex.EncodeToString(payload[2:18]))
+	require.Equal([]byte{1, 2, 3, 4, 5, 6, 7, 8}, payload[18:26])
+	memo, err = DecodeMemoPayload(payload)
+	require.Nil(err)
+	require.Equal(&SenderWithPaymentRequestMemo{AddressHash: senderHash, PaymentRequestID: 0x0102030405060708}, memo)
+
+	payload, err = MCDestinationMemoCreate(recipientAddress, 2, MOB_MINIMUM_FEE, 5*MILLIMOB_TO_PICOMOB)
+	require.Nil(err)
+	require.Equal([]byte{0x02, 0x00}, payload[:2])
+	require.Equal(recipientHash, hex.EncodeToString(payload[2:18]))
+	require.Equal(byte(2), payload[18])
+	require.Equal(uint64(MOB_MINIMUM_FEE), binary.BigEndian.Uint64(append([]byte{0}, payload[19:26]...)))
+	require.Equal(uint64(5*MILLIMOB_TO_PICOMOB), binary.BigEndian.Uint64(payload[26:34]))
+	memo, err = DecodeMemoPayload(payload)
+	require.Nil(err)
+	require.Equal(&DestinationMemo{AddressHash: recipientHash, NumberOfRecipients: 2, Fee: MOB_MINIMUM_FEE, TotalOutlay: 5 * MILLIMOB_TO_PICOMOB}, memo)
+	_, err = MCDestinationMemoCreate(recipientAddress, 0, MOB_MINIMUM_FEE, 5*MILLIMOB_TO_PICOMOB)
+	require.NotNil(err)
+
+	payload, err = MCGiftCodeFundingMemoCreate(txOutPublicKey, MOB_MINIMUM_FEE, "happy birthday")
+	require.Nil(err)
+	require.Equal([]byte{0x02, 0x01}, payload[:2])
+	require.Equal(uint64(MOB_MINIMUM_FEE), binary.BigEndian.Uint64(append([]byte{0}, payload[6:13]...)))
+	require.Equal([]byte("happy birthday\x00"), payload[13:28])
+	memo, err = DecodeMemoPayload(payload)
+	require.Nil(err)
+	require.Equal(&GiftCodeFundingMemo{Note: "happy birthday", Fee: MOB_MINIMUM_FEE}, memo)
+
+	payload, err = MCGiftCodeSenderMemoCreate(MOB_MINIMUM_FEE, "thanks")
+	require.Nil(err)
+	require.Equal([]byte{0x00, 0x02}, payload[:2])
+	require.Equal(uint64(MOB_MINIMUM_FEE), binary.BigEndian.Uint64(append([]byte{0}, payload[2:9]...)))
+	require.Equal([]byte("thanks\x00"), payload[9:16])
+	memo, err = DecodeMemoPayload(payload)
+	require.Nil(err)
+	require.Equal(&GiftCodeSenderMemo{Note: "thanks", Fee: MOB_MINIMUM_FEE}, memo)
+	_, err = MCGiftCodeSenderMemoCreate(MOB_MINIMUM_FEE, string(bytes.Repeat([]byte{'a'}, 59)))
+	require.NotNil(err)
+
+	payload, err = MCGiftCodeCancellationMemoCreate(MOB_MINIMUM_FEE, 4242)
+	require.Nil(err)
+	require.Equal([]byte{0x02, 0x02}, payload[:2])
+	require.Equal(uint64(4242), binary.BigEndian.Uint64(payload[2:10]))
+	require.Equal(uint64(MOB_MINIMUM_FEE), binary.BigEndian.Uint64(append([]byte{0}, payload[10:17]...)))
+	memo, err = DecodeMemoPayload(payload)
+	require.Nil(err)
+	require.Equal(&GiftCodeCancellationMemo{GiftCodeTxOutIndex: 4242, Fee: MOB_MINIMUM_FEE}, memo)
+}
+
+func TestAuthenticateSenderMemoC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	sender := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	senderAddress, err := sender.B58Code(0)
+	require.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	recipientAddress, err := recipient.B58Code(1)
+	require.Nil(err)
+	var otherView, otherSpend ristretto.Scalar
+	other := &account.Account{ViewPrivateKey: otherView.Rand(), SpendPrivateKey: otherSpend.Rand()}
+	otherAddress, err := other.B58Code(0)
+	require.Nil(err)
+
+	inputs, proofs := newTestInputs(assert.New(t), sender, []uint64{10 * MILLIMOB_TO_PICOMOB}, 0)
+	outlays := []*PaymentOutlay{{Address: recipientAddress, Amount: 4 * MILLIMOB_TO_PICOMOB}}
+	output, err := TransactionBuilderBuildOutlaysWithMemo(context.Background(), inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, 3, senderAddress, &MemoOptions{Builder: MEMO_BUILDER_SENDER_AND_DESTINATION}, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	tx, err := decodeTestTx(output.RawTransaction)
+	require.Nil(err)
+	scanner, err := NewScanner(hex.EncodeToString(recipientView.Bytes()), hex.EncodeToString(account.PublicKey(&recipientSpend).Bytes()), 2)
+	require.Nil(err)
+	matches, err := scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(uint64(1), matches[0].SubaddressIndex)
+	out := matches[0].TxOut
+
+	senderHash, err := MCAccountKeyGetShortAddressHash(sender.PublicAddress(0))
+	require.Nil(err)
+	memo, err := AuthenticateSenderMemo(out, recipient, 1, senderAddress)
+	require.Nil(err)
+	require.Equal(&SenderMemo{AddressHash: senderHash}, memo)
+	book, err := NewContactBook([]string{otherAddress, senderAddress})
+	require.Nil(err)
+	from, memo, err := book.Authenticate(out, recipient, 1)
+	require.Nil(err)
+	require.Equal(senderAddress, from)
+	require.Equal(&SenderMemo{AddressHash: senderHash}, memo)
+
+	// the HMAC is keyed by the view private key of the receiving subaddress
+	_, err = AuthenticateSenderMemo(out, recipient, 0, senderAddress)
+	require.True(errors.Is(err, ErrInvalidSenderMemo))
+	_, _, err = book.Authenticate(out, recipient, 0)
+	require.True(errors.Is(err, ErrInvalidSenderMemo))
+	_, err = AuthenticateSenderMemo(out, recipient, 1, otherAddress)
+	require.True(errors.Is(err, ErrInvalidSenderMemo))
+	book, err = NewContactBook([]string{otherAddress})
+	require.Nil(err)
+	_, _, err = book.Authenticate(out, recipient, 1)
+	require.True(errors.Is(err, ErrUnknownSender))
+}
+
+func TestSenderMemoHMACC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	sender := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	var txOutPublic ristretto.Point
+	txOutPublic.Rand()
+	txOutPublicKey := hex.EncodeToString(txOutPublic.Bytes())
+
+	payload, err := MCSenderWithPaymentRequestMemoCreate(hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes()), recipient.PublicAddress(1).ViewPublicKey, txOutPublicKey, 7)
+	require.Nil(err)
+	subaddressView := recipient.SubaddressViewPrivateKey(recipient.SubaddressSpendPrivateKey(1))
+	valid, err := MCSenderMemoIsValid(payload, sender.PublicAddress(0), subaddressView, txOutPublicKey)
+	require.Nil(err)
+	require.True(valid)
+
+	defaultView := recipient.SubaddressViewPrivateKey(recipient.SubaddressSpendPrivateKey(0))
+	valid, err = MCSenderMemoIsValid(payload, sender.PublicAddress(0), defaultView, txOutPublicKey)
+	require.Nil(err)
+	require.False(valid)
+	var otherPublic ristretto.Point
+	otherPublic.Rand()
+	valid, err = MCSenderMemoIsValid(payload, sender.PublicAddress(0), subaddressView, hex.EncodeToString(otherPublic.Bytes()))
+	require.Nil(err)
+	require.False(valid)
+	payload[len(payload)-1] ^= 1
+	valid, err = MCSenderMemoIsValid(payload, sender.PublicAddress(0), subaddressView, txOutPublicKey)
+	require.Nil(err)
+	require.False(valid)
+	_, err = MCSenderMemoIsValid(joinMemoPayload(MEMO_TYPE_DESTINATION, payload[2:]), sender.PublicAddress(0), subaddressView, txOutPublicKey)
+	require.NotNil(err)
+}
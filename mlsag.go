@@ -0,0 +1,202 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bwesterb/go-ristretto"
+	"github.com/dchest/blake2b"
+)
+
+type mlsagRingElement struct {
+	TargetKey  *ristretto.Point
+	Commitment *ristretto.Point
+}
+
+// SignRingMLSAG signs message with the onetime private key of ring[realIndex],
+// proving the output commitment has the same value as the real input commitment.
+func SignRingMLSAG(message []byte, ring []*TxOut, realIndex int, onetimePrivate *ristretto.Scalar, value uint64, blinding, outputBlinding *ristretto.Scalar, tokenID uint64) (*RingMLSAG, error) {
+	return signRingMLSAG(rand.Reader, message, ring, realIndex, onetimePrivate, value, blinding, outputBlinding, tokenID)
+}
+
+// signRingMLSAG draws the random responses of the other ring elements in the
+// ring order from rng, then the two alphas of the real element.
+func signRingMLSAG(rng io.Reader, message []byte, ring []*TxOut, realIndex int, onetimePrivate *ristretto.Scalar, value uint64, blinding, outputBlinding *ristretto.Scalar, tokenID uint64) (*RingMLSAG, error) {
+	ringSize := len(ring)
+	if realIndex < 0 || realIndex >= ringSize {
+		return nil, fmt.Errorf("invalid real index %d, ring size %d", realIndex, ringSize)
+	}
+	elements, err := decodeMLSAGRing(ring)
+	if err != nil {
+		return nil, err
+	}
+
+	var G ristretto.Point
+	G.SetBase()
+	keyImage := KeyImageFromPrivate(onetimePrivate)
+	outputCommitment := NewPedersenGensForToken(tokenID).Commit(uint64ToScalar(value), outputBlinding)
+
+	var z ristretto.Scalar
+	z.Sub(outputBlinding, blinding)
+
+	// The difference of the real commitments must be a commitment to zero.
+	var diff, zG ristretto.Point
+	diff.Sub(outputCommitment, elements[realIndex].Commitment)
+	if !diff.Equals(zG.ScalarMult(&G, &z)) {
+		return nil, errors.New("value not conserved")
+	}
+
+	c := make([]*ristretto.Scalar, ringSize)
+	r := make([]*ristretto.Scalar, 2*ringSize)
+	for i := 0; i < ringSize; i++ {
+		if i == realIndex {
+			continue
+		}
+		r[2*i], err = randomScalar(rng)
+		if err != nil {
+			return nil, err
+		}
+		r[2*i+1], err = randomScalar(rng)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	alpha0, err := randomScalar(rng)
+	if err != nil {
+		return nil, err
+	}
+	alpha1, err := randomScalar(rng)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := 0; n < ringSize; n++ {
+		i := (realIndex + n) % ringSize
+		element := elements[i]
+
+		var L0, R0, L1 ristretto.Point
+		if i == realIndex {
+			L0.ScalarMult(&G, alpha0)
+			R0.ScalarMult(hashToPoint(element.TargetKey), alpha0)
+			L1.ScalarMult(&G, alpha1)
+		} else {
+			mlsagChallengePoints(&L0, &R0, &L1, element, r[2*i], r[2*i+1], c[i], keyImage, outputCommitment)
+		}
+		c[(i+1)%ringSize] = mlsagChallenge(message, keyImage, &L0, &R0, &L1)
+	}
+
+	var s0, s1, t ristretto.Scalar
+	r[2*realIndex] = s0.Sub(alpha0, t.Mul(c[realIndex], onetimePrivate))
+	r[2*realIndex+1] = s1.Sub(alpha1, t.Mul(c[realIndex], &z))
+
+	responses := make([]string, len(r))
+	for i, s := range r {
+		responses[i] = hex.EncodeToString(s.Bytes())
+	}
+	return &RingMLSAG{
+		CZero:     hex.EncodeToString(c[0].Bytes()),
+		Responses: responses,
+		KeyImage:  hex.EncodeToString(keyImage.Bytes()),
+	}, nil
+}
+
+// VerifyRingMLSAG checks the signature of message against ring and the
+// pseudo output commitment of the input.
+func VerifyRingMLSAG(mlsag *RingMLSAG, message []byte, ring []*TxOut, outputCommitment string) error {
+	ringSize := len(ring)
+	if ringSize == 0 {
+		return errors.New("empty ring")
+	}
+	if len(mlsag.Responses) != 2*ringSize {
+		return fmt.Errorf("invalid responses len %d, ring size %d", len(mlsag.Responses), ringSize)
+	}
+	elements, err := decodeMLSAGRing(ring)
+	if err != nil {
+		return err
+	}
+	keyImage, err := decodePoint(mlsag.KeyImage)
+	if err != nil {
+		return fmt.Errorf("invalid key image %s: %v", mlsag.KeyImage, err)
+	}
+	commitment, err := decodePoint(outputCommitment)
+	if err != nil {
+		return fmt.Errorf("invalid output commitment %s: %v", outputCommitment, err)
+	}
+	cZero, err := decodeScalar(mlsag.CZero)
+	if err != nil {
+		return err
+	}
+	r := make([]*ristretto.Scalar, len(mlsag.Responses))
+	for i, resp := range mlsag.Responses {
+		r[i], err = decodeScalar(resp)
+		if err != nil {
+			return err
+		}
+	}
+
+	c := cZero
+	for i := 0; i < ringSize; i++ {
+		var L0, R0, L1 ristretto.Point
+		mlsagChallengePoints(&L0, &R0, &L1, elements[i], r[2*i], r[2*i+1], c, keyImage, commitment)
+		c = mlsagChallenge(message, keyImage, &L0, &R0, &L1)
+	}
+	if !c.Equals(cZero) {
+		return errors.New("invalid ring signature")
+	}
+	return nil
+}
+
+// L0 = r0 * G + c * P
+// R0 = r0 * Hp(P) + c * I
+// L1 = r1 * G + c * (output_commitment - input_commitment)
+func mlsagChallengePoints(L0, R0, L1 *ristretto.Point, element *mlsagRingElement, r0, r1, c *ristretto.Scalar, keyImage, outputCommitment *ristretto.Point) {
+	var G, t, diff ristretto.Point
+	G.SetBase()
+
+	L0.ScalarMult(&G, r0)
+	L0.Add(L0, t.ScalarMult(element.TargetKey, c))
+
+	R0.ScalarMult(hashToPoint(element.TargetKey), r0)
+	R0.Add(R0, t.ScalarMult(keyImage, c))
+
+	diff.Sub(outputCommitment, element.Commitment)
+	L1.ScalarMult(&G, r1)
+	L1.Add(L1, t.ScalarMult(&diff, c))
+}
+
+func mlsagChallenge(message []byte, keyImage, L0, R0, L1 *ristretto.Point) *ristretto.Scalar {
+	hash := blake2b.New512()
+	hash.Write([]byte(RING_MLSAG_CHALLENGE_DOMAIN_TAG))
+	hash.Write(message)
+	hash.Write(keyImage.Bytes())
+	hash.Write(L0.Bytes())
+	hash.Write(R0.Bytes())
+	hash.Write(L1.Bytes())
+	return fromBytesModOrderWide(hash.Sum(nil))
+}
+
+func decodeMLSAGRing(ring []*TxOut) ([]*mlsagRingElement, error) {
+	elements := make([]*mlsagRingElement, len(ring))
+	for i, out := range ring {
+		if out.Amount == nil {
+			return nil, fmt.Errorf("ring element %d without amount", i)
+		}
+		targetKey, err := decodePoint(out.TargetKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ring element %d target key: %v", i, err)
+		}
+		commitment, err := decodePoint(out.Amount.Commitment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ring element %d commitment: %v", i, err)
+		}
+		elements[i] = &mlsagRingElement{
+			TargetKey:  targetKey,
+			Commitment: commitment,
+		}
+	}
+	return elements, nil
+}
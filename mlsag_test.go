@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingMLSAG(t *testing.T) {
+	assert := assert.New(t)
+
+	message := []byte("mobilecoin ring mlsag")
+	value := uint64(12 * MILLIMOB_TO_PICOMOB)
+	realIndex := 3
+	generators := NewPedersenGens()
+
+	var onetimePrivate, blinding, outputBlinding ristretto.Scalar
+	onetimePrivate.Rand()
+	blinding.Rand()
+	outputBlinding.Rand()
+
+	ring := make([]*TxOut, RING_SIZE)
+	for i := range ring {
+		var targetKey, commitment ristretto.Point
+		targetKey.Rand()
+		commitment.Rand()
+		if i == realIndex {
+			targetKey.ScalarMultBase(&onetimePrivate)
+			commitment.Set(generators.Commit(uint64ToScalar(value), &blinding))
+		}
+		ring[i] = &TxOut{
+			TargetKey: hex.EncodeToString(targetKey.Bytes()),
+			Amount:    &Amount{Commitment: hex.EncodeToString(commitment.Bytes())},
+		}
+	}
+	outputCommitment := hex.EncodeToString(generators.Commit(uint64ToScalar(value), &outputBlinding).Bytes())
+
+	mlsag, err := SignRingMLSAG(message, ring, realIndex, &onetimePrivate, value, &blinding, &outputBlinding, 0)
+	assert.Nil(err)
+	assert.Len(mlsag.Responses, 2*RING_SIZE)
+	assert.Equal(hex.EncodeToString(KeyImageFromPrivate(&onetimePrivate).Bytes()), mlsag.KeyImage)
+	assert.Nil(VerifyRingMLSAG(mlsag, message, ring, outputCommitment))
+
+	assert.NotNil(VerifyRingMLSAG(mlsag, []byte("another message"), ring, outputCommitment))
+	assert.NotNil(VerifyRingMLSAG(mlsag, message, ring[1:], outputCommitment))
+	assert.NotNil(VerifyRingMLSAG(mlsag, message, ring, ring[0].Amount.Commitment))
+
+	_, err = SignRingMLSAG(message, ring, realIndex, &onetimePrivate, value+1, &blinding, &outputBlinding, 0)
+	assert.NotNil(err)
+}
+
+// The vector is computed apart from this package with curve25519-dalek, the
+// scalars are sha512 of their labels reduced wide, and the decoys are
+// multiples of the basepoint.
+func TestRingMLSAGVector(t *testing.T) {
+	assert := assert.New(t)
+
+	message := []byte("mobilecoin ring mlsag")
+	value := uint64(12 * MILLIMOB_TO_PICOMOB)
+	realIndex := 3
+	onetimePrivate := hexToScalar("ccc434acf1dd5e86d8040a6c6546342991289b56ded4cee1e4f846774f83e60e")
+	blinding := hexToScalar("b3077f0b3222be4188167ad29ac97d90cae4f4ea296e1536f165204f1ff28d05")
+	outputBlinding := hexToScalar("93212b3696344e7e17ddac3c83a68a7c793059512fbe41c65cc5c92abbd1f30e")
+	outputCommitment := "eeaeff474f668c0699663321a60393232b0c8beb0ecc2c58b1577143df888a49"
+
+	keys := [][2]string{
+		{"a82e09beed13834918eb5e8787ef62aa5615ef51642d0d62ad4536a9bfddbc16", "4c581ae0792702f04105bff13b3704a66d14a84779205dacf1912a903e107b48"},
+		{"e8f45d1c5dadace99afe80ab8c1e1119ac8d54e233835b3cb0b9e60c8180d512", "44ba1c139eb3d36409b690c1e6c2fd78b003d217cdacda57e9ab52069020ed76"},
+		{"72b838e7b2d34e4057192f00c874e7d696730a8da46a3f25cba3f58e89316912", "84367b62907f598baccc5e0872eb23a4707cf5c37ba2ec7f588ec83d51887207"},
+		{"d49159c390bda4cdd740d087f480968689fbbcb51fe65fefa761af474161eb58", "cc43dfbdf142c2b5ee9e30138da1bef2cb0bf3765cc31f3750a0e50cb274a210"},
+		{"2259d7a9d02791b97f4d4c2b400b885ead78c840c7f08bf32581108aed5bce4d", "9621adff0a2defb31201be555ad545530989f497f701f95477ca5549f38a0702"},
+		{"da93574a0d096917aa049bcf4dc18ed784584ebde5977da70451176f615fc32c", "6cbe80a264512eeebf659a93775b32431b651c6c6f78794726bf346e546d3407"},
+		{"1a9e29b9529c476c27d41d4b2a94e8f13367f6f4ddfc5073d4912f6455bfc036", "88c4b4be3526b6553a6c2775f578ac7282208678193e53a8be643980537c5f53"},
+		{"d8404b1a5856f16fc48e7b075b6911bb8f4b2e733a0872ca856eb10387c5b11b", "70d17fef60eebba3a52aac5829dda1d0fa113d7370f06c9a87948959f9902c38"},
+		{"3c44d546f17a750d6d880dd41e668f2baa585c12b2c41d50a2b083587cd79452", "90b109b4ec5cb1f3551e0bd60ec6c900e52a3e9ae4814cbb7d907f4eff83a160"},
+		{"ae4edf1db7cf69c456e3eb6ad80d2209d0c9f044ff14a8a88167a5798cfae436", "5e81caf6339f7271aa1cc52668a1eb0e03f3c66a812d3586dac36a83813b940f"},
+		{"fee10d84d7d8cbe3a420392e17184a302862cf95909b562418cc05a7d530b71f", "d4729c5a1c3fe1942b8183a0d644fa5334e98e2a27ebc41471c90d9eb79a2272"},
+	}
+	ring := make([]*TxOut, len(keys))
+	for i, k := range keys {
+		ring[i] = &TxOut{TargetKey: k[0], Amount: &Amount{Commitment: k[1]}}
+	}
+
+	nonces := &nonceReader{label: []byte("mobilecoin mlsag nonce")}
+	mlsag, err := signRingMLSAG(nonces, message, ring, realIndex, onetimePrivate, value, blinding, outputBlinding, 0)
+	assert.Nil(err)
+	assert.Equal("5c63a7a77c9ba86d5cf91dc60f212e5dab8bad688510f3a7c32241893eec4121", mlsag.KeyImage)
+	assert.Equal("3b48cb2b6e69d03ae98d906f5737350e9e500389254317994a38be868abe500a", mlsag.CZero)
+	assert.Equal([]string{
+		"b0e887634343acf96eb82da7ecd079aa9fc83c7b80dde3a87e0cdd2034c9b402",
+		"b1cfa8340e38fec6594f3f11c8df8aa5d6e9dcc09921ad5a28fbbe96c010360b",
+		"86b0ef75ee2d5efba6a9ddad28f23c23f603a67490ee64e328683cdcf21a3003",
+		"43c53c13e6c33525cd2b4f637af2727f966862c6bc40a1d773ef4f923416ff0c",
+		"3ab75699160e5e702ee99c0b57e5d3c7a4442ae219636aa90ba27dd5b8425501",
+		"cac73e4ef3e8adaa118a12d1f327c36d93aa57b0834755cf757d4fe56523a70b",
+		"f2b5aa54da7f1f9b2a518cee7397a61e546348d9e81409c5d665fcc99dd59208",
+		"9f07991cf60a18703a177a5657a751871f4c3d469eefd0f2285f3a0328014f01",
+		"7c95ebb16ed8f1e24d24a46ff4058c11c69bd6e4e4c5ca84868cbe1fc735d109",
+		"6af6bcc95e735a03b848f945c1cc6e0415086279ef5ea06d49353bd38d711006",
+		"52a8de227ebc901113d1fe4797465377cf939096e3ec59bb3af3827291fa000f",
+		"dbe33c9a7bcca9e7727e3b8dba4345d7961ecc9db04679340744f17d31ec7f0d",
+		"01f238104202722de43caa6d346efebc38f8a5522c30900e5d257f7d9641270f",
+		"72a283976d3b599356064cb2fa59d7f2fe418e06263ecdc2b9abb4f69aabad03",
+		"54ac9ba37e87a415c0fb77f8d71539b6f45e444ecdd5e43ab233ea1d6b2b9e06",
+		"0fe576fb091318f357b46a914d4bf65b21e562d93d6bebc189bef04df55d530c",
+		"0cc28309e6a72f0198cfd2b2b07b3b1369d3c417fd03f9132795757b5f244c0e",
+		"82406625167f4079ea61ad835f3d4481168f468b458334d4f1cb62b20aff8f0a",
+		"d0851f0fb87cf0dd4f4be15b1e4018c1c0aa1835b9936f30367e2c275035c604",
+		"e908d26bcb22aa00d5977d24eb7a359dc6255097448bee26cd7744673cc80501",
+		"12a439c966b876e26a57d09cd53bd60635ad5e00b136bb6d00b9ab0ec9336c05",
+		"2c2f6aed493f28683b57fd7dbfc33aeae691772554e9aa15bfb287d90d539f0c",
+	}, mlsag.Responses)
+	assert.Nil(VerifyRingMLSAG(mlsag, message, ring, outputCommitment))
+}
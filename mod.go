@@ -1,8 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"io"
 
 	"github.com/bwesterb/go-ristretto"
 	"github.com/dchest/blake2b"
@@ -73,6 +76,39 @@ func hexToPoint(h string) *ristretto.Point {
 	return &s
 }
 
+// decodePoint rejects the buffers which are not a canonical ristretto encoding
+func decodePoint(h string) (*ristretto.Point, error) {
+	buf, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, err
+	}
+	var p ristretto.Point
+	err = p.UnmarshalBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// decodeScalar rejects the buffers which are not reduced modulo the group order
+func decodeScalar(h string) (*ristretto.Scalar, error) {
+	buf, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != 32 {
+		return nil, fmt.Errorf("invalid scalar length %d", len(buf))
+	}
+	var buf32 [32]byte
+	copy(buf32[:], buf)
+	var s ristretto.Scalar
+	s.SetBytes(&buf32)
+	if !bytes.Equal(s.Bytes(), buf) {
+		return nil, fmt.Errorf("non canonical scalar %s", h)
+	}
+	return &s, nil
+}
+
 func multiscalarMul(scalars []*ristretto.Scalar, points []*ristretto.Point) *ristretto.Point {
 	var p ristretto.Point
 	p.SetZero()
@@ -89,6 +125,16 @@ func createSharedSecret(public *ristretto.Point, private *ristretto.Scalar) *ris
 	return r.ScalarMult(public, private)
 }
 
+// randomScalar reduces 64 bytes of rng like Scalar::random of dalek
+func randomScalar(rng io.Reader) (*ristretto.Scalar, error) {
+	var data [64]byte
+	_, err := io.ReadFull(rng, data[:])
+	if err != nil {
+		return nil, err
+	}
+	return fromBytesModOrderWide(data[:]), nil
+}
+
 func fromBytesModOrderWide(data []byte) *ristretto.Scalar {
 	var data64 [64]byte
 	copy(data64[:], data)
@@ -94,7 +94,10 @@ func generatorsBlinding(base *ristretto.Point) *ristretto.Point {
 
 func RecoverPublicSubaddressSpendKey(viewPrivate, onetimePublicKey, publicKey string) (*ristretto.Point, error) {
 	var a ristretto.Scalar
-	R := hexToPoint(publicKey)
+	R, err := decodePoint(publicKey)
+	if err != nil {
+		return nil, err
+	}
 	var aBytes [32]byte
 	aData, err := hex.DecodeString(viewPrivate)
 	if err != nil {
@@ -112,7 +115,10 @@ func RecoverPublicSubaddressSpendKey(viewPrivate, onetimePublicKey, publicKey st
 	copy(key[:], hash.Sum(nil))
 
 	// p
-	p := hexToPoint(onetimePublicKey)
+	p, err := decodePoint(onetimePublicKey)
+	if err != nil {
+		return nil, err
+	}
 
 	var g ristretto.Point
 	var r1, r ristretto.Point
@@ -132,6 +138,21 @@ func GetBlindingFactorsV2(secret []byte) (uint64, error) {
 	return binary.LittleEndian.Uint64(value_mask), nil
 }
 
+// get_blinding_factors, the blinding of the commitment of a v2 amount
+func GetBlindingV2(secret []byte) (*ristretto.Scalar, error) {
+	blinding := make([]byte, 64)
+	kdf := hkdf.New(sha512.New, secret, []byte(AMOUNT_BLINDING_FACTORS_DOMAIN_TAG), []byte(AMOUNT_BLINDING_DOMAIN_TAG))
+	_, err := io.ReadFull(kdf, blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	var hs ristretto.Scalar
+	var key [64]byte
+	copy(key[:], blinding)
+	return hs.SetReduced(&key), nil
+}
+
 // compute_commitment
 func ComputeCommitmentV2(masked_value uint64, secret []byte) (uint64, error) {
 	value_mask, err := GetBlindingFactorsV2(secret)
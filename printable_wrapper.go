@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/base58"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// PrintableWrapper is the b58 code shared between wallets, exactly one of
+// the variants is set.
+type PrintableWrapper struct {
+	PublicAddress   *account.PublicAddress `json:"public_address,omitempty"`
+	PaymentRequest  *PaymentRequest        `json:"payment_request,omitempty"`
+	TransferPayload *TransferPayload       `json:"transfer_payload,omitempty"`
+}
+
+// PaymentRequest asks to pay Value of TokenID to Address, a zero value lets
+// the payer choose the amount.
+type PaymentRequest struct {
+	Address *account.PublicAddress `json:"address"`
+	Value   uint64                 `json:"value,string"`
+	Memo    string                 `json:"memo"`
+	TokenID uint64                 `json:"token_id,string"`
+}
+
+// TransferPayload gives the hex entropy of an account holding the TxOut with
+// the hex TxOutPublicKey, the gift codes are transfer payloads.
+type TransferPayload struct {
+	RootEntropy    string `json:"root_entropy,omitempty"`
+	Bip39Entropy   string `json:"bip39_entropy,omitempty"`
+	TxOutPublicKey string `json:"tx_out_public_key"`
+	Memo           string `json:"memo"`
+}
+
+func DecodePrintableWrapper(code string) (*PrintableWrapper, error) {
+	wrapper, err := decodePrintableWrapper(code)
+	if err != nil {
+		return nil, err
+	}
+	switch w := wrapper.Wrapper.(type) {
+	case *types.PrintableWrapper_PublicAddress:
+		return &PrintableWrapper{PublicAddress: publicAddressFromProto(w.PublicAddress)}, nil
+	case *types.PrintableWrapper_PaymentRequest:
+		if w.PaymentRequest.GetPublicAddress() == nil {
+			return nil, fmt.Errorf("invalid payment request %s", code)
+		}
+		return &PrintableWrapper{PaymentRequest: &PaymentRequest{
+			Address: publicAddressFromProto(w.PaymentRequest.GetPublicAddress()),
+			Value:   w.PaymentRequest.GetValue(),
+			Memo:    w.PaymentRequest.GetMemo(),
+			TokenID: w.PaymentRequest.GetTokenId(),
+		}}, nil
+	case *types.PrintableWrapper_TransferPayload:
+		return &PrintableWrapper{TransferPayload: &TransferPayload{
+			RootEntropy:    hex.EncodeToString(w.TransferPayload.GetRootEntropy()),
+			Bip39Entropy:   hex.EncodeToString(w.TransferPayload.GetBip39Entropy()),
+			TxOutPublicKey: hex.EncodeToString(w.TransferPayload.GetTxOutPublicKey().GetData()),
+			Memo:           w.TransferPayload.GetMemo(),
+		}}, nil
+	}
+	return nil, fmt.Errorf("unsupported printable wrapper %s", code)
+}
+
+func (w *PrintableWrapper) B58Code() (string, error) {
+	var wrapper types.PrintableWrapper
+	switch {
+	case w.PublicAddress != nil && w.PaymentRequest == nil && w.TransferPayload == nil:
+		address, err := PublicAddressToProtobuf(w.PublicAddress)
+		if err != nil {
+			return "", err
+		}
+		wrapper.Wrapper = &types.PrintableWrapper_PublicAddress{PublicAddress: address}
+	case w.PublicAddress == nil && w.PaymentRequest != nil && w.TransferPayload == nil:
+		if w.PaymentRequest.Address == nil {
+			return "", errors.New("empty payment request address")
+		}
+		address, err := PublicAddressToProtobuf(w.PaymentRequest.Address)
+		if err != nil {
+			return "", err
+		}
+		wrapper.Wrapper = &types.PrintableWrapper_PaymentRequest{PaymentRequest: &types.PaymentRequest{
+			PublicAddress: address,
+			Value:         w.PaymentRequest.Value,
+			Memo:          w.PaymentRequest.Memo,
+			TokenId:       w.PaymentRequest.TokenID,
+		}}
+	case w.PublicAddress == nil && w.PaymentRequest == nil && w.TransferPayload != nil:
+		rootEntropy, err := hex.DecodeString(w.TransferPayload.RootEntropy)
+		if err != nil {
+			return "", err
+		}
+		bip39Entropy, err := hex.DecodeString(w.TransferPayload.Bip39Entropy)
+		if err != nil {
+			return "", err
+		}
+		txOutPublicKey, err := hex.DecodeString(w.TransferPayload.TxOutPublicKey)
+		if err != nil {
+			return "", err
+		}
+		wrapper.Wrapper = &types.PrintableWrapper_TransferPayload{TransferPayload: &types.TransferPayload{
+			RootEntropy:    rootEntropy,
+			TxOutPublicKey: &types.CompressedRistretto{Data: txOutPublicKey},
+			Memo:           w.TransferPayload.Memo,
+			Bip39Entropy:   bip39Entropy,
+		}}
+	default:
+		return "", errors.New("invalid printable wrapper variant")
+	}
+	return encodePrintableWrapper(&wrapper)
+}
+
+// decodeRecipient accepts a b58 public address or payment request to pay
+// amount of tokenID, the payment is returned as a request with the Memo of
+// the payment request if any. The amount of the request is used when amount
+// is 0, otherwise they must be equal, and a zero payment is rejected.
+func decodeRecipient(code string, amount uint64, tokenID uint) (*PaymentRequest, error) {
+	w, err := DecodePrintableWrapper(code)
+	if err != nil {
+		return nil, err
+	}
+	payment := &PaymentRequest{Address: w.PublicAddress, Value: amount, TokenID: uint64(tokenID)}
+	if request := w.PaymentRequest; request != nil {
+		if request.TokenID != uint64(tokenID) {
+			return nil, fmt.Errorf("payment request token %d, required %d", request.TokenID, tokenID)
+		}
+		if amount == 0 {
+			amount = request.Value
+		}
+		if request.Value > 0 && request.Value != amount {
+			return nil, fmt.Errorf("payment request value %d, required %d", request.Value, amount)
+		}
+		payment.Address, payment.Value, payment.Memo = request.Address, amount, request.Memo
+	}
+	if payment.Address == nil {
+		return nil, fmt.Errorf("invalid recipient %s", code)
+	}
+	if payment.Value == 0 {
+		return nil, fmt.Errorf("zero amount to recipient %s", code)
+	}
+	return payment, nil
+}
+
+func decodePrintableWrapper(code string) (*types.PrintableWrapper, error) {
+	data := base58.Decode(code)
+	if len(data) < 4 {
+		return nil, fmt.Errorf("invalid printable wrapper %s", code)
+	}
+	sum := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sum, crc32.ChecksumIEEE(data[4:]))
+	if !bytes.Equal(sum, data[:4]) {
+		return nil, fmt.Errorf("invalid printable wrapper %s", code)
+	}
+	var wrapper types.PrintableWrapper
+	err := proto.Unmarshal(data[4:], &wrapper)
+	if err != nil {
+		return nil, err
+	}
+	return &wrapper, nil
+}
+
+func encodePrintableWrapper(wrapper *types.PrintableWrapper) (string, error) {
+	data, err := proto.Marshal(wrapper)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, crc32.ChecksumIEEE(data))
+	buf = append(buf, data...)
+	return base58.Encode(buf), nil
+}
+
+func publicAddressFromProto(address *types.PublicAddress) *account.PublicAddress {
+	return &account.PublicAddress{
+		ViewPublicKey:   hex.EncodeToString(address.GetViewPublicKey().GetData()),
+		SpendPublicKey:  hex.EncodeToString(address.GetSpendPublicKey().GetData()),
+		FogReportUrl:    address.GetFogReportUrl(),
+		FogReportId:     address.GetFogReportId(),
+		FogAuthoritySig: hex.EncodeToString(address.GetFogAuthoritySig()),
+	}
+}
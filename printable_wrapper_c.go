@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo darwin LDFLAGS: ${SRCDIR}/include/libmobilecoin.a -framework Security -framework Foundation
+// #cgo linux LDFLAGS: ${SRCDIR}/include/libmobilecoin_linux.a -lm -ldl
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <errno.h>
+// #include "libmobilecoin.h"
+import "C"
+
+func MCPrintableWrapperB58Encode(wrapper *types.PrintableWrapper) (string, error) {
+	data, err := proto.Marshal(wrapper)
+	if err != nil {
+		return "", err
+	}
+	printable_wrapper_proto_bytes, free_printable_wrapper_proto_bytes := newBufferC(data)
+	defer free_printable_wrapper_proto_bytes()
+
+	c_code, err := C.mc_printable_wrapper_b58_encode(printable_wrapper_proto_bytes)
+	if err != nil {
+		return "", err
+	}
+	if c_code == nil {
+		return "", errors.New("mc_printable_wrapper_b58_encode failure")
+	}
+	defer C.mc_string_free(c_code)
+	return C.GoString(c_code), nil
+}
+
+// MCPrintableWrapperB58Decode, the first call with a nil buffer gets the size
+func MCPrintableWrapperB58Decode(code string) (*types.PrintableWrapper, error) {
+	b58_encoded_string := C.CString(code)
+	defer C.free(unsafe.Pointer(b58_encoded_string))
+
+	var out_error *C.McError
+	var out_size *C.McMutableBuffer
+	wrapper_size := C.mc_printable_wrapper_b58_decode(b58_encoded_string, out_size, &out_error)
+	if wrapper_size < 0 {
+		return nil, mcError("mc_printable_wrapper_b58_decode", out_error)
+	}
+
+	out_printable_wrapper_proto_bytes, free_out_printable_wrapper_proto_bytes := newMutableBufferC(int(wrapper_size))
+	defer free_out_printable_wrapper_proto_bytes()
+	wrapper_size = C.mc_printable_wrapper_b58_decode(b58_encoded_string, out_printable_wrapper_proto_bytes, &out_error)
+	if wrapper_size < 0 {
+		return nil, mcError("mc_printable_wrapper_b58_decode", out_error)
+	}
+
+	var wrapper types.PrintableWrapper
+	data := C.GoBytes(unsafe.Pointer(out_printable_wrapper_proto_bytes.buffer), C.int(wrapper_size))
+	err := proto.Unmarshal(data, &wrapper)
+	if err != nil {
+		return nil, err
+	}
+	return &wrapper, nil
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPrintableWrapper(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	address := acc.PublicAddress(0)
+
+	b58, err := acc.B58Code(0)
+	assert.Nil(err)
+	w, err := DecodePrintableWrapper(b58)
+	assert.Nil(err)
+	assert.Equal(address, w.PublicAddress)
+	code, err := w.B58Code()
+	assert.Nil(err)
+	assert.Equal(b58, code)
+
+	request := &PrintableWrapper{PaymentRequest: &PaymentRequest{Address: address, Value: 1000000000, Memo: "invoice 42", TokenID: 1}}
+	code, err = request.B58Code()
+	assert.Nil(err)
+	w, err = DecodePrintableWrapper(code)
+	assert.Nil(err)
+	assert.Equal(request, w)
+
+	payment, err := decodeRecipient(code, 0, 1)
+	assert.Nil(err)
+	assert.Equal(address, payment.Address)
+	assert.Equal(uint64(1000000000), payment.Value)
+	assert.Equal("invoice 42", payment.Memo)
+	_, err = decodeRecipient(code, 1000000000, 1)
+	assert.Nil(err)
+	_, err = decodeRecipient(code, 2000000000, 1)
+	assert.NotNil(err)
+	_, err = decodeRecipient(code, 0, 0)
+	assert.NotNil(err)
+	payment, err = decodeRecipient(b58, 5, 0)
+	assert.Nil(err)
+	assert.Equal(address, payment.Address)
+	assert.Equal(uint64(5), payment.Value)
+	assert.Equal("", payment.Memo)
+	_, err = decodeRecipient(b58, 0, 0)
+	assert.ErrorContains(err, "zero amount")
+	open := &PrintableWrapper{PaymentRequest: &PaymentRequest{Address: address, TokenID: 1}}
+	code, err = open.B58Code()
+	assert.Nil(err)
+	payment, err = decodeRecipient(code, 7, 1)
+	assert.Nil(err)
+	assert.Equal(uint64(7), payment.Value)
+	_, err = decodeRecipient(code, 0, 1)
+	assert.ErrorContains(err, "zero amount")
+
+	payload := &PrintableWrapper{TransferPayload: &TransferPayload{
+		Bip39Entropy:   "000102030405060708090a0b0c0d0e0f",
+		TxOutPublicKey: address.ViewPublicKey,
+		Memo:           "happy birthday",
+	}}
+	code, err = payload.B58Code()
+	assert.Nil(err)
+	w, err = DecodePrintableWrapper(code)
+	assert.Nil(err)
+	assert.Equal(payload, w)
+	_, err = decodeRecipient(code, 5, 0)
+	assert.NotNil(err)
+
+	_, err = (&PrintableWrapper{}).B58Code()
+	assert.NotNil(err)
+	_, err = (&PrintableWrapper{PublicAddress: address, PaymentRequest: request.PaymentRequest}).B58Code()
+	assert.NotNil(err)
+	_, err = DecodePrintableWrapper(b58[:len(b58)-1])
+	assert.NotNil(err)
+}
+
+func TestPrintableWrapperC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	request := &PrintableWrapper{PaymentRequest: &PaymentRequest{Address: acc.PublicAddress(0), Value: 1000000000, Memo: "invoice 42"}}
+	code, err := request.B58Code()
+	assert.Nil(err)
+
+	wrapper, err := MCPrintableWrapperB58Decode(code)
+	assert.Nil(err)
+	expected, err := decodePrintableWrapper(code)
+	assert.Nil(err)
+	assert.True(proto.Equal(expected, wrapper))
+	c, err := MCPrintableWrapperB58Encode(wrapper)
+	assert.Nil(err)
+	assert.Equal(code, c)
+}
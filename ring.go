@@ -26,21 +26,16 @@ type InputC struct {
 
 // mc_transaction_builder_ring_add_element
 func BuildRingElements(utxos []*UTXO, proofs *Proofs) ([]*InputC, error) {
+	if proofs == nil {
+		return nil, fmt.Errorf("Invalid proofs nil")
+	}
 	if len(proofs.Ring) == 0 || len(proofs.Ring) != len(proofs.Rings) {
-		return nil, fmt.Errorf("Invalid proofs ring len %d, rings len %d", len(proofs.Rings), len(proofs.Ring))
+		return nil, fmt.Errorf("Invalid proofs ring len %d, rings len %d", len(proofs.Ring), len(proofs.Rings))
 	}
 
 	inputSet := make(map[string]*UTXO)
 	for _, utxo := range utxos {
-		if len(utxo.ScriptPubKey) < 8 {
-			return nil, fmt.Errorf("MobileCoin invalid script pub key %s", utxo.ScriptPubKey)
-		}
-		data, err := hex.DecodeString(utxo.ScriptPubKey)
-		if err != nil {
-			return nil, err
-		}
-		var txOut TxOut
-		err = json.Unmarshal(data, &txOut)
+		txOut, err := utxoTxOut(utxo)
 		if err != nil {
 			return nil, err
 		}
@@ -75,15 +70,21 @@ func BuildRingElements(utxos []*UTXO, proofs *Proofs) ([]*InputC, error) {
 		if inputSet[itemi.TxOut.PublicKey] == nil {
 			return nil, fmt.Errorf("UTXO did not find")
 		}
-		utxo := inputSet[itemi.TxOut.PublicKey]
-		acc, err := account.NewAccountKey(utxo.PrivateKey[:64], utxo.PrivateKey[64:])
+		owner, err := inputSet[itemi.TxOut.PublicKey].SpendAccount()
+		if err != nil {
+			return nil, err
+		}
+		if owner.SpendPrivateKey == "" {
+			return nil, fmt.Errorf("spend key of %s held by remote signer %s, build with TransactionBuilderBuildWithSigner", itemi.TxOut.PublicKey, owner.RemoteSigner)
+		}
+		acc, err := owner.accountKey()
 		if err != nil {
 			return nil, err
 		}
 		inputCs = append(inputCs, &InputC{
-			ViewPrivate:            account.HexToScalar(utxo.PrivateKey[:64]),
-			SpendPrivate:           account.HexToScalar(utxo.PrivateKey[64:]),
-			SubAddressSpendPrivate: acc.SubaddressSpendPrivateKey(0),
+			ViewPrivate:            acc.ViewPrivateKey,
+			SpendPrivate:           acc.SpendPrivateKey,
+			SubAddressSpendPrivate: acc.SubaddressSpendPrivateKey(owner.SubaddressIndex),
 			RealIndex:              index,
 			TxOutWithProofCs:       txOutWithProofCs,
 		})
@@ -91,6 +92,23 @@ func BuildRingElements(utxos []*UTXO, proofs *Proofs) ([]*InputC, error) {
 	return inputCs, nil
 }
 
+// The script pub key of a MobileCoin UTXO is the hex of its TxOut JSON
+func utxoTxOut(utxo *UTXO) (*TxOut, error) {
+	if len(utxo.ScriptPubKey) < 8 {
+		return nil, fmt.Errorf("MobileCoin invalid script pub key %s", utxo.ScriptPubKey)
+	}
+	data, err := hex.DecodeString(utxo.ScriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+	var txOut TxOut
+	err = json.Unmarshal(data, &txOut)
+	if err != nil {
+		return nil, err
+	}
+	return &txOut, nil
+}
+
 func MarshalTxOut(input *TxOut) *types.TxOut {
 	out := &types.TxOut{
 		TargetKey: &types.CompressedRistretto{
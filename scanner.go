@@ -0,0 +1,207 @@
+package api
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/dchest/blake2b"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	CHANGE_SUBADDRESS_INDEX = math.MaxUint64 - 1
+)
+
+// Scanner finds the TxOuts received by the subaddresses of a view key only
+// account. The spend public keys of the subaddresses 0..N, change and gift
+// code subaddresses are computed once, a TxOut belongs to the account if the
+// spend public key recovered from it is in the table.
+type Scanner struct {
+	viewPrivate    *ristretto.Scalar
+	viewPrivateStr string
+	subaddresses   map[[32]byte]uint64
+}
+
+type ScanMatch struct {
+	Index           int
+	TxOut           *TxOut
+	SubaddressIndex uint64
+	Value           uint64
+	TokenID         uint64
+	MemoPayload     []byte
+}
+
+// NewScanner with the hex view private key and the hex spend public key of
+// the account, subaddresses is the number of the default subaddresses.
+func NewScanner(viewPrivateStr, spendPublicStr string, subaddresses uint64) (*Scanner, error) {
+	viewPrivate, err := decodeScalar(viewPrivateStr)
+	if err != nil {
+		return nil, err
+	}
+	spendPublic, err := decodePoint(spendPublicStr)
+	if err != nil {
+		return nil, err
+	}
+	scanner := &Scanner{
+		viewPrivate:    viewPrivate,
+		viewPrivateStr: hex.EncodeToString(viewPrivate.Bytes()),
+		subaddresses:   make(map[[32]byte]uint64),
+	}
+	indices := []uint64{CHANGE_SUBADDRESS_INDEX, GIFT_CODE_SUBADDRESS_INDEX}
+	for i := uint64(0); i < subaddresses; i++ {
+		indices = append(indices, i)
+	}
+	for _, i := range indices {
+		var key [32]byte
+		copy(key[:], subaddressSpendPublicKey(viewPrivate, spendPublic, i).Bytes())
+		scanner.subaddresses[key] = i
+	}
+	return scanner, nil
+}
+
+// Scan checks the txOuts in parallel, the matches are in the order of txOuts
+// and Index is the position in txOuts.
+func (s *Scanner) Scan(txOuts []*TxOut) ([]*ScanMatch, error) {
+	matches := make([]*ScanMatch, len(txOuts))
+	errs := make([]error, len(txOuts))
+
+	workers := runtime.NumCPU()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(txOuts); i += workers {
+				matches[i], errs[i] = s.scan(i, txOuts[i])
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var found []*ScanMatch
+	for i, m := range matches {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("txout %d: %v", i, errs[i])
+		}
+		if m != nil {
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
+
+func (s *Scanner) scan(i int, txOut *TxOut) (*ScanMatch, error) {
+	if txOut.Amount == nil {
+		return nil, fmt.Errorf("invalid txout amount %s", txOut.PublicKey)
+	}
+	spendPublic, err := RecoverPublicSubaddressSpendKey(s.viewPrivateStr, txOut.TargetKey, txOut.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], spendPublic.Bytes())
+	index, found := s.subaddresses[key]
+	if !found {
+		return nil, nil
+	}
+
+	value, err := GetValue(txOut, s.viewPrivateStr)
+	if err != nil {
+		return nil, err
+	}
+	tokenID, err := getTokenID(txOut, s.viewPrivate)
+	if err != nil {
+		return nil, err
+	}
+	match := &ScanMatch{
+		Index:           i,
+		TxOut:           txOut,
+		SubaddressIndex: index,
+		Value:           value,
+		TokenID:         tokenID,
+	}
+	if txOut.EMemo != "" {
+		match.MemoPayload, err = DecryptMemo(txOut.EMemo, txOut.PublicKey, s.viewPrivateStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return match, nil
+}
+
+// subaddressSpendPublicKey is Hs(a || i) * G + B
+func subaddressSpendPublicKey(viewPrivate *ristretto.Scalar, spendPublic *ristretto.Point, index uint64) *ristretto.Point {
+	var buf [32]byte
+	binary.LittleEndian.PutUint64(buf[:], index)
+	var address ristretto.Scalar
+	hash := blake2b.New512()
+	hash.Write([]byte(account.SUBADDRESS_DOMAIN_TAG))
+	hash.Write(viewPrivate.Bytes())
+	hash.Write(address.SetBytes(&buf).Bytes())
+
+	var hs ristretto.Scalar
+	var key [64]byte
+	copy(key[:], hash.Sum(nil))
+
+	var p, r ristretto.Point
+	return r.Add(p.ScalarMultBase(hs.SetReduced(&key)), spendPublic)
+}
+
+// getTokenID unmasks the token id, an empty masked token id is token 0
+func getTokenID(output *TxOut, viewPrivate *ristretto.Scalar) (uint64, error) {
+	masked, err := hex.DecodeString(output.Amount.MaskedTokenID)
+	if err != nil {
+		return 0, err
+	}
+	if len(masked) == 0 {
+		return 0, nil
+	}
+	if len(masked) != 8 {
+		return 0, fmt.Errorf("invalid masked token id %s", output.Amount.MaskedTokenID)
+	}
+
+	publicKey, err := decodePoint(output.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	secret := createSharedSecret(publicKey, viewPrivate)
+	var mask uint64
+	if output.Amount.Version == 2 {
+		mask, err = getTokenIDMaskV2(ComputeAmountSharedSecretV2(secret))
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		mask = getTokenIDMask(secret)
+	}
+	return binary.LittleEndian.Uint64(masked) ^ mask, nil
+}
+
+func getTokenIDMask(secret *ristretto.Point) uint64 {
+	hash := blake2b.New512()
+	hash.Write([]byte(AMOUNT_TOKEN_ID_DOMAIN_TAG))
+	hash.Write(secret.Bytes())
+
+	var hs ristretto.Scalar
+	var key [64]byte
+	copy(key[:], hash.Sum(nil))
+	return binary.LittleEndian.Uint64(hs.SetReduced(&key).Bytes()[:8])
+}
+
+func getTokenIDMaskV2(secret []byte) (uint64, error) {
+	mask := make([]byte, 8)
+	kdf := hkdf.New(sha512.New, secret, []byte(AMOUNT_BLINDING_FACTORS_DOMAIN_TAG), []byte(AMOUNT_TOKEN_ID_DOMAIN_TAG))
+	_, err := io.ReadFull(kdf, mask)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(mask), nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/dchest/blake2b"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanner(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	spendPublic := account.PublicKey(&spend)
+	for _, i := range []uint64{0, 3, CHANGE_SUBADDRESS_INDEX, GIFT_CODE_SUBADDRESS_INDEX} {
+		address := acc.PublicAddress(i)
+		assert.Equal(address.SpendPublicKey, hex.EncodeToString(subaddressSpendPublicKey(&view, spendPublic, i).Bytes()))
+	}
+
+	scanner, err := NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(spendPublic.Bytes()), 10)
+	assert.Nil(err)
+
+	payload := joinMemoPayload(MEMO_TYPE_UNUSED, make([]byte, MEMO_DATA_LENGTH))
+	out := newScannerTestTxOut(assert, acc.PublicAddress(3), 12345, 1, payload)
+	var other ristretto.Scalar
+	other.Rand()
+	foreign := newScannerTestTxOut(assert, (&account.Account{ViewPrivateKey: &other, SpendPrivateKey: &spend}).PublicAddress(0), 1, 0, nil)
+
+	matches, err := scanner.Scan([]*TxOut{foreign, out, foreign})
+	assert.Nil(err)
+	assert.Len(matches, 1)
+	assert.Equal(1, matches[0].Index)
+	assert.Equal(uint64(3), matches[0].SubaddressIndex)
+	assert.Equal(uint64(12345), matches[0].Value)
+	assert.Equal(uint64(1), matches[0].TokenID)
+	assert.Equal(payload, matches[0].MemoPayload)
+
+	scanner, err = NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(spendPublic.Bytes()), 3)
+	assert.Nil(err)
+	matches, err = scanner.Scan([]*TxOut{out})
+	assert.Nil(err)
+	assert.Len(matches, 0)
+
+	for _, invalid := range []*TxOut{
+		{Amount: out.Amount, TargetKey: out.TargetKey, PublicKey: "zz"},
+		{Amount: out.Amount, TargetKey: "zz", PublicKey: out.PublicKey},
+		{Amount: out.Amount, TargetKey: out.TargetKey, PublicKey: hex.EncodeToString(bytes.Repeat([]byte{0xff}, 32))},
+		{Amount: out.Amount, TargetKey: out.TargetKey[:62], PublicKey: out.PublicKey},
+	} {
+		_, err = scanner.Scan([]*TxOut{out, invalid})
+		assert.ErrorContains(err, "txout 1: ")
+	}
+}
+
+func newScannerTestTxOut(assert *assert.Assertions, address *account.PublicAddress, value, tokenID uint64, payload []byte) *TxOut {
+	var r ristretto.Scalar
+	r.Rand()
+	spendPublic := account.HexToPoint(address.SpendPublicKey)
+	viewPublic := account.HexToPoint(address.ViewPublicKey)
+	var R ristretto.Point
+	R.ScalarMult(spendPublic, &r)
+	secret := createSharedSecret(viewPublic, &r)
+
+	hash := blake2b.New512()
+	hash.Write([]byte(HASH_TO_SCALAR_DOMAIN_TAG))
+	hash.Write(secret.Bytes())
+	var key [64]byte
+	copy(key[:], hash.Sum(nil))
+	var hs ristretto.Scalar
+	var target, g ristretto.Point
+	target.Add(g.ScalarMultBase(hs.SetReduced(&key)), spendPublic)
+
+	amountSecret := ComputeAmountSharedSecretV2(secret)
+	valueMask, err := GetBlindingFactorsV2(amountSecret)
+	assert.Nil(err)
+	tokenIDMask, err := getTokenIDMaskV2(amountSecret)
+	assert.Nil(err)
+	maskedTokenID := binary.LittleEndian.AppendUint64(nil, tokenID^tokenIDMask)
+	blinding, err := GetBlindingV2(amountSecret)
+	assert.Nil(err)
+	commitment := NewPedersenGensForToken(tokenID).Commit(uint64ToScalar(value), blinding)
+
+	out := &TxOut{
+		Amount: &Amount{
+			Commitment:    hex.EncodeToString(commitment.Bytes()),
+			MaskedValue:   MaskedValue(value ^ valueMask),
+			MaskedTokenID: hex.EncodeToString(maskedTokenID),
+			Version:       2,
+		},
+		TargetKey: hex.EncodeToString(target.Bytes()),
+		PublicKey: hex.EncodeToString(R.Bytes()),
+	}
+	if payload != nil {
+		memo, err := EncryptMemo(hex.EncodeToString(payload), address.ViewPublicKey, hex.EncodeToString(r.Bytes()))
+		assert.Nil(err)
+		out.EMemo = hex.EncodeToString(memo)
+	}
+	return out
+}
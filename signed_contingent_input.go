@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrPartialFillNotSupported rejects the offers with partial fill rules, which
+// could only be filled with a fraction of the offered amount.
+var ErrPartialFillNotSupported = errors.New("partial fill signed contingent input is not supported")
+
+// SCIRequiredOutlay is an output the filler of a signed contingent input must
+// pay, an empty Address is the change back to the owner of the input.
+type SCIRequiredOutlay struct {
+	Address string
+	Amount  uint64
+	TokenID uint
+}
+
+type SCIAmount struct {
+	Value   uint64
+	TokenID uint64
+}
+
+// SCIOffer is the summary of a signed contingent input, the Offered amount is
+// released to the filler who pays all the Required amounts.
+type SCIOffer struct {
+	BlockVersion      uint32
+	KeyImage          string
+	Offered           *SCIAmount
+	Required          []*SCIAmount
+	MaxTombstoneBlock uint64
+	GlobalIndices     []uint64
+}
+
+type SCIOutput struct {
+	SignedContingentInput string
+	Offer                 *SCIOffer
+	RequiredOutputs       []*OutlayOutput
+}
+
+// BuildSignedContingentInput signs the utxo as an offer which could only be
+// spent in a transaction with all the required outlays.
+func BuildSignedContingentInput(ctx context.Context, utxo *UTXO, proofs *Proofs, required []*SCIRequiredOutlay, tombstone uint64, version uint, trust *FogTrustConfig, fetcher FogReportFetcher) (*SCIOutput, error) {
+	if len(required) == 0 {
+		return nil, errors.New("empty required outlays")
+	}
+	inputCs, err := BuildRingElements([]*UTXO{utxo}, proofs)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputCs) != 1 {
+		return nil, fmt.Errorf("invalid proofs ring len %d", len(inputCs))
+	}
+
+	var fogReportUrls []string
+	outlayCs := make([]*MixedOutlayC, len(required))
+	for i, outlay := range required {
+		outlayCs[i] = &MixedOutlayC{Amount: outlay.Amount, TokenID: uint64(outlay.TokenID)}
+		if outlay.Address == "" {
+			continue
+		}
+		recipient, err := account.DecodeB58Code(outlay.Address)
+		if err != nil {
+			return nil, err
+		}
+		outlayCs[i].Recipient = recipient
+		if recipient.FogReportUrl != "" {
+			fogReportUrls = append(fogReportUrls, recipient.FogReportUrl)
+		}
+	}
+	var verifier *FogVerifier
+	if len(fogReportUrls) > 0 {
+		verifier, err = trust.Verifier(fogReportUrls)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sciC, err := MCSignedContingentInputBuilderCreateC(ctx, inputCs[0], outlayCs, tombstone, version, verifier, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	_, offer, err := decodeSignedContingentInput(sciC.SCI)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*OutlayOutput, len(required))
+	for i, out := range sciC.RequiredOutputs {
+		outputs[i] = &OutlayOutput{
+			Address:            required[i].Address,
+			Amount:             required[i].Amount,
+			TokenID:            required[i].TokenID,
+			OutputHash:         hex.EncodeToString(out.TxOut.PublicKey.GetData()),
+			ConfirmationNumber: hex.EncodeToString(out.Confirmation),
+		}
+	}
+	return &SCIOutput{
+		SignedContingentInput: hex.EncodeToString(sciC.SCI),
+		Offer:                 offer,
+		RequiredOutputs:       outputs,
+	}, nil
+}
+
+// ValidateSignedContingentInput checks the offer sent by the other side, the
+// ring membership is checked by the consensus when it's filled.
+func ValidateSignedContingentInput(sciHex string) (*SCIOffer, error) {
+	data, err := hex.DecodeString(sciHex)
+	if err != nil {
+		return nil, err
+	}
+	_, offer, err := decodeSignedContingentInput(data)
+	if err != nil {
+		return nil, err
+	}
+	err = MCSignedContingentInputIsValidC(data)
+	if err != nil {
+		return nil, err
+	}
+	return offer, nil
+}
+
+// FillSignedContingentInput spends the offer together with the inputs of
+// tokenID, which pay the required amounts of tokenID and the fee. The
+// sciProofs are the membership proofs of the offer ring, in the order of its
+// global indices. The offered amount, net of its required amounts, and the
+// change of tokenID are sent to changeStr, the change of tokenID is the last
+// of the Outlays at ChangeIndex. The offers with partial fill rules are
+// rejected with ErrPartialFillNotSupported.
+func FillSignedContingentInput(ctx context.Context, sciHex string, sciProofs []*TxOutMembershipProof, inputs []*UTXO, proofs *Proofs, fee, tombstone uint64, tokenID, version uint, changeStr string, trust *FogTrustConfig, fetcher FogReportFetcher) (*Output, error) {
+	data, err := hex.DecodeString(sciHex)
+	if err != nil {
+		return nil, err
+	}
+	sci, offer, err := decodeSignedContingentInput(data)
+	if err != nil {
+		return nil, err
+	}
+	if offer.MaxTombstoneBlock > 0 && tombstone > offer.MaxTombstoneBlock {
+		return nil, fmt.Errorf("tombstone %d exceeds the offer max tombstone %d", tombstone, offer.MaxTombstoneBlock)
+	}
+	if len(sciProofs) != len(offer.GlobalIndices) {
+		return nil, fmt.Errorf("invalid offer proofs len %d, ring len %d", len(sciProofs), len(offer.GlobalIndices))
+	}
+	sci.TxIn.Proofs = make([]*types.TxOutMembershipProof, len(sciProofs))
+	for i, proof := range sciProofs {
+		if proof.Index != strconv.FormatUint(offer.GlobalIndices[i], 10) {
+			return nil, fmt.Errorf("invalid offer proof index %s, global index %d", proof.Index, offer.GlobalIndices[i])
+		}
+		sci.TxIn.Proofs[i] = MarshalTxOutMembershipProof(proof)
+	}
+	data, err = proto.Marshal(sci)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := account.DecodeB58Code(changeStr)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := signedContingentInputBalances(offer, inputs, fee, uint64(tokenID))
+	if err != nil {
+		return nil, err
+	}
+	tokens := signedContingentInputTokens(balances, uint64(tokenID))
+	outlayCs := make([]*MixedOutlayC, len(tokens))
+	for i, token := range tokens {
+		outlayCs[i] = &MixedOutlayC{Amount: balances[token], TokenID: token, Recipient: change}
+	}
+
+	var inputCs []*InputC
+	if len(inputs) > 0 {
+		inputCs, err = BuildRingElements(inputs, proofs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var verifier *FogVerifier
+	if change.FogReportUrl != "" {
+		verifier, err = trust.Verifier([]string{change.FogReportUrl})
+		if err != nil {
+			return nil, err
+		}
+	}
+	txC, err := MCTransactionBuilderAddPresignedInputC(ctx, data, inputCs, outlayCs, fee, tombstone, tokenID, version, verifier, fetcher)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &Output{
+		RawTransaction: hex.EncodeToString(txC.Tx),
+		Fee:            fee,
+		OutputIndex:    0,
+		ChangeIndex:    -1,
+		ChangeAmount:   balances[uint64(tokenID)],
+	}
+	for i, out := range txC.Outputs {
+		if outlayCs[i].TokenID == uint64(tokenID) {
+			output.ChangeIndex = int64(i)
+			output.ChangeHash = hex.EncodeToString(out.TxOut.PublicKey.GetData())
+		}
+		output.Outlays = append(output.Outlays, &OutlayOutput{
+			Address:            changeStr,
+			Amount:             outlayCs[i].Amount,
+			TokenID:            uint(outlayCs[i].TokenID),
+			OutputHash:         hex.EncodeToString(out.TxOut.PublicKey.GetData()),
+			SharedSecret:       hex.EncodeToString(out.SharedSecret),
+			ConfirmationNumber: hex.EncodeToString(out.Confirmation),
+		})
+	}
+	if len(output.Outlays) > 0 {
+		output.TransactionHash = output.Outlays[0].OutputHash
+		output.OutputHash = output.Outlays[0].OutputHash
+		output.SharedSecret = output.Outlays[0].SharedSecret
+	}
+	return output, nil
+}
+
+// signedContingentInputTokens orders the tokens with a balance left, the
+// other tokens by id and then tokenID, whose balance is the change.
+func signedContingentInputTokens(balances map[uint64]uint64, tokenID uint64) []uint64 {
+	var tokens []uint64
+	for token, balance := range balances {
+		if balance > 0 && token != tokenID {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.Sort(tokens)
+	if balances[tokenID] > 0 {
+		tokens = append(tokens, tokenID)
+	}
+	return tokens
+}
+
+// signedContingentInputBalances is the amount left of each token after the
+// offer and the inputs pay the required amounts and the fee
+func signedContingentInputBalances(offer *SCIOffer, inputs []*UTXO, fee, tokenID uint64) (map[uint64]uint64, error) {
+	credits := map[uint64]uint64{offer.Offered.TokenID: offer.Offered.Value}
+	for _, input := range inputs {
+		credits[tokenID] += input.Amount
+	}
+	debits := map[uint64]uint64{tokenID: fee}
+	for _, required := range offer.Required {
+		debits[required.TokenID] += required.Value
+	}
+
+	balances := make(map[uint64]uint64)
+	for token, debit := range debits {
+		if credits[token] < debit {
+			return nil, fmt.Errorf("%w: token %d %d, required %d", ErrInsufficientFunds, token, credits[token], debit)
+		}
+	}
+	for token, credit := range credits {
+		balances[token] = credit - debits[token]
+	}
+	return balances, nil
+}
+
+// decodeSignedContingentInput checks the structure of the sci, partial fills
+// are rejected with ErrPartialFillNotSupported.
+func decodeSignedContingentInput(data []byte) (*types.SignedContingentInput, *SCIOffer, error) {
+	sci := &types.SignedContingentInput{}
+	err := proto.Unmarshal(data, sci)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sci.TxIn == nil || sci.TxIn.InputRules == nil {
+		return nil, nil, errors.New("signed contingent input without input rules")
+	}
+	if sci.Mlsag == nil || sci.PseudoOutputAmount == nil {
+		return nil, nil, errors.New("signed contingent input without signature")
+	}
+	rules := sci.TxIn.InputRules
+	if len(rules.PartialFillOutputs) > 0 || rules.PartialFillChange != nil {
+		return nil, nil, ErrPartialFillNotSupported
+	}
+	if len(sci.TxIn.Ring) != RING_SIZE || len(sci.TxOutGlobalIndices) != len(sci.TxIn.Ring) {
+		return nil, nil, fmt.Errorf("invalid ring len %d, global indices len %d", len(sci.TxIn.Ring), len(sci.TxOutGlobalIndices))
+	}
+	if len(rules.RequiredOutputs) == 0 || len(rules.RequiredOutputs) != len(sci.RequiredOutputAmounts) {
+		return nil, nil, fmt.Errorf("invalid required outputs len %d, amounts len %d", len(rules.RequiredOutputs), len(sci.RequiredOutputAmounts))
+	}
+
+	offer := &SCIOffer{
+		BlockVersion:      sci.BlockVersion,
+		KeyImage:          hex.EncodeToString(sci.Mlsag.KeyImage.GetData()),
+		Offered:           &SCIAmount{Value: sci.PseudoOutputAmount.Value, TokenID: sci.PseudoOutputAmount.TokenId},
+		MaxTombstoneBlock: rules.MaxTombstoneBlock,
+		GlobalIndices:     slices.Clone(sci.TxOutGlobalIndices),
+	}
+	for _, amount := range sci.RequiredOutputAmounts {
+		offer.Required = append(offer.Required, &SCIAmount{Value: amount.Value, TokenID: amount.TokenId})
+	}
+	return sci, offer, nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"errors"
+)
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo darwin LDFLAGS: ${SRCDIR}/include/libmobilecoin.a -framework Security -framework Foundation
+// #cgo linux LDFLAGS: ${SRCDIR}/include/libmobilecoin_linux.a -lm -ldl
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <errno.h>
+// #include "libmobilecoin.h"
+// #include "signed_contingent_input.h"
+import "C"
+
+type SignedContingentInputC struct {
+	SCI             []byte
+	RequiredOutputs []*TxOutC
+}
+
+// mc_signed_contingent_input_builder_create, the input is signed on the
+// condition that all the required outputs appear in the transaction which
+// spends it.
+func MCSignedContingentInputBuilderCreateC(ctx context.Context, input *InputC, required []*MixedOutlayC, tombstone uint64, version uint, verifier *FogVerifier, fetcher FogReportFetcher) (*SignedContingentInputC, error) {
+	if len(required) == 0 {
+		return nil, errors.New("no required outputs")
+	}
+
+	var fog_resolver *C.McFogResolver
+	recipients := mixedOutlayRecipients(required)
+	if len(outlaysFogReportUrls(recipients)) > 0 {
+		resolver, err := newFogResolverWithReportsC(ctx, recipients, verifier, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		defer resolver.free()
+		fog_resolver = resolver.resolver
+	}
+
+	account_key, free_account_key := newAccountKeyC(input.ViewPrivate, input.SpendPrivate)
+	defer free_account_key()
+
+	memo_builder, err := C.mc_memo_builder_default_create()
+	if err != nil {
+		return nil, err
+	}
+	defer C.mc_memo_builder_free(memo_builder)
+
+	view_private_key, free_view_private_key := newBufferC(input.ViewPrivate.Bytes())
+	defer free_view_private_key()
+	subaddress_spend_private_key, free_subaddress_spend_private_key := newBufferC(input.SubAddressSpendPrivate.Bytes())
+	defer free_subaddress_spend_private_key()
+
+	ring, err := newRingC(input.TxOutWithProofCs)
+	if err != nil {
+		return nil, err
+	}
+	defer C.mc_transaction_builder_ring_free(ring)
+
+	var build_error *C.McError
+	sci_builder, err := C.mc_signed_contingent_input_builder_create(C.uint32_t(version), C.uint64_t(tombstone), fog_resolver, memo_builder, view_private_key, subaddress_spend_private_key, C.size_t(input.RealIndex), ring, &build_error)
+	if err != nil {
+		return nil, err
+	}
+	if sci_builder == nil {
+		return nil, mcError("mc_signed_contingent_input_builder_create", build_error)
+	}
+	defer C.mc_signed_contingent_input_builder_free(sci_builder)
+
+	outputs := make([]*TxOutC, len(required))
+	for i, outlay := range required {
+		out_tx_out_confirmation_number, free_confirmation_number := newMutableBufferC(32)
+		defer free_confirmation_number()
+
+		var rng_callback *C.McRngCallback
+		var out_error *C.McError
+		var mcDataOut *C.McData
+		if outlay.Recipient == nil {
+			mcDataOut, err = C.mc_signed_contingent_input_builder_add_required_change_output(account_key, sci_builder, C.uint64_t(outlay.Amount), C.uint64_t(outlay.TokenID), rng_callback, out_tx_out_confirmation_number, &out_error)
+		} else {
+			recipient_address, free_recipient_address := newPublicAddressC(outlay.Recipient)
+			defer free_recipient_address()
+			mcDataOut, err = C.mc_signed_contingent_input_builder_add_required_output(sci_builder, C.uint64_t(outlay.Amount), C.uint64_t(outlay.TokenID), recipient_address, rng_callback, out_tx_out_confirmation_number, &out_error)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if mcDataOut == nil {
+			return nil, mcError("mc_signed_contingent_input_builder_add_required_output", out_error)
+		}
+		defer C.mc_data_free(mcDataOut)
+
+		txOut, err := txOutFromMcData(mcDataOut)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = &TxOutC{
+			TxOut:        txOut,
+			Confirmation: mutableBufferBytes(out_tx_out_confirmation_number),
+		}
+	}
+
+	var rng_callback *C.McRngCallback
+	var out_error *C.McError
+	mcData, err := C.mc_signed_contingent_input_builder_build(sci_builder, rng_callback, ring, &out_error)
+	if err != nil {
+		return nil, err
+	}
+	if mcData == nil {
+		return nil, mcError("mc_signed_contingent_input_builder_build", out_error)
+	}
+	defer C.mc_data_free(mcData)
+
+	return &SignedContingentInputC{
+		SCI:             mcDataBytes(mcData),
+		RequiredOutputs: outputs,
+	}, nil
+}
+
+// mc_signed_contingent_input_data_is_valid checks the signature and the
+// amounts of the sci, but not the membership of its ring.
+func MCSignedContingentInputIsValidC(sci []byte) error {
+	sci_data, free_sci_data := newBufferC(sci)
+	defer free_sci_data()
+
+	var out_error *C.McError
+	b, err := C.mc_signed_contingent_input_data_is_valid(sci_data, &out_error)
+	if err != nil {
+		return err
+	}
+	if out_error != nil {
+		return mcError("mc_signed_contingent_input_data_is_valid", out_error)
+	}
+	if !b {
+		return errors.New("invalid signed contingent input")
+	}
+	return nil
+}
+
+// mc_transaction_builder_add_presigned_input, the sci must have the membership
+// proofs of its ring. The required outputs of the sci are added by the
+// builder, the outlays should balance every token of the inputs and the sci.
+func MCTransactionBuilderAddPresignedInputC(ctx context.Context, sci []byte, inputCs []*InputC, outlays []*MixedOutlayC, fee, tombstone uint64, feeTokenID, version uint, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	if len(sci) == 0 {
+		return nil, errors.New("empty signed contingent input")
+	}
+	return buildMixedC(ctx, inputCs, sci, outlays, fee, tombstone, feeTokenID, version, verifier, fetcher)
+}
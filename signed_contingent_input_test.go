@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecodeSignedContingentInput(t *testing.T) {
+	assert := assert.New(t)
+
+	sci := newTestSignedContingentInput()
+	data, err := proto.Marshal(sci)
+	assert.Nil(err)
+
+	_, offer, err := decodeSignedContingentInput(data)
+	assert.Nil(err)
+	assert.Equal(uint32(3), offer.BlockVersion)
+	assert.Equal("0102", offer.KeyImage)
+	assert.Equal(uint64(10*MILLIMOB_TO_PICOMOB), offer.Offered.Value)
+	assert.Len(offer.Required, 1)
+	assert.Equal(uint64(1), offer.Required[0].TokenID)
+	assert.Equal(uint64(1000), offer.MaxTombstoneBlock)
+
+	balances, err := signedContingentInputBalances(offer, []*UTXO{{Amount: 6000000}}, 2560, 1)
+	assert.Nil(err)
+	assert.Equal(uint64(10*MILLIMOB_TO_PICOMOB), balances[0])
+	assert.Equal(uint64(6000000-5000000-2560), balances[1])
+	_, err = signedContingentInputBalances(offer, []*UTXO{{Amount: 5000000}}, 2560, 1)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+
+	assert.Equal([]uint64{0, 1}, signedContingentInputTokens(balances, 1))
+	assert.Equal([]uint64{1, 0}, signedContingentInputTokens(balances, 0))
+	assert.Equal([]uint64{0}, signedContingentInputTokens(map[uint64]uint64{0: 1, 1: 0}, 1))
+	assert.Equal([]uint64{0, 1}, signedContingentInputTokens(map[uint64]uint64{0: 1, 1: 2, 2: 0}, 1))
+
+	sci.TxIn.InputRules.PartialFillChange = &types.RevealedTxOut{}
+	data, err = proto.Marshal(sci)
+	assert.Nil(err)
+	_, _, err = decodeSignedContingentInput(data)
+	assert.True(errors.Is(err, ErrPartialFillNotSupported))
+
+	sci.TxIn.InputRules.PartialFillChange = nil
+	sci.TxIn.InputRules.PartialFillOutputs = []*types.RevealedTxOut{{}}
+	data, err = proto.Marshal(sci)
+	assert.Nil(err)
+	_, err = FillSignedContingentInput(context.Background(), hex.EncodeToString(data), nil, nil, &Proofs{}, 2560, 1000, 1, 3, "", DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.True(errors.Is(err, ErrPartialFillNotSupported))
+
+	sci.TxIn.InputRules.PartialFillOutputs = nil
+	sci.TxOutGlobalIndices = sci.TxOutGlobalIndices[1:]
+	data, err = proto.Marshal(sci)
+	assert.Nil(err)
+	_, _, err = decodeSignedContingentInput(data)
+	assert.NotNil(err)
+}
+
+func TestFillSignedContingentInput(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	change, err := acc.B58Code(0)
+	assert.Nil(err)
+
+	data, err := proto.Marshal(newTestSignedContingentInput())
+	assert.Nil(err)
+	sciHex := hex.EncodeToString(data)
+	sciProofs := make([]*TxOutMembershipProof, RING_SIZE)
+	for i := range sciProofs {
+		sciProofs[i] = &TxOutMembershipProof{Index: fmt.Sprint(100 + i), HighestIndex: "200"}
+	}
+	inputs := []*UTXO{{Amount: 6000000}}
+
+	_, err = FillSignedContingentInput(context.Background(), sciHex, sciProofs, inputs, &Proofs{}, 2560, 1001, 1, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "tombstone 1001 exceeds the offer max tombstone 1000")
+
+	_, err = FillSignedContingentInput(context.Background(), sciHex, sciProofs[1:], inputs, &Proofs{}, 2560, 1000, 1, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, fmt.Sprintf("invalid offer proofs len %d, ring len %d", RING_SIZE-1, RING_SIZE))
+
+	sciProofs[0], sciProofs[1] = sciProofs[1], sciProofs[0]
+	_, err = FillSignedContingentInput(context.Background(), sciHex, sciProofs, inputs, &Proofs{}, 2560, 1000, 1, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "invalid offer proof index 101, global index 100")
+
+	sciProofs[0], sciProofs[1] = sciProofs[1], sciProofs[0]
+	_, err = FillSignedContingentInput(context.Background(), sciHex, sciProofs, []*UTXO{{Amount: 5000000}}, &Proofs{}, 2560, 1000, 1, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+}
+
+func TestBuildSignedContingentInputC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	address, err := recipient.B58Code(1)
+	require.Nil(err)
+
+	inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{10 * MILLIMOB_TO_PICOMOB}, 0)
+	required := []*SCIRequiredOutlay{
+		{Address: address, Amount: 5 * MINIMUM_EUSD, TokenID: 1},
+		{Amount: 2 * MILLIMOB_TO_PICOMOB, TokenID: 0},
+	}
+	output, err := BuildSignedContingentInput(context.Background(), inputs[0], proofs, required, 1000, 3, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	require.Equal(uint64(10*MILLIMOB_TO_PICOMOB), output.Offer.Offered.Value)
+	require.Equal(uint64(1000), output.Offer.MaxTombstoneBlock)
+	require.Len(output.Offer.Required, len(required))
+	offer, err := ValidateSignedContingentInput(output.SignedContingentInput)
+	require.Nil(err)
+	require.Equal(output.Offer, offer)
+
+	data, err := hex.DecodeString(output.SignedContingentInput)
+	require.Nil(err)
+	sci, _, err := decodeSignedContingentInput(data)
+	require.Nil(err)
+	requiredOutputs := make(map[string]*TxOut)
+	for _, out := range sci.TxIn.InputRules.RequiredOutputs {
+		txOut := UnmarshalTxOut(out)
+		requiredOutputs[txOut.PublicKey] = txOut
+	}
+
+	require.Len(output.RequiredOutputs, len(required))
+	for i, o := range output.RequiredOutputs {
+		require.Equal(required[i].Address, o.Address)
+		require.Equal(required[i].Amount, o.Amount)
+		require.Equal(required[i].TokenID, o.TokenID)
+		require.Len(o.ConfirmationNumber, 64)
+		require.NotNil(requiredOutputs[o.OutputHash])
+	}
+
+	scanner, err := NewScanner(hex.EncodeToString(recipientView.Bytes()), hex.EncodeToString(account.PublicKey(&recipientSpend).Bytes()), 2)
+	require.Nil(err)
+	matches, err := scanner.Scan([]*TxOut{requiredOutputs[output.RequiredOutputs[0].OutputHash]})
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(uint64(1), matches[0].SubaddressIndex)
+	require.Equal(uint64(5*MINIMUM_EUSD), matches[0].Value)
+	require.Equal(uint64(1), matches[0].TokenID)
+
+	scanner, err = NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(account.PublicKey(&spend).Bytes()), 1)
+	require.Nil(err)
+	matches, err = scanner.Scan([]*TxOut{requiredOutputs[output.RequiredOutputs[1].OutputHash]})
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(uint64(CHANGE_SUBADDRESS_INDEX), matches[0].SubaddressIndex)
+	require.Equal(uint64(2*MILLIMOB_TO_PICOMOB), matches[0].Value)
+	require.Equal(uint64(0), matches[0].TokenID)
+}
+
+func newTestSignedContingentInput() *types.SignedContingentInput {
+	sci := &types.SignedContingentInput{
+		BlockVersion: 3,
+		TxIn: &types.TxIn{
+			Ring: make([]*types.TxOut, RING_SIZE),
+			InputRules: &types.InputRules{
+				RequiredOutputs:   []*types.TxOut{{}},
+				MaxTombstoneBlock: 1000,
+			},
+		},
+		Mlsag:                 &types.RingMLSAG{KeyImage: &types.KeyImage{Data: []byte{1, 2}}},
+		PseudoOutputAmount:    &types.UnmaskedAmount{Value: 10 * MILLIMOB_TO_PICOMOB, TokenId: 0},
+		RequiredOutputAmounts: []*types.UnmaskedAmount{{Value: 5000000, TokenId: 1}},
+		TxOutGlobalIndices:    make([]uint64, RING_SIZE),
+	}
+	for i := range sci.TxIn.Ring {
+		sci.TxIn.Ring[i] = &types.TxOut{}
+		sci.TxOutGlobalIndices[i] = uint64(100 + i)
+	}
+	return sci
+}
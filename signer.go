@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"google.golang.org/grpc/credentials"
+)
+
+// Signer holds the spend key of an account, TransactionBuilderBuildWithSigner
+// only sends it the public TxOuts and the blindings of the inputs, so that the
+// spend key could live in an HSM or a separate process.
+type Signer interface {
+	KeyImage(ctx context.Context, txOut *TxOut, subaddressIndex uint64) (string, error)
+	SignRingMLSAG(ctx context.Context, req *SignRingRequest) (*RingMLSAG, error)
+}
+
+// SignRingRequest signs the hex Message with the onetime private key of
+// Ring[RealIndex], received by the subaddress. The hex blindings are those of
+// the real input and its pseudo output.
+type SignRingRequest struct {
+	Message         string   `json:"message"`
+	Ring            []*TxOut `json:"ring"`
+	RealIndex       int      `json:"real_index"`
+	SubaddressIndex uint64   `json:"subaddress_index,string"`
+	Value           uint64   `json:"value,string"`
+	Blinding        string   `json:"blinding"`
+	OutputBlinding  string   `json:"output_blinding"`
+	TokenID         uint64   `json:"token_id,string"`
+}
+
+// LocalSigner signs with the spend key in memory
+type LocalSigner struct {
+	account *account.Account
+}
+
+func NewLocalSigner(viewPrivateStr, spendPrivateStr string) (*LocalSigner, error) {
+	owner := &SpendAccount{ViewPrivateKey: viewPrivateStr, SpendPrivateKey: spendPrivateStr}
+	acc, err := owner.accountKey()
+	if err != nil {
+		return nil, err
+	}
+	if acc.SpendPrivateKey == nil {
+		return nil, errors.New("no spend private key")
+	}
+	return &LocalSigner{account: acc}, nil
+}
+
+// NewSigner of the owner, the remote signer is dialed with creds when the
+// owner has no spend key.
+func NewSigner(owner *SpendAccount, creds credentials.TransportCredentials) (Signer, error) {
+	if owner.SpendPrivateKey != "" {
+		return NewLocalSigner(owner.ViewPrivateKey, owner.SpendPrivateKey)
+	}
+	if owner.RemoteSigner == "" {
+		return nil, errors.New("no spend private key or remote signer")
+	}
+	return DialGRPCSigner(owner.RemoteSigner, creds)
+}
+
+func (s *LocalSigner) KeyImage(ctx context.Context, txOut *TxOut, subaddressIndex uint64) (string, error) {
+	return KeyImageForTxOut(txOut, s.account.ViewPrivateKey, s.account.SubaddressSpendPrivateKey(subaddressIndex))
+}
+
+func (s *LocalSigner) SignRingMLSAG(ctx context.Context, req *SignRingRequest) (*RingMLSAG, error) {
+	if req.RealIndex < 0 || req.RealIndex >= len(req.Ring) {
+		return nil, errors.New("invalid real index")
+	}
+	message, err := hex.DecodeString(req.Message)
+	if err != nil {
+		return nil, err
+	}
+	blinding, err := decodeScalar(req.Blinding)
+	if err != nil {
+		return nil, err
+	}
+	outputBlinding, err := decodeScalar(req.OutputBlinding)
+	if err != nil {
+		return nil, err
+	}
+	onetimePrivate, err := OnetimePrivateKey(req.Ring[req.RealIndex], s.account.ViewPrivateKey, s.account.SubaddressSpendPrivateKey(req.SubaddressIndex))
+	if err != nil {
+		return nil, err
+	}
+	return SignRingMLSAG(message, req.Ring, req.RealIndex, onetimePrivate, req.Value, blinding, outputBlinding, req.TokenID)
+}
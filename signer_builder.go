@@ -0,0 +1,500 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"github.com/bwesterb/go-ristretto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ENCRYPTED_FOG_HINT_LENGTH = 84
+)
+
+type signerInput struct {
+	in              *TxIn
+	realIndex       int
+	subaddressIndex uint64
+	value           uint64
+	blinding        *ristretto.Scalar
+	pseudoBlinding  *ristretto.Scalar
+}
+
+type signerOutput struct {
+	txOut        *TxOut
+	address      string
+	value        uint64
+	blinding     *ristretto.Scalar
+	sharedSecret *ristretto.Point
+}
+
+// TransactionBuilderBuildWithSigner builds the transaction in Go without any
+// spend key, the key images and ring signatures of the inputs are made by
+// signer, so the inputs may be owned by a view only SpendAccount. The outlays
+// and the change to changeStr, which must be owned by the account of the
+// inputs, get unused memos and a random fog hint, so fog recipients are
+// rejected. It supports a single token from block version 2. The Outlays of
+// the output follow the order of outlays, with the change last at ChangeIndex.
+func TransactionBuilderBuildWithSigner(ctx context.Context, inputs []*UTXO, proofs *Proofs, outlays []*PaymentOutlay, fee uint64, tombstone uint64, tokenID, version uint, changeStr string, signer Signer) (*Output, error) {
+	if len(outlays) == 0 {
+		return nil, errors.New("empty outlays")
+	}
+	if version < 2 {
+		return nil, fmt.Errorf("block version %d not supported", version)
+	}
+	change, err := account.DecodeB58Code(changeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var amount uint64
+	recipients := make([]*account.PublicAddress, len(outlays))
+	values := make([]uint64, len(outlays))
+	requestMemos := make([]string, len(outlays))
+	for i, outlay := range outlays {
+		payment, err := decodeRecipient(outlay.Address, outlay.Amount, tokenID)
+		if err != nil {
+			return nil, err
+		}
+		recipients[i], values[i], requestMemos[i] = payment.Address, payment.Value, payment.Memo
+		if recipients[i].FogReportUrl != "" {
+			return nil, fmt.Errorf("fog recipient %s not supported", outlay.Address)
+		}
+		if amount+values[i] < amount {
+			return nil, fmt.Errorf("outlays amount overflow %d %d", amount, values[i])
+		}
+		amount += values[i]
+	}
+	if amount+fee < amount {
+		return nil, fmt.Errorf("outlays amount overflow %d %d", amount, fee)
+	}
+
+	var totalAmount uint64
+	for _, input := range inputs {
+		totalAmount += input.Amount
+	}
+	if totalAmount < amount+fee {
+		return nil, fmt.Errorf("%w: %d, required %d", ErrInsufficientFunds, totalAmount, amount+fee)
+	}
+	changeAmount := totalAmount - amount - fee
+	err = checkChangeAmount(changeAmount, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if changeAmount > 0 {
+		if change.FogReportUrl != "" {
+			return nil, fmt.Errorf("fog change address %s not supported", changeStr)
+		}
+		recipients = append(recipients, change)
+		values = append(values, changeAmount)
+	}
+	if len(recipients) > MAX_OUTPUTS {
+		return nil, fmt.Errorf("too many outputs %d, max %d", len(recipients), MAX_OUTPUTS)
+	}
+
+	signerInputs, viewPrivate, err := buildSignerInputs(inputs, proofs, uint64(tokenID))
+	if err != nil {
+		return nil, err
+	}
+	err = checkChangeOwner(change, viewPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*signerOutput, len(recipients))
+	var outputBlinding ristretto.Scalar
+	outputBlinding.SetZero()
+	for i, recipient := range recipients {
+		outputs[i], err = newSignerOutput(recipient, values[i], uint64(tokenID), version)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i].address = changeStr
+		if i < len(outlays) {
+			outputs[i].address = outlays[i].Address
+		}
+		outputBlinding.Add(&outputBlinding, outputs[i].blinding)
+	}
+
+	// The pseudo outputs balance the outputs and the fee, whose commitment
+	// has no blinding, so their blindings sum to those of the outputs.
+	for i, input := range signerInputs {
+		var blinding ristretto.Scalar
+		if i == len(signerInputs)-1 {
+			blinding.Set(&outputBlinding)
+		} else {
+			blinding.Rand()
+			outputBlinding.Sub(&outputBlinding, &blinding)
+		}
+		input.pseudoBlinding = &blinding
+	}
+	sort.Slice(signerInputs, func(i, j int) bool {
+		return signerInputs[i].in.Ring[0].PublicKey < signerInputs[j].in.Ring[0].PublicKey
+	})
+	sorted := append([]*signerOutput{}, outputs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].txOut.PublicKey < sorted[j].txOut.PublicKey
+	})
+
+	generators := NewPedersenGensForToken(uint64(tokenID))
+	prefix := &TxPrefix{Fee: FeeValue(fee), FeeTokenID: uint64(tokenID), TombstoneBlock: TombstoneValue(tombstone)}
+	signature := &SignatureRctBulletproofs{}
+	var rangeValues []uint64
+	var rangeBlindings []*ristretto.Scalar
+	for _, input := range signerInputs {
+		prefix.Inputs = append(prefix.Inputs, input.in)
+		commitment := generators.Commit(uint64ToScalar(input.value), input.pseudoBlinding)
+		signature.PseudoOutputCommitments = append(signature.PseudoOutputCommitments, hex.EncodeToString(commitment.Bytes()))
+		rangeValues = append(rangeValues, input.value)
+		rangeBlindings = append(rangeBlindings, input.pseudoBlinding)
+	}
+	for _, output := range sorted {
+		prefix.Outputs = append(prefix.Outputs, output.txOut)
+		rangeValues = append(rangeValues, output.value)
+		rangeBlindings = append(rangeBlindings, output.blinding)
+	}
+	proof, _, err := GenerateRangeProofs(rangeValues, rangeBlindings, generators)
+	if err != nil {
+		return nil, err
+	}
+	if version < 3 {
+		signature.RangeProofs = hex.EncodeToString(proof.Bytes())
+	} else {
+		signature.TokenRangeProofs = []string{hex.EncodeToString(proof.Bytes())}
+		for range signerInputs {
+			signature.PseudoOutputTokenIDs = append(signature.PseudoOutputTokenIDs, uint64(tokenID))
+		}
+		for range sorted {
+			signature.OutputTokenIDs = append(signature.OutputTokenIDs, uint64(tokenID))
+		}
+	}
+
+	digest, err := TxPrefixDigest(prefix)
+	if err != nil {
+		return nil, err
+	}
+	message, err := ExtendedMessageDigest(version, digest, signature)
+	if err != nil {
+		return nil, err
+	}
+	for i, input := range signerInputs {
+		mlsag, err := signer.SignRingMLSAG(ctx, &SignRingRequest{
+			Message:         hex.EncodeToString(message),
+			Ring:            input.in.Ring,
+			RealIndex:       input.realIndex,
+			SubaddressIndex: input.subaddressIndex,
+			Value:           input.value,
+			Blinding:        hex.EncodeToString(input.blinding.Bytes()),
+			OutputBlinding:  hex.EncodeToString(input.pseudoBlinding.Bytes()),
+			TokenID:         uint64(tokenID),
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = VerifyRingMLSAG(mlsag, message, input.in.Ring, signature.PseudoOutputCommitments[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ring signature of input %d: %v", i, err)
+		}
+		signature.RingSignatures = append(signature.RingSignatures, mlsag)
+	}
+
+	raw, err := proto.Marshal(MarshalTx(&Tx{Prefix: prefix, Signature: signature}))
+	if err != nil {
+		return nil, err
+	}
+	output := &Output{
+		RawTransaction: hex.EncodeToString(raw),
+		Fee:            fee,
+		ChangeIndex:    -1,
+		ChangeAmount:   changeAmount,
+	}
+	for i, out := range outputs {
+		outlay := &OutlayOutput{
+			Address:            out.address,
+			Amount:             out.value,
+			TokenID:            tokenID,
+			OutputHash:         out.txOut.PublicKey,
+			SharedSecret:       hex.EncodeToString(out.sharedSecret.Bytes()),
+			ConfirmationNumber: hex.EncodeToString(ConfirmationNumberFromSecret(out.sharedSecret.Bytes())),
+		}
+		if i < len(outlays) {
+			outlay.RequestMemo = requestMemos[i]
+		} else {
+			output.ChangeIndex = int64(i)
+			output.ChangeHash = outlay.OutputHash
+		}
+		output.Outlays = append(output.Outlays, outlay)
+	}
+	output.TransactionHash = output.Outlays[0].OutputHash
+	output.OutputHash = output.Outlays[0].OutputHash
+	output.SharedSecret = output.Outlays[0].SharedSecret
+	return output, nil
+}
+
+// buildSignerInputs sorts the ring of each input in proofs, and unmasks the
+// value and blinding of the real input with the view key of its owner, which
+// is returned for the first input.
+func buildSignerInputs(utxos []*UTXO, proofs *Proofs, tokenID uint64) ([]*signerInput, *ristretto.Scalar, error) {
+	if proofs == nil || len(proofs.Ring) == 0 || len(proofs.Ring) != len(proofs.Rings) {
+		return nil, nil, errors.New("Invalid proofs")
+	}
+	if len(proofs.Ring) != len(utxos) || len(utxos) > MAX_INPUTS {
+		return nil, nil, fmt.Errorf("invalid inputs count %d, proofs count %d", len(utxos), len(proofs.Ring))
+	}
+	inputSet := make(map[string]*UTXO)
+	for _, utxo := range utxos {
+		txOut, err := utxoTxOut(utxo)
+		if err != nil {
+			return nil, nil, err
+		}
+		inputSet[txOut.PublicKey] = utxo
+	}
+
+	var firstViewPrivate *ristretto.Scalar
+	inputs := make([]*signerInput, len(proofs.Ring))
+	for i, real := range proofs.Ring {
+		utxo := inputSet[real.TxOut.PublicKey]
+		if utxo == nil {
+			return nil, nil, fmt.Errorf("UTXO %s not found", real.TxOut.PublicKey)
+		}
+		owner, err := utxo.SpendAccount()
+		if err != nil {
+			return nil, nil, err
+		}
+		viewPrivate, err := decodeScalar(owner.ViewPrivateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			firstViewPrivate = viewPrivate
+		}
+
+		value, blinding, err := unmaskTxOut(real.TxOut, viewPrivate, tokenID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if value != utxo.Amount {
+			return nil, nil, fmt.Errorf("UTXO %s value %d, amount %d", real.TxOut.PublicKey, value, utxo.Amount)
+		}
+
+		ring := []*TxOutWithProof{real}
+		for _, decoy := range proofs.Rings[i] {
+			if decoy.TxOut.PublicKey != real.TxOut.PublicKey {
+				ring = append(ring, decoy)
+			}
+		}
+		sort.Slice(ring, func(i, j int) bool { return ring[i].TxOut.PublicKey < ring[j].TxOut.PublicKey })
+		in := &TxIn{}
+		var realIndex int
+		for j, element := range ring {
+			if element == real {
+				realIndex = j
+			}
+			if element.Proof == nil {
+				return nil, nil, fmt.Errorf("no membership proof of %s", element.TxOut.PublicKey)
+			}
+			in.Ring = append(in.Ring, element.TxOut)
+			in.Proofs = append(in.Proofs, element.Proof)
+		}
+		inputs[i] = &signerInput{
+			in:              in,
+			realIndex:       realIndex,
+			subaddressIndex: owner.SubaddressIndex,
+			value:           value,
+			blinding:        blinding,
+		}
+	}
+	return inputs, firstViewPrivate, nil
+}
+
+// unmaskTxOut returns the value and the blinding of the commitment of txOut,
+// which must be of tokenID
+func unmaskTxOut(txOut *TxOut, viewPrivate *ristretto.Scalar, tokenID uint64) (uint64, *ristretto.Scalar, error) {
+	if txOut.Amount == nil {
+		return 0, nil, fmt.Errorf("invalid txout amount %s", txOut.PublicKey)
+	}
+	id, err := getTokenID(txOut, viewPrivate)
+	if err != nil {
+		return 0, nil, err
+	}
+	if id != tokenID {
+		return 0, nil, fmt.Errorf("txout %s token %d, required %d", txOut.PublicKey, id, tokenID)
+	}
+
+	publicKey, err := decodePoint(txOut.PublicKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	secret := createSharedSecret(publicKey, viewPrivate)
+	var value uint64
+	var blinding *ristretto.Scalar
+	if txOut.Amount.Version == 2 {
+		amountSecret := ComputeAmountSharedSecretV2(secret)
+		value, err = ComputeCommitmentV2(uint64(txOut.Amount.MaskedValue), amountSecret)
+		if err != nil {
+			return 0, nil, err
+		}
+		blinding, err = GetBlindingV2(amountSecret)
+		if err != nil {
+			return 0, nil, err
+		}
+	} else {
+		value = uint64(txOut.Amount.MaskedValue) ^ GetValueMask(secret)
+		blinding = GetBlinding(secret)
+	}
+
+	commitment := NewPedersenGensForToken(tokenID).Commit(uint64ToScalar(value), blinding)
+	if hex.EncodeToString(commitment.Bytes()) != txOut.Amount.Commitment {
+		return 0, nil, fmt.Errorf("txout %s commitment mismatch", txOut.PublicKey)
+	}
+	return value, blinding, nil
+}
+
+// newSignerOutput pays value to recipient with the v2 masked amount from block
+// version 3, an unused memo and a random fog hint
+func newSignerOutput(recipient *account.PublicAddress, value, tokenID uint64, version uint) (*signerOutput, error) {
+	spendPublic, err := decodePoint(recipient.SpendPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	viewPublic, err := decodePoint(recipient.ViewPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var r ristretto.Scalar
+	r.Rand()
+	var R ristretto.Point
+	R.ScalarMult(spendPublic, &r)
+	secret := createSharedSecret(viewPublic, &r)
+
+	var target, g ristretto.Point
+	target.Add(g.ScalarMultBase(hashToScalar(viewPublic, &r)), spendPublic)
+
+	amount := &Amount{Version: 1}
+	var valueMask, tokenIDMask uint64
+	var blinding *ristretto.Scalar
+	if version < 3 {
+		valueMask = GetValueMask(secret)
+		tokenIDMask = getTokenIDMask(secret)
+		blinding = GetBlinding(secret)
+	} else {
+		amount.Version = 2
+		amountSecret := ComputeAmountSharedSecretV2(secret)
+		valueMask, err = GetBlindingFactorsV2(amountSecret)
+		if err != nil {
+			return nil, err
+		}
+		tokenIDMask, err = getTokenIDMaskV2(amountSecret)
+		if err != nil {
+			return nil, err
+		}
+		blinding, err = GetBlindingV2(amountSecret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	commitment := NewPedersenGensForToken(tokenID).Commit(uint64ToScalar(value), blinding)
+	amount.Commitment = hex.EncodeToString(commitment.Bytes())
+	amount.MaskedValue = MaskedValue(value ^ valueMask)
+	amount.MaskedTokenID = hex.EncodeToString(binary.LittleEndian.AppendUint64(nil, tokenID^tokenIDMask))
+
+	hint := make([]byte, ENCRYPTED_FOG_HINT_LENGTH)
+	_, err = rand.Read(hint)
+	if err != nil {
+		return nil, err
+	}
+	unused := joinMemoPayload(MEMO_TYPE_UNUSED, make([]byte, MEMO_DATA_LENGTH))
+	memo, err := EncryptMemo(hex.EncodeToString(unused), recipient.ViewPublicKey, hex.EncodeToString(r.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &signerOutput{
+		txOut: &TxOut{
+			Amount:    amount,
+			TargetKey: hex.EncodeToString(target.Bytes()),
+			PublicKey: hex.EncodeToString(R.Bytes()),
+			EFogHint:  hex.EncodeToString(hint),
+			EMemo:     hex.EncodeToString(memo),
+		},
+		value:        value,
+		blinding:     blinding,
+		sharedSecret: secret,
+	}, nil
+}
+
+func MarshalTx(tx *Tx) *types.Tx {
+	return &types.Tx{
+		Prefix:    MarshalPrefix(tx.Prefix),
+		Signature: MarshalSignatureRctBulletproofs(tx.Signature),
+	}
+}
+
+func MarshalPrefix(prefix *TxPrefix) *types.TxPrefix {
+	ins := make([]*types.TxIn, len(prefix.Inputs))
+	for i, in := range prefix.Inputs {
+		ring := make([]*types.TxOut, len(in.Ring))
+		for j, r := range in.Ring {
+			ring[j] = MarshalTxOut(r)
+		}
+		proofs := make([]*types.TxOutMembershipProof, len(in.Proofs))
+		for j, p := range in.Proofs {
+			proofs[j] = MarshalTxOutMembershipProof(p)
+		}
+		ins[i] = &types.TxIn{Ring: ring, Proofs: proofs}
+	}
+	outs := make([]*types.TxOut, len(prefix.Outputs))
+	for i, out := range prefix.Outputs {
+		outs[i] = MarshalTxOut(out)
+	}
+	return &types.TxPrefix{
+		Inputs:         ins,
+		Outputs:        outs,
+		Fee:            uint64(prefix.Fee),
+		FeeTokenId:     prefix.FeeTokenID,
+		TombstoneBlock: uint64(prefix.TombstoneBlock),
+	}
+}
+
+func MarshalSignatureRctBulletproofs(signature *SignatureRctBulletproofs) *types.SignatureRctBulletproofs {
+	signatures := make([]*types.RingMLSAG, len(signature.RingSignatures))
+	for i, s := range signature.RingSignatures {
+		signatures[i] = MarshalRingMLSAG(s)
+	}
+	commitments := make([]*types.CompressedRistretto, len(signature.PseudoOutputCommitments))
+	for i, c := range signature.PseudoOutputCommitments {
+		commitments[i] = &types.CompressedRistretto{Data: account.HexToBytes(c)}
+	}
+	rangeProofs := make([][]byte, len(signature.TokenRangeProofs))
+	for i, p := range signature.TokenRangeProofs {
+		rangeProofs[i] = account.HexToBytes(p)
+	}
+	return &types.SignatureRctBulletproofs{
+		RingSignatures:          signatures,
+		PseudoOutputCommitments: commitments,
+		RangeProofBytes:         account.HexToBytes(signature.RangeProofs),
+		RangeProofs:             rangeProofs,
+		PseudoOutputTokenIds:    signature.PseudoOutputTokenIDs,
+		OutputTokenIds:          signature.OutputTokenIDs,
+	}
+}
+
+func MarshalRingMLSAG(mlsag *RingMLSAG) *types.RingMLSAG {
+	responses := make([]*types.CurveScalar, len(mlsag.Responses))
+	for i, resp := range mlsag.Responses {
+		responses[i] = &types.CurveScalar{Data: account.HexToBytes(resp)}
+	}
+	return &types.RingMLSAG{
+		CZero:     &types.CurveScalar{Data: account.HexToBytes(mlsag.CZero)},
+		Responses: responses,
+		KeyImage:  &types.KeyImage{Data: account.HexToBytes(mlsag.KeyImage)},
+	}
+}
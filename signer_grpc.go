@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	SIGNER_SERVICE_NAME = "mobilecoin.Signer"
+	SIGNER_CODEC_NAME   = "mobilecoin-signer-json"
+)
+
+// the signer messages are JSON, so no protobuf definitions are required. The
+// codec is registered under its own name for the signer server to decode the
+// requests, and the client forces it on each call.
+type signerCodec struct{}
+
+func (signerCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (signerCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (signerCodec) Name() string {
+	return SIGNER_CODEC_NAME
+}
+
+func init() {
+	encoding.RegisterCodec(signerCodec{})
+}
+
+type keyImageRequest struct {
+	TxOut           *TxOut `json:"tx_out"`
+	SubaddressIndex uint64 `json:"subaddress_index,string"`
+}
+
+type keyImageResponse struct {
+	KeyImage string `json:"key_image"`
+}
+
+// GRPCSigner calls the signer server at target, e.g. unix:///run/signer.sock.
+// The signer signs whatever it is sent, so the connection must authenticate
+// both ends, e.g. TLS with client certificates.
+type GRPCSigner struct {
+	conn *grpc.ClientConn
+}
+
+// DialGRPCSigner rejects the insecure credentials, the signer server should
+// require and verify the client certificate of creds.
+func DialGRPCSigner(target string, creds credentials.TransportCredentials) (*GRPCSigner, error) {
+	if creds == nil || creds.Info().SecurityProtocol == "insecure" {
+		return nil, errors.New("insecure signer credentials")
+	}
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCSigner{conn: conn}, nil
+}
+
+func (s *GRPCSigner) Close() error {
+	return s.conn.Close()
+}
+
+func (s *GRPCSigner) KeyImage(ctx context.Context, txOut *TxOut, subaddressIndex uint64) (string, error) {
+	var resp keyImageResponse
+	req := &keyImageRequest{TxOut: txOut, SubaddressIndex: subaddressIndex}
+	err := s.conn.Invoke(ctx, "/"+SIGNER_SERVICE_NAME+"/KeyImage", req, &resp, grpc.ForceCodec(signerCodec{}))
+	if err != nil {
+		return "", err
+	}
+	return resp.KeyImage, nil
+}
+
+func (s *GRPCSigner) SignRingMLSAG(ctx context.Context, req *SignRingRequest) (*RingMLSAG, error) {
+	var resp RingMLSAG
+	err := s.conn.Invoke(ctx, "/"+SIGNER_SERVICE_NAME+"/SignRingMLSAG", req, &resp, grpc.ForceCodec(signerCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RegisterSignerServer serves signer, usually a LocalSigner in the process
+// holding the spend key, on server, which should be created with the TLS
+// credentials requiring the client certificates.
+func RegisterSignerServer(server *grpc.Server, signer Signer) {
+	server.RegisterService(&signerServiceDesc, signer)
+}
+
+var signerServiceDesc = grpc.ServiceDesc{
+	ServiceName: SIGNER_SERVICE_NAME,
+	HandlerType: (*Signer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "KeyImage",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			var req keyImageRequest
+			err := dec(&req)
+			if err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				r := req.(*keyImageRequest)
+				keyImage, err := srv.(Signer).KeyImage(ctx, r.TxOut, r.SubaddressIndex)
+				if err != nil {
+					return nil, err
+				}
+				return &keyImageResponse{KeyImage: keyImage}, nil
+			}
+			if interceptor == nil {
+				return handler(ctx, &req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + SIGNER_SERVICE_NAME + "/KeyImage"}
+			return interceptor(ctx, &req, info, handler)
+		},
+	}, {
+		MethodName: "SignRingMLSAG",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			var req SignRingRequest
+			err := dec(&req)
+			if err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return srv.(Signer).SignRingMLSAG(ctx, req.(*SignRingRequest))
+			}
+			if interceptor == nil {
+				return handler(ctx, &req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + SIGNER_SERVICE_NAME + "/SignRingMLSAG"}
+			return interceptor(ctx, &req, info, handler)
+		},
+	}},
+}
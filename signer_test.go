@@ -0,0 +1,376 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestGRPCSigner(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+
+	// the signer codec does not replace the codecs of other packages
+	assert.Nil(encoding.GetCodec("json"))
+	assert.NotNil(encoding.GetCodec(SIGNER_CODEC_NAME))
+
+	local, err := NewLocalSigner(viewStr, spendStr)
+	assert.Nil(err)
+	socket, clientCreds := newTestSignerServer(t, local)
+
+	owner := &SpendAccount{ViewPrivateKey: viewStr, SubaddressIndex: 2, RemoteSigner: "unix://" + socket}
+	_, err = NewSigner(owner, nil)
+	assert.NotNil(err)
+	_, err = NewSigner(owner, insecure.NewCredentials())
+	assert.NotNil(err)
+	signer, err := NewSigner(owner, clientCreds)
+	assert.Nil(err)
+	remote := signer.(*GRPCSigner)
+	defer remote.Close()
+
+	value := uint64(12 * MILLIMOB_TO_PICOMOB)
+	var blinding, outputBlinding ristretto.Scalar
+	blinding.Rand()
+	outputBlinding.Rand()
+	generators := NewPedersenGens()
+	ring := make([]*TxOut, RING_SIZE)
+	for i := range ring {
+		var targetKey, commitment ristretto.Point
+		targetKey.Rand()
+		commitment.Rand()
+		ring[i] = &TxOut{
+			TargetKey: hex.EncodeToString(targetKey.Bytes()),
+			Amount:    &Amount{Commitment: hex.EncodeToString(commitment.Bytes())},
+		}
+	}
+	owned := newScannerTestTxOut(assert, acc.PublicAddress(2), value, 0, nil)
+	owned.Amount.Commitment = hex.EncodeToString(generators.Commit(uint64ToScalar(value), &blinding).Bytes())
+	ring[4] = owned
+
+	keyImage, err := remote.KeyImage(ctx, owned, 2)
+	assert.Nil(err)
+	expected, err := KeyImageForTxOut(owned, &view, acc.SubaddressSpendPrivateKey(2))
+	assert.Nil(err)
+	assert.Equal(expected, keyImage)
+
+	message := []byte("mobilecoin remote signer")
+	req := &SignRingRequest{
+		Message:         hex.EncodeToString(message),
+		Ring:            ring,
+		RealIndex:       4,
+		SubaddressIndex: 2,
+		Value:           value,
+		Blinding:        hex.EncodeToString(blinding.Bytes()),
+		OutputBlinding:  hex.EncodeToString(outputBlinding.Bytes()),
+	}
+	mlsag, err := remote.SignRingMLSAG(ctx, req)
+	assert.Nil(err)
+	assert.Equal(keyImage, mlsag.KeyImage)
+	outputCommitment := hex.EncodeToString(generators.Commit(uint64ToScalar(value), &outputBlinding).Bytes())
+	assert.Nil(VerifyRingMLSAG(mlsag, message, ring, outputCommitment))
+
+	req.SubaddressIndex = 0
+	_, err = remote.SignRingMLSAG(ctx, req)
+	assert.NotNil(err)
+
+	// the server rejects the clients without a certificate of its CA
+	anonymous, err := DialGRPCSigner("unix://"+socket, credentials.NewTLS(&tls.Config{ServerName: "signer", InsecureSkipVerify: true}))
+	assert.Nil(err)
+	defer anonymous.Close()
+	_, err = anonymous.KeyImage(ctx, owned, 2)
+	assert.NotNil(err)
+
+	_, err = NewSigner(&SpendAccount{ViewPrivateKey: viewStr}, clientCreds)
+	assert.NotNil(err)
+}
+
+func TestTransactionBuilderBuildWithSigner(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+	change, err := acc.B58Code(0)
+	assert.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	recipientAddress, err := recipient.B58Code(1)
+	assert.Nil(err)
+
+	local, err := NewLocalSigner(viewStr, spendStr)
+	assert.Nil(err)
+	socket, clientCreds := newTestSignerServer(t, local)
+	owner := &SpendAccount{ViewPrivateKey: viewStr, RemoteSigner: "unix://" + socket}
+	signer, err := NewSigner(owner, clientCreds)
+	assert.Nil(err)
+	defer signer.(*GRPCSigner).Close()
+
+	for _, version := range []uint{2, 3} {
+		inputs, proofs := newTestInputs(assert, acc, []uint64{5 * MILLIMOB_TO_PICOMOB, 7 * MILLIMOB_TO_PICOMOB}, 0)
+		for _, input := range inputs {
+			input.Account = owner
+		}
+		outlays := []*PaymentOutlay{{Address: recipientAddress, Amount: 4 * MILLIMOB_TO_PICOMOB}}
+		output, err := TransactionBuilderBuildWithSigner(ctx, inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, version, change, signer)
+		assert.Nil(err)
+		assert.Equal(uint64(8*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE), output.ChangeAmount)
+		assert.Len(output.Outlays, 2)
+		assert.Equal(int64(1), output.ChangeIndex)
+		assert.Equal(change, output.Outlays[1].Address)
+		assert.Equal(output.ChangeHash, output.Outlays[1].OutputHash)
+		assert.Equal(output.ChangeAmount, output.Outlays[1].Amount)
+
+		tx, err := decodeTestTx(output.RawTransaction)
+		assert.Nil(err)
+		assert.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version}))
+		assert.Len(tx.Prefix.Inputs, 2)
+		assert.Len(tx.Prefix.Outputs, 2)
+		for _, out := range tx.Prefix.Outputs {
+			assert.Len(out.EFogHint, 2*ENCRYPTED_FOG_HINT_LENGTH)
+		}
+
+		scanner, err := NewScanner(hex.EncodeToString(recipientView.Bytes()), hex.EncodeToString(account.PublicKey(&recipientSpend).Bytes()), 2)
+		assert.Nil(err)
+		matches, err := scanner.Scan(tx.Prefix.Outputs)
+		assert.Nil(err)
+		assert.Len(matches, 1)
+		assert.Equal(output.OutputHash, matches[0].TxOut.PublicKey)
+		assert.Equal(uint64(1), matches[0].SubaddressIndex)
+		assert.Equal(uint64(4*MILLIMOB_TO_PICOMOB), matches[0].Value)
+		assert.Equal(joinMemoPayload(MEMO_TYPE_UNUSED, make([]byte, MEMO_DATA_LENGTH)), matches[0].MemoPayload)
+		secret := createSharedSecret(hexToPoint(matches[0].TxOut.PublicKey), &recipientView)
+		assert.Equal(output.SharedSecret, hex.EncodeToString(secret.Bytes()))
+		assert.Equal(output.Outlays[0].ConfirmationNumber, hex.EncodeToString(ConfirmationNumberFromSecret(secret.Bytes())))
+
+		scanner, err = NewScanner(viewStr, hex.EncodeToString(account.PublicKey(&spend).Bytes()), 1)
+		assert.Nil(err)
+		matches, err = scanner.Scan(tx.Prefix.Outputs)
+		assert.Nil(err)
+		assert.Len(matches, 1)
+		assert.Equal(output.ChangeHash, matches[0].TxOut.PublicKey)
+		assert.Equal(output.ChangeAmount, matches[0].Value)
+	}
+
+	inputs, proofs := newTestInputs(assert, acc, []uint64{6 * MILLIMOB_TO_PICOMOB}, 0)
+	for _, input := range inputs {
+		input.Account = owner
+	}
+	outlays := []*PaymentOutlay{{Address: recipientAddress, Amount: 4 * MILLIMOB_TO_PICOMOB}}
+	output, err := TransactionBuilderBuildWithSigner(ctx, inputs, proofs, []*PaymentOutlay{{Address: recipientAddress, Amount: 6*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE}}, MOB_MINIMUM_FEE, 100, 0, 3, change, signer)
+	assert.Nil(err)
+	assert.Len(output.Outlays, 1)
+	assert.Equal(int64(-1), output.ChangeIndex)
+	assert.Equal("", output.ChangeHash)
+	request, err := (&PrintableWrapper{PaymentRequest: &PaymentRequest{Address: recipient.PublicAddress(1), Value: 6*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE, Memo: "invoice 42"}}).B58Code()
+	assert.Nil(err)
+	output, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, []*PaymentOutlay{{Address: request}}, MOB_MINIMUM_FEE, 100, 0, 3, change, signer)
+	assert.Nil(err)
+	assert.Equal(uint64(6*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE), output.Outlays[0].Amount)
+	assert.Equal("invoice 42", output.Outlays[0].RequestMemo)
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, []*PaymentOutlay{{Address: recipientAddress}}, MOB_MINIMUM_FEE, 100, 0, 3, change, signer)
+	assert.ErrorContains(err, "zero amount")
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, []*PaymentOutlay{{Address: recipientAddress, Amount: math.MaxUint64}, {Address: recipientAddress, Amount: 1}}, MOB_MINIMUM_FEE, 100, 0, 3, change, signer)
+	assert.ErrorContains(err, "outlays amount overflow")
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, 1, change, signer)
+	assert.NotNil(err)
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, 3, recipientAddress, signer)
+	assert.ErrorContains(err, "change address not owned")
+
+	fog := recipient.PublicAddress(0)
+	fog.FogReportUrl = "fog://fog.prod.mobilecoinww.com"
+	fogAddress, err := fog.B58Code()
+	assert.Nil(err)
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, []*PaymentOutlay{{Address: fogAddress, Amount: 4 * MILLIMOB_TO_PICOMOB}}, MOB_MINIMUM_FEE, 100, 0, 3, change, signer)
+	assert.ErrorContains(err, "fog recipient")
+
+	// the signer of another account can not spend the inputs
+	var otherView, otherSpend ristretto.Scalar
+	other, err := NewLocalSigner(hex.EncodeToString(otherView.Rand().Bytes()), hex.EncodeToString(otherSpend.Rand().Bytes()))
+	assert.Nil(err)
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, 3, change, other)
+	assert.ErrorContains(err, "not owned by the subaddress")
+	// nor is the signature of another message accepted
+	_, err = TransactionBuilderBuildWithSigner(ctx, inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, 3, change, &messageSigner{local})
+	assert.ErrorContains(err, "invalid ring signature of input 0")
+}
+
+// TestTransactionBuilderBuildWithSignerC checks the outputs and key images of
+// the transaction built in Go with libmobilecoin, and verifies it with VerifyTx,
+// which TestVerifyTxC checks against the transactions built by libmobilecoin.
+func TestTransactionBuilderBuildWithSignerC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+	change, err := acc.B58Code(0)
+	require.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	recipientAddress, err := recipient.B58Code(1)
+	require.Nil(err)
+	signer, err := NewLocalSigner(viewStr, spendStr)
+	require.Nil(err)
+
+	for _, version := range []uint{2, 3} {
+		inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{5 * MILLIMOB_TO_PICOMOB, 7 * MILLIMOB_TO_PICOMOB}, 0)
+		outlays := []*PaymentOutlay{{Address: recipientAddress, Amount: 4 * MILLIMOB_TO_PICOMOB}}
+		output, err := TransactionBuilderBuildWithSigner(ctx, inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, version, change, signer)
+		require.Nil(err)
+		tx, err := decodeTestTx(output.RawTransaction)
+		require.Nil(err)
+		require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version}))
+
+		keys := map[string][2]string{
+			output.Outlays[0].OutputHash: {hex.EncodeToString(recipientView.Bytes()), hex.EncodeToString(recipientSpend.Bytes())},
+			output.ChangeHash:            {viewStr, spendStr},
+		}
+		for _, out := range tx.Prefix.Outputs {
+			key := keys[out.PublicKey]
+			require.NotEmpty(key[0])
+			amount, err := MCTxOutGetAmount(fmt.Sprint(uint64(out.Amount.MaskedValue)), out.Amount.MaskedTokenID, out.Amount.Version, out.PublicKey, key[0])
+			require.Nil(err)
+			require.Equal(uint64(0), amount.TokenID)
+			commitment, err := MCTxOutReconstructCommitment(fmt.Sprint(uint64(out.Amount.MaskedValue)), out.Amount.MaskedTokenID, out.Amount.Version, out.PublicKey, key[0])
+			require.Nil(err)
+			require.Equal(out.Amount.Commitment, commitment)
+			secret, err := McTxOutGetSharedSecret(out.PublicKey, key[0])
+			require.Nil(err)
+			payload, err := DecryptEMemoPayload(out.EMemo, out.PublicKey, key[0], key[1])
+			require.Nil(err)
+			require.Equal(hex.EncodeToString(joinMemoPayload(MEMO_TYPE_UNUSED, make([]byte, MEMO_DATA_LENGTH))), payload)
+			for _, outlay := range output.Outlays {
+				if outlay.OutputHash == out.PublicKey {
+					require.Equal(outlay.Amount, amount.Value)
+					require.Equal(outlay.SharedSecret, secret)
+				}
+			}
+		}
+
+		for i, in := range tx.Prefix.Inputs {
+			for _, utxo := range inputs {
+				real, err := utxoTxOut(utxo)
+				require.Nil(err)
+				for _, member := range in.Ring {
+					if member.PublicKey == real.PublicKey {
+						keyImage, err := MCTxOutGetKeyImage(real.TargetKey, real.PublicKey, viewStr, hex.EncodeToString(acc.SubaddressSpendPrivateKey(0).Bytes()))
+						require.Nil(err)
+						require.Equal(keyImage, tx.Signature.RingSignatures[i].KeyImage)
+					}
+				}
+			}
+		}
+	}
+}
+
+// messageSigner signs the rings with another message
+type messageSigner struct {
+	*LocalSigner
+}
+
+func (s *messageSigner) SignRingMLSAG(ctx context.Context, req *SignRingRequest) (*RingMLSAG, error) {
+	other := *req
+	other.Message = hex.EncodeToString([]byte("another message"))
+	return s.LocalSigner.SignRingMLSAG(ctx, &other)
+}
+
+// newTestSignerServer serves signer on a unix socket with TLS, requiring the
+// client certificates of a test CA, and returns the client credentials.
+func newTestSignerServer(t *testing.T, signer Signer) (string, credentials.TransportCredentials) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "signer ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "signer"},
+			DNSNames:     []string{"signer"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	serverCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{issue(2, x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	clientCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{issue(3, x509.ExtKeyUsageClientAuth)},
+		RootCAs:      pool,
+		ServerName:   "signer",
+	})
+
+	socket := filepath.Join(t.TempDir(), "signer.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.Creds(serverCreds))
+	RegisterSignerServer(server, signer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return socket, clientCreds
+}
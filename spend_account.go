@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+)
+
+// SpendAccount is the subaddress of an account which owns a UTXO. The hex
+// SpendPrivateKey is empty for a view only account, whose spend key is held
+// by the RemoteSigner, e.g. an HSM or a separate process.
+type SpendAccount struct {
+	ViewPrivateKey  string
+	SpendPrivateKey string
+	SubaddressIndex uint64
+	RemoteSigner    string
+}
+
+// SpendAccount of the utxo, parsed from PrivateKey when Account is nil
+func (utxo *UTXO) SpendAccount() (*SpendAccount, error) {
+	if utxo.Account != nil {
+		if len(utxo.Account.ViewPrivateKey) != 64 {
+			return nil, fmt.Errorf("invalid view private key length %d", len(utxo.Account.ViewPrivateKey))
+		}
+		return utxo.Account, nil
+	}
+	if len(utxo.PrivateKey) != 128 {
+		return nil, fmt.Errorf("invalid private key length %d", len(utxo.PrivateKey))
+	}
+	return &SpendAccount{
+		ViewPrivateKey:  utxo.PrivateKey[:64],
+		SpendPrivateKey: utxo.PrivateKey[64:],
+	}, nil
+}
+
+func (owner *SpendAccount) accountKey() (*account.Account, error) {
+	view, err := decodeScalar(owner.ViewPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	acc := &account.Account{ViewPrivateKey: view}
+	if owner.SpendPrivateKey != "" {
+		acc.SpendPrivateKey, err = decodeScalar(owner.SpendPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
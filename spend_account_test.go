@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpendAccount(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	viewStr, spendStr := hex.EncodeToString(view.Bytes()), hex.EncodeToString(spend.Bytes())
+
+	out := newScannerTestTxOut(assert, acc.PublicAddress(4), 100, 0, nil)
+	script, err := json.Marshal(out)
+	assert.Nil(err)
+	proofs := &Proofs{
+		Ring:  []*TxOutWithProof{{TxOut: out, Proof: &TxOutMembershipProof{Index: "1", HighestIndex: "1"}}},
+		Rings: [][]*TxOutWithProof{{{TxOut: out, Proof: &TxOutMembershipProof{Index: "1", HighestIndex: "1"}}}},
+	}
+
+	utxo := &UTXO{
+		Amount:       100,
+		ScriptPubKey: hex.EncodeToString(script),
+		Account:      &SpendAccount{ViewPrivateKey: viewStr, SpendPrivateKey: spendStr, SubaddressIndex: 4},
+	}
+	inputCs, err := BuildRingElements([]*UTXO{utxo}, proofs)
+	assert.Nil(err)
+	assert.Equal(acc.SubaddressSpendPrivateKey(4).Bytes(), inputCs[0].SubAddressSpendPrivate.Bytes())
+	assert.Equal(spend.Bytes(), inputCs[0].SpendPrivate.Bytes())
+
+	utxo.Account = nil
+	utxo.PrivateKey = viewStr + spendStr
+	owner, err := utxo.SpendAccount()
+	assert.Nil(err)
+	assert.Equal(uint64(0), owner.SubaddressIndex)
+	inputCs, err = BuildRingElements([]*UTXO{utxo}, proofs)
+	assert.Nil(err)
+	assert.Equal(acc.SubaddressSpendPrivateKey(0).Bytes(), inputCs[0].SubAddressSpendPrivate.Bytes())
+
+	utxo.PrivateKey = viewStr
+	_, err = utxo.SpendAccount()
+	assert.NotNil(err)
+
+	utxo.Account = &SpendAccount{ViewPrivateKey: viewStr, SubaddressIndex: 4, RemoteSigner: "unix:///tmp/signer.sock"}
+	_, err = BuildRingElements([]*UTXO{utxo}, proofs)
+	assert.ErrorContains(err, "TransactionBuilderBuildWithSigner")
+	inputs, viewPrivate, err := buildSignerInputs([]*UTXO{utxo}, proofs, 0)
+	assert.Nil(err)
+	assert.Equal(view.Bytes(), viewPrivate.Bytes())
+	assert.Equal(uint64(4), inputs[0].subaddressIndex)
+	assert.Equal(uint64(100), inputs[0].value)
+	utxo.Amount = 101
+	_, _, err = buildSignerInputs([]*UTXO{utxo}, proofs, 0)
+	assert.NotNil(err)
+	utxo.Amount = 100
+	_, _, err = buildSignerInputs([]*UTXO{utxo}, proofs, 1)
+	assert.NotNil(err)
+}
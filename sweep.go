@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// SweepTransaction is a self spend of up to MAX_INPUTS inputs to a single
+// output of Amount at the change address. The inputs are the Inputs, whose
+// Proofs are known, and the outputs of the Sources, the indices of earlier
+// transactions of the plan. The transactions of round 0 have no Sources.
+type SweepTransaction struct {
+	Round   int
+	Inputs  []*UTXO
+	Proofs  *Proofs
+	Sources []int
+	Amount  uint64
+	Fee     uint64
+}
+
+// SweepPlan is the sequence of consolidation rounds until a single output is
+// left, Fee is the total fee of all the transactions and Value is the amount
+// of the final output at the change address. The Skipped utxos could not be
+// merged into any transaction of the plan.
+type SweepPlan struct {
+	Transactions []*SweepTransaction
+	Skipped      []*UTXO
+	Rounds       int
+	Fee          uint64
+	Value        uint64
+}
+
+// sweepOutput is a utxo, or the output of the transaction source of the plan
+type sweepOutput struct {
+	utxo   *UTXO
+	source int
+	amount uint64
+}
+
+// PlanSweep groups the utxos of the token, largest first, into transactions of
+// MAX_INPUTS inputs each, with the proofs of each transaction taken from
+// proofs. The outputs of each round, and the utxos left over by it, are grouped
+// the same way in the next round, until one output is left. A transaction must
+// leave an output of at least the MinimumChange of the FeePolicy of the token.
+func PlanSweep(utxos []*UTXO, proofs *Proofs, fee uint64, tokenID uint) (*SweepPlan, error) {
+	if len(utxos) < 2 {
+		return nil, errors.New("nothing to sweep")
+	}
+	if proofs == nil {
+		return nil, errors.New("nil proofs")
+	}
+	if len(proofs.Ring) != len(proofs.Rings) {
+		return nil, fmt.Errorf("Invalid proofs ring len %d, rings len %d", len(proofs.Ring), len(proofs.Rings))
+	}
+	policy, err := DefaultFeePolicy(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	minimum := fee + policy.MinimumChange
+	if minimum < fee {
+		return nil, fmt.Errorf("invalid fee %d", fee)
+	}
+	proofSet := make(map[string]int)
+	for i, item := range proofs.Ring {
+		proofSet[item.TxOut.PublicKey] = i
+	}
+
+	pending := make([]*sweepOutput, len(utxos))
+	for i, utxo := range utxos {
+		pending[i] = &sweepOutput{utxo: utxo, source: -1, amount: utxo.Amount}
+	}
+	plan := &SweepPlan{}
+	for round := 0; len(pending) > 1; round++ {
+		slices.SortStableFunc(pending, func(a, b *sweepOutput) int {
+			switch {
+			case a.amount > b.amount:
+				return -1
+			case a.amount < b.amount:
+				return 1
+			}
+			return 0
+		})
+
+		var next []*sweepOutput
+		for start := 0; start < len(pending); start += MAX_INPUTS {
+			batch := pending[start:min(start+MAX_INPUTS, len(pending))]
+			var total uint64
+			for _, out := range batch {
+				if total+out.amount < total {
+					return nil, fmt.Errorf("sweep round %d total overflow", round)
+				}
+				total += out.amount
+			}
+			if len(batch) < 2 || total < minimum {
+				next = append(next, batch...)
+				continue
+			}
+
+			tx := &SweepTransaction{Round: round, Amount: total - fee, Fee: fee}
+			for _, out := range batch {
+				if out.utxo == nil {
+					tx.Sources = append(tx.Sources, out.source)
+					continue
+				}
+				txOut, err := utxoTxOut(out.utxo)
+				if err != nil {
+					return nil, err
+				}
+				i, found := proofSet[txOut.PublicKey]
+				if !found {
+					return nil, fmt.Errorf("proof of UTXO %s not found", txOut.PublicKey)
+				}
+				if tx.Proofs == nil {
+					tx.Proofs = &Proofs{}
+				}
+				tx.Inputs = append(tx.Inputs, out.utxo)
+				tx.Proofs.Ring = append(tx.Proofs.Ring, proofs.Ring[i])
+				tx.Proofs.Rings = append(tx.Proofs.Rings, proofs.Rings[i])
+			}
+			plan.Transactions = append(plan.Transactions, tx)
+			plan.Fee += tx.Fee
+			plan.Rounds = round + 1
+			next = append(next, &sweepOutput{source: len(plan.Transactions) - 1, amount: tx.Amount})
+		}
+		if len(next) == len(pending) {
+			break
+		}
+		pending = next
+	}
+
+	var outputs []*sweepOutput
+	for _, out := range pending {
+		if out.utxo != nil {
+			plan.Skipped = append(plan.Skipped, out.utxo)
+		} else {
+			outputs = append(outputs, out)
+		}
+	}
+	switch len(outputs) {
+	case 0:
+		return nil, errors.New("nothing to sweep")
+	case 1:
+		plan.Value = outputs[0].amount
+		return plan, nil
+	default:
+		return nil, fmt.Errorf("sweep left %d outputs below the minimum %d", len(outputs), minimum)
+	}
+}
+
+// Build signs the transactions of round 0 of the plan, paying to changeStr.
+// The later rounds spend the outputs of the previous round, whose proofs are
+// not known before they are in the ledger, so the caller submits the
+// transactions built, waits for their outputs, then plans the sweep again
+// with the new utxos and Builds its round 0, until the plan has one round.
+func (plan *SweepPlan) Build(ctx context.Context, tombstone uint64, tokenID, version uint, changeStr string, trust *FogTrustConfig, fetcher FogReportFetcher) ([]*Output, error) {
+	var outputs []*Output
+	for i, tx := range plan.Transactions {
+		if tx.Round > 0 {
+			continue
+		}
+		outlays := []*PaymentOutlay{{Address: changeStr, Amount: tx.Amount}}
+		output, err := TransactionBuilderBuildOutlays(ctx, tx.Inputs, tx.Proofs, outlays, tx.Fee, tombstone, tokenID, version, changeStr, trust, fetcher)
+		if err != nil {
+			return nil, fmt.Errorf("sweep transaction %d: %v", i, err)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
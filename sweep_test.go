@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanSweep(t *testing.T) {
+	assert := assert.New(t)
+
+	utxos, proofs := newTestSweepUTXOs(assert, 2*MAX_INPUTS+1)
+	plan, err := PlanSweep(utxos, proofs, MOB_MINIMUM_FEE, 0)
+	assert.Nil(err)
+	assert.Len(plan.Transactions, 3)
+	assert.Empty(plan.Skipped)
+	assert.Equal(2, plan.Rounds)
+	assert.Equal(uint64(3*MOB_MINIMUM_FEE), plan.Fee)
+
+	var total uint64
+	for _, tx := range plan.Transactions[:2] {
+		assert.Equal(0, tx.Round)
+		assert.Len(tx.Inputs, MAX_INPUTS)
+		assert.Len(tx.Proofs.Ring, MAX_INPUTS)
+		for i, utxo := range tx.Inputs {
+			txOut, err := utxoTxOut(utxo)
+			assert.Nil(err)
+			assert.Equal(txOut.PublicKey, tx.Proofs.Ring[i].TxOut.PublicKey)
+			total += utxo.Amount
+		}
+	}
+	// the utxo left over by round 0 is merged in round 1
+	last := plan.Transactions[2]
+	assert.Equal(1, last.Round)
+	assert.Equal([]int{0, 1}, last.Sources)
+	assert.Len(last.Inputs, 1)
+	assert.Equal(uint64(MILLIMOB_TO_PICOMOB), last.Inputs[0].Amount)
+	assert.Equal(proofs.Ring[0], last.Proofs.Ring[0])
+	total += last.Inputs[0].Amount
+	assert.Equal(plan.Transactions[0].Amount+plan.Transactions[1].Amount+MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE, last.Amount)
+	assert.Equal(total-plan.Fee, plan.Value)
+	assert.Equal(last.Amount, plan.Value)
+
+	_, err = PlanSweep(utxos, &Proofs{Ring: proofs.Ring[1:], Rings: proofs.Rings[1:]}, MOB_MINIMUM_FEE, 0)
+	assert.ErrorContains(err, "not found")
+	_, err = PlanSweep(utxos[:1], proofs, MOB_MINIMUM_FEE, 0)
+	assert.NotNil(err)
+	_, err = PlanSweep(utxos, nil, MOB_MINIMUM_FEE, 0)
+	assert.NotNil(err)
+	_, err = PlanSweep(utxos, &Proofs{Ring: proofs.Ring, Rings: proofs.Rings[:1]}, MOB_MINIMUM_FEE, 0)
+	assert.ErrorContains(err, fmt.Sprintf("ring len %d, rings len 1", len(proofs.Ring)))
+	_, err = PlanSweep(utxos, proofs, MOB_MINIMUM_FEE, 9)
+	assert.ErrorContains(err, "no default fee policy for token 9")
+
+	// the dust batch worth less than the fee and the minimum change is
+	// carried into the next round
+	utxos, proofs = newTestSweepUTXOs(assert, MAX_INPUTS+2)
+	utxos[0].Amount, utxos[1].Amount = 10, 20
+	plan, err = PlanSweep(utxos, proofs, MOB_MINIMUM_FEE, 0)
+	assert.Nil(err)
+	assert.Len(plan.Transactions, 2)
+	assert.Empty(plan.Skipped)
+	assert.Len(plan.Transactions[0].Inputs, MAX_INPUTS)
+	assert.Equal([]int{0}, plan.Transactions[1].Sources)
+	assert.Len(plan.Transactions[1].Inputs, 2)
+	total = 0
+	for _, utxo := range utxos {
+		total += utxo.Amount
+	}
+	assert.Equal(total-2*MOB_MINIMUM_FEE, plan.Value)
+
+	// the utxos never worth a transaction are skipped
+	_, err = PlanSweep(utxos[:2], proofs, MOB_MINIMUM_FEE, 0)
+	assert.ErrorContains(err, "nothing to sweep")
+	utxos, proofs = newTestSweepUTXOs(assert, MAX_INPUTS+1)
+	for _, utxo := range utxos {
+		utxo.Amount = (MOB_MINIMUM_FEE + MILLIMOB_TO_PICOMOB) / MAX_INPUTS
+	}
+	utxos[0].Amount = 10
+	plan, err = PlanSweep(utxos, proofs, MOB_MINIMUM_FEE, 0)
+	assert.Nil(err)
+	assert.Len(plan.Transactions, 1)
+	assert.Len(plan.Transactions[0].Inputs, MAX_INPUTS)
+	assert.Equal(uint64(MILLIMOB_TO_PICOMOB), plan.Value)
+	assert.Len(plan.Skipped, 1)
+	assert.Equal(uint64(10), plan.Skipped[0].Amount)
+
+	utxos, proofs = newTestSweepUTXOs(assert, 300)
+	plan, err = PlanSweep(utxos, proofs, MOB_MINIMUM_FEE, 0)
+	assert.Nil(err)
+	assert.Equal(3, plan.Rounds)
+	assert.Len(plan.Transactions, 19+2+1)
+	assert.Empty(plan.Skipped)
+	spent := make(map[int]bool)
+	total = 0
+	for i, tx := range plan.Transactions {
+		for _, utxo := range tx.Inputs {
+			total += utxo.Amount
+		}
+		for _, source := range tx.Sources {
+			assert.Less(source, i)
+			assert.Less(plan.Transactions[source].Round, tx.Round)
+			assert.False(spent[source])
+			spent[source] = true
+		}
+	}
+	assert.Len(spent, len(plan.Transactions)-1)
+	assert.Equal(uint64(len(plan.Transactions))*MOB_MINIMUM_FEE, plan.Fee)
+	assert.Equal(total-plan.Fee, plan.Value)
+}
+
+func TestBuildRingElements(t *testing.T) {
+	assert := assert.New(t)
+
+	utxos, proofs := newTestSweepUTXOs(assert, 2)
+	_, err := BuildRingElements(utxos, nil)
+	assert.ErrorContains(err, "Invalid proofs nil")
+	_, err = BuildRingElements(utxos, &Proofs{Ring: proofs.Ring, Rings: proofs.Rings[:1]})
+	assert.ErrorContains(err, "ring len 2, rings len 1")
+}
+
+func newTestSweepUTXOs(assert *assert.Assertions, n int) ([]*UTXO, *Proofs) {
+	var utxos []*UTXO
+	proofs := &Proofs{}
+	for i := 0; i < n; i++ {
+		txOut := &TxOut{PublicKey: fmt.Sprintf("%064x", i)}
+		data, err := json.Marshal(txOut)
+		assert.Nil(err)
+		utxos = append(utxos, &UTXO{
+			Amount:       uint64(i+1) * MILLIMOB_TO_PICOMOB,
+			ScriptPubKey: hex.EncodeToString(data),
+		})
+		item := &TxOutWithProof{TxOut: txOut}
+		proofs.Ring = append(proofs.Ring, item)
+		proofs.Rings = append(proofs.Rings, []*TxOutWithProof{item})
+	}
+	return utxos, proofs
+}
@@ -1,13 +1,15 @@
 package api
 
 import (
-	"encoding/hex"
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"unsafe"
 
 	account "github.com/MixinNetwork/mobilecoin-account"
 	"github.com/MixinNetwork/mobilecoin-account/types"
+	"github.com/bwesterb/go-ristretto"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -28,317 +30,378 @@ type TxC struct {
 	TxOutChange        *types.TxOut
 	ShareSecretChange  []byte
 	ConfirmationChange []byte
+	Outputs            []*TxOutC
 }
 
-var myenclaves = []string{
-	// "248356aa0d3431abc45da1773cfd6191a4f2989a4a99da31f450bd7c461e312b", //  v5.0.0 testnet
-	"7d10f5e72cacc87a6027b2be42ed4a74a6370a03c3476be754933eb18c404b0b", // v5.0.0
-	"a8af815564569aae3558d8e4e4be14d1bcec896623166a10494b4eaea3e1c48c", // v4.0.0
-	"3370f131b41e5a49ed97c4188f7a976461ac6127f8d222a37929ac46b46d560e", // v3.0.0
-	"3e9bf61f3191add7b054f0e591b62f832854606f6594fd63faef1e2aedec4021", // lower than v3.0.0
+type TxOutC struct {
+	TxOut        *types.TxOut
+	SharedSecret []byte
+	Confirmation []byte
+}
+
+type OutlayC struct {
+	Amount    uint64
+	Recipient *account.PublicAddress
 }
 
 func MCTransactionBuilderCreateC(inputCs []*InputC, amount, changeAmount, fee, tombstone, memo uint64, tokenID, version uint, recipient, change *account.PublicAddress) (*TxC, error) {
-	var errors string
-	for _, enclave := range myenclaves {
-		txC, err := MCTransactionBuilderCreateCWithEnclave(inputCs, amount, changeAmount, fee, tombstone, memo, tokenID, version, recipient, change, enclave)
-		if err != nil {
-			errors += fmt.Sprintf("MCTransactionBuilderCreateCWithEnclave enclave: %s, error: %v \n", enclave, err)
-			continue
-		}
-		return txC, nil
+	outlays := []*OutlayC{{Amount: amount, Recipient: recipient}}
+	return MCTransactionBuilderCreateOutlaysC(context.Background(), inputCs, outlays, changeAmount, fee, tombstone, memo, tokenID, version, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+}
+
+// MCTransactionBuilderCreateOutlaysC builds the transaction with the fog reports
+// of the recipients attested by the enclaves and signers trusted by trust.
+func MCTransactionBuilderCreateOutlaysC(ctx context.Context, inputCs []*InputC, outlays []*OutlayC, changeAmount, fee, tombstone, memo uint64, tokenID, version uint, change *account.PublicAddress, trust *FogTrustConfig, fetcher FogReportFetcher) (*TxC, error) {
+	verifier, err := outlaysVerifier(outlays, trust)
+	if err != nil {
+		return nil, err
 	}
-	destination, _ := recipient.B58Code()
-	return nil, fmt.Errorf("recipient %s, errors %s", destination, errors)
+	return MCTransactionBuilderCreateOutlaysCWithVerifier(ctx, inputCs, outlays, changeAmount, fee, tombstone, memo, tokenID, version, change, verifier, fetcher)
 }
 
-// mc_transaction_builder_create
-func MCTransactionBuilderCreateCWithEnclave(inputCs []*InputC, amount, changeAmount, fee, tombstone, memo uint64, tokenID, version uint, recipient, change *account.PublicAddress, enclave string) (*TxC, error) {
-	var fog_resolver *C.McFogResolver
+func MCTransactionBuilderCreateCWithEnclave(ctx context.Context, inputCs []*InputC, amount, changeAmount, fee, tombstone, memo uint64, tokenID, version uint, recipient, change *account.PublicAddress, enclave string, fetcher FogReportFetcher) (*TxC, error) {
+	outlays := []*OutlayC{{Amount: amount, Recipient: recipient}}
+	return MCTransactionBuilderCreateOutlaysCWithEnclave(ctx, inputCs, outlays, changeAmount, fee, tombstone, memo, tokenID, version, change, enclave, fetcher)
+}
 
-	if recipient != nil && recipient.FogReportUrl != "" {
-		mr_enclave_hex, err := fetchValidFogEnclave(recipient.FogReportUrl, enclave)
-		if err != nil {
-			return nil, err
-		}
-		// Construct a verifier object that is used to verify the report's attestation
-		mr_enclave_bytes, err := hex.DecodeString(mr_enclave_hex)
+// MCTransactionBuilderCreateOutlaysCWithEnclave builds the transaction with a
+// single enclave, the fog hosts and advisories are from DefaultFogTrustConfig.
+// The fog reports of the recipients are provided by fetcher, nil for
+// DefaultFogReportFetcher.
+func MCTransactionBuilderCreateOutlaysCWithEnclave(ctx context.Context, inputCs []*InputC, outlays []*OutlayC, changeAmount, fee, tombstone, memo uint64, tokenID, version uint, change *account.PublicAddress, enclave string, fetcher FogReportFetcher) (*TxC, error) {
+	var verifier *FogVerifier
+	fogReportUrls := outlaysFogReportUrls(outlayRecipients(outlays))
+	if len(fogReportUrls) > 0 {
+		var err error
+		verifier, err = DefaultFogTrustConfig.EnclaveVerifier(enclave, fogReportUrls)
 		if err != nil {
 			return nil, err
 		}
+	}
+	return MCTransactionBuilderCreateOutlaysCWithVerifier(ctx, inputCs, outlays, changeAmount, fee, tombstone, memo, tokenID, version, change, verifier, fetcher)
+}
 
-		c_mr_enclave_bytes := C.CBytes(mr_enclave_bytes)
-		defer C.free(c_mr_enclave_bytes)
-
-		c_mr_enclave := C.McBuffer{
-			buffer: (*C.uchar)(c_mr_enclave_bytes),
-			len:    C.ulong(len(mr_enclave_bytes)),
-		}
+// outlaysVerifier is nil when no outlay has a fog report url
+func outlaysVerifier(outlays []*OutlayC, trust *FogTrustConfig) (*FogVerifier, error) {
+	fogReportUrls := outlaysFogReportUrls(outlayRecipients(outlays))
+	if len(fogReportUrls) == 0 {
+		return nil, nil
+	}
+	return trust.Verifier(fogReportUrls)
+}
 
-		mr_enclave_verifier, err := C.mc_mr_enclave_verifier_create(&c_mr_enclave)
-		if err != nil {
-			return nil, err
+// outlaysFogReportUrls lists the distinct fog report urls of the recipients,
+// the nil recipients of the change are skipped
+func outlaysFogReportUrls(recipients []*account.PublicAddress) []string {
+	var fogReportUrls []string
+	for _, recipient := range recipients {
+		if recipient == nil || recipient.FogReportUrl == "" {
+			continue
 		}
-		if mr_enclave_verifier == nil {
-			return nil, errors.New("mc_mr_enclave_verifier_create failed")
+		if slices.Contains(fogReportUrls, recipient.FogReportUrl) {
+			continue
 		}
-		defer C.mc_mr_enclave_verifier_free(mr_enclave_verifier)
+		fogReportUrls = append(fogReportUrls, recipient.FogReportUrl)
+	}
+	return fogReportUrls
+}
 
-		c_advisory_id := C.CString("INTEL-SA-00334")
-		defer C.free(unsafe.Pointer(c_advisory_id))
-		ret, err := C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id)
-		if err != nil {
-			return nil, err
-		}
-		if ret == false {
-			return nil, errors.New("mc_mr_enclave_verifier_allow_hardening_advisory INTEL-SA-00334 failed")
-		}
+func outlayRecipients(outlays []*OutlayC) []*account.PublicAddress {
+	recipients := make([]*account.PublicAddress, len(outlays))
+	for i, outlay := range outlays {
+		recipients[i] = outlay.Recipient
+	}
+	return recipients
+}
 
-		c_advisory_id_00615 := C.CString("INTEL-SA-00615")
-		defer C.free(unsafe.Pointer(c_advisory_id_00615))
-		ret, err = C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id_00615)
-		if err != nil {
-			return nil, err
-		}
-		if ret == false {
-			return nil, errors.New("mc_mr_enclave_verifier_allow_hardening_advisory INTEL-SA-00615 failed")
-		}
+// MCTransactionBuilderCreateOutlaysCWithVerifier attaches memo as the payment
+// request id of the sender memos.
+func MCTransactionBuilderCreateOutlaysCWithVerifier(ctx context.Context, inputCs []*InputC, outlays []*OutlayC, changeAmount, fee, tombstone, memo uint64, tokenID, version uint, change *account.PublicAddress, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	return MCTransactionBuilderCreateOutlaysCWithMemo(ctx, inputCs, outlays, changeAmount, fee, tombstone, tokenID, version, change, PaymentRequestMemoOptions(memo), verifier, fetcher)
+}
 
-		c_advisory_id_00657 := C.CString("INTEL-SA-00657")
-		defer C.free(unsafe.Pointer(c_advisory_id_00657))
-		ret, err = C.mc_mr_enclave_verifier_allow_hardening_advisory(mr_enclave_verifier, c_advisory_id_00657)
-		if err != nil {
-			return nil, err
-		}
-		if ret == false {
-			return nil, errors.New("mc_mr_enclave_verifier_allow_hardening_advisory INTEL-SA-00657 failed")
-		}
+// mc_transaction_builder_create, the fog report of each fog report url is
+// fetched once and must be attested by the enclaves or signers of its own fog
+// host in verifier.
+// The memos are written by the memo builder selected by memo.
+func MCTransactionBuilderCreateOutlaysCWithMemo(ctx context.Context, inputCs []*InputC, outlays []*OutlayC, changeAmount, fee, tombstone uint64, tokenID, version uint, change *account.PublicAddress, memo *MemoOptions, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	if len(inputCs) == 0 {
+		return nil, errors.New("no inputs")
+	}
+	if len(outlays) == 0 {
+		return nil, errors.New("no outlays")
+	}
+	err := memo.validate()
+	if err != nil {
+		return nil, err
+	}
 
-		mc_verifier, err := C.mc_verifier_create()
+	var fog_resolver *C.McFogResolver
+	recipients := outlayRecipients(outlays)
+	if len(outlaysFogReportUrls(recipients)) > 0 {
+		resolver, err := newFogResolverWithReportsC(ctx, recipients, verifier, fetcher)
 		if err != nil {
 			return nil, err
 		}
-		defer C.mc_verifier_free(mc_verifier)
+		defer resolver.free()
+		fog_resolver = resolver.resolver
+	}
 
-		ret, err = C.mc_verifier_add_mr_enclave(mc_verifier, mr_enclave_verifier)
-		if err != nil {
-			return nil, err
-		}
-		if ret == false {
-			return nil, errors.New("mc_verifier_add_mr_enclave failed")
-		}
+	account_key, free_account_key := newAccountKeyC(inputCs[0].ViewPrivate, inputCs[0].SpendPrivate)
+	defer free_account_key()
 
-		fog_resolver, err = C.mc_fog_resolver_create(mc_verifier)
+	memo_builder, err := newMemoBuilderC(memo, account_key)
+	if err != nil {
+		return nil, err
+	}
+	defer C.mc_memo_builder_free(memo_builder)
+
+	var build_error *C.McError
+	transaction_builder, err := C.mc_transaction_builder_create(C.uint64_t(fee), C.uint64_t(tokenID), C.uint64_t(tombstone), fog_resolver, memo_builder, C.uint32_t(version), &build_error)
+	if err != nil {
+		return nil, err
+	}
+	if transaction_builder == nil {
+		err = fmt.Errorf("mc_transaction_builder_create failed: [%d] %s", build_error.error_code, C.GoString(build_error.error_description))
+		C.mc_error_free(build_error)
+		return nil, err
+	}
+	defer C.mc_transaction_builder_free(transaction_builder)
+
+	// add input
+	for _, input := range inputCs {
+		err = addInputC(transaction_builder, input)
 		if err != nil {
 			return nil, err
 		}
-		defer C.mc_fog_resolver_free(fog_resolver)
+	}
 
-		report, err := GetFogReportResponse(recipient.FogReportUrl)
+	// mc_transaction_builder_add_output
+	outputs := make([]*TxOutC, len(outlays))
+	for i, outlay := range outlays {
+		output, err := addOutputC(transaction_builder, outlay.Amount, outlay.Recipient)
 		if err != nil {
 			return nil, err
 		}
+		outputs[i] = output
+	}
 
-		// Convert the report back to protobuf bytes so that it could be handed to libmobilecoin
-		reportBytes, err := proto.Marshal(report)
+	// mc_transaction_builder_add_output for change
+	changeOutput := &TxOutC{
+		TxOut:        &types.TxOut{},
+		SharedSecret: make([]byte, 32),
+		Confirmation: make([]byte, 32),
+	}
+	if changeAmount > 0 {
+		changeOutput, err = addOutputC(transaction_builder, changeAmount, change)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("change: %v", err)
 		}
+	}
+
+	var rng_callback *C.McRngCallback
+	var out_error *C.McError
+	mcData, err := C.mc_transaction_builder_build(transaction_builder, rng_callback, &out_error)
+	if err != nil {
+		return nil, err
+	}
+	if out_error != nil {
+		err = fmt.Errorf("mc_transaction_builder_build failed: [%d] %s", out_error.error_code, C.GoString(out_error.error_description))
+		C.mc_error_free(out_error)
+		return nil, err
+	}
+	defer C.mc_data_free(mcData)
 
-		// Add the report bytes to the resolver
-		c_report_buf_bytes := C.CBytes(reportBytes)
-		defer C.free(c_report_buf_bytes)
+	return &TxC{
+		Tx:                 mcDataBytes(mcData),
+		TxOut:              outputs[0].TxOut,
+		ShareSecretOut:     outputs[0].SharedSecret,
+		ConfirmationOut:    outputs[0].Confirmation,
+		TxOutChange:        changeOutput.TxOut,
+		ShareSecretChange:  changeOutput.SharedSecret,
+		ConfirmationChange: changeOutput.Confirmation,
+		Outputs:            outputs,
+	}, nil
+}
 
-		report_buf := C.McBuffer{
-			buffer: (*C.uchar)(c_report_buf_bytes),
-			len:    C.ulong(len(reportBytes)),
-		}
+// MixedOutlayC pays Amount of TokenID to Recipient, a nil Recipient is the
+// change output to the account key of the first input.
+type MixedOutlayC struct {
+	Amount    uint64
+	TokenID   uint64
+	Recipient *account.PublicAddress
+}
 
-		c_address := C.CString(recipient.FogReportUrl)
-		defer C.free(unsafe.Pointer(c_address))
+func mixedOutlayRecipients(outlays []*MixedOutlayC) []*account.PublicAddress {
+	recipients := make([]*account.PublicAddress, len(outlays))
+	for i, outlay := range outlays {
+		recipients[i] = outlay.Recipient
+	}
+	return recipients
+}
 
-		var mc_error *C.McError
-		ret, err = C.mc_fog_resolver_add_report_response(
-			fog_resolver,
-			c_address,
-			&report_buf,
-			&mc_error,
-		)
+// MCTransactionBuilderCreateMixedC builds a transaction with the fee in
+// feeTokenID, the inputs and the outlays may be of any token as long as each
+// token is balanced.
+func MCTransactionBuilderCreateMixedC(ctx context.Context, inputCs []*InputC, outlays []*MixedOutlayC, fee, tombstone uint64, feeTokenID, version uint, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	if len(outlays) == 0 {
+		return nil, errors.New("no outlays")
+	}
+	return buildMixedC(ctx, inputCs, nil, outlays, fee, tombstone, feeTokenID, version, verifier, fetcher)
+}
+
+// buildMixedC adds the inputs, the optional presigned input sci and the
+// outlays with their own token ids
+func buildMixedC(ctx context.Context, inputCs []*InputC, sci []byte, outlays []*MixedOutlayC, fee, tombstone uint64, feeTokenID, version uint, verifier *FogVerifier, fetcher FogReportFetcher) (*TxC, error) {
+	if len(inputCs) == 0 {
+		return nil, errors.New("no inputs")
+	}
+
+	var fog_resolver *C.McFogResolver
+	recipients := mixedOutlayRecipients(outlays)
+	if len(outlaysFogReportUrls(recipients)) > 0 {
+		resolver, err := newFogResolverWithReportsC(ctx, recipients, verifier, fetcher)
 		if err != nil {
 			return nil, err
 		}
-		if ret == false {
-			if mc_error == nil {
-				return nil, errors.New("mc_fog_resolver_add_report_response failed")
-			} else {
-				err = fmt.Errorf("mc_fog_resolver_add_report_response failed: [%d] %s", mc_error.error_code, C.GoString(mc_error.error_description))
-				C.mc_error_free(mc_error)
-				return nil, err
-			}
-		}
+		defer resolver.free()
+		fog_resolver = resolver.resolver
 	}
 
-	random := inputCs[0]
-	random_view_private_buf := random.ViewPrivate.Bytes()
-	random_view_private_bytes := C.CBytes(random_view_private_buf)
-	defer C.free(random_view_private_bytes)
-	random_view_private_key := &C.McBuffer{
-		buffer: (*C.uint8_t)(random_view_private_bytes),
-		len:    C.size_t(len(random_view_private_buf)),
-	}
-	random_spend_private_buf := random.SpendPrivate.Bytes()
-	random_spend_private_bytes := C.CBytes(random_spend_private_buf)
-	defer C.free(random_spend_private_bytes)
-	random_spend_private_key := &C.McBuffer{
-		buffer: (*C.uint8_t)(random_spend_private_bytes),
-		len:    C.size_t(len(random_spend_private_buf)),
-	}
-	var random_fog_info *C.McAccountKeyFogInfo
-	account_key := (*C.McAccountKey)(C.malloc(C.sizeof_McAccountKey))
-	defer C.free(unsafe.Pointer(account_key))
-	account_key.view_private_key = random_view_private_key
-	account_key.spend_private_key = random_spend_private_key
-	account_key.fog_info = random_fog_info
+	account_key, free_account_key := newAccountKeyC(inputCs[0].ViewPrivate, inputCs[0].SpendPrivate)
+	defer free_account_key()
 
-	memo_builder, err := C.mc_memo_builder_sender_payment_request_and_destination_create(C.uint64_t(memo), account_key)
+	memo_builder, err := C.mc_memo_builder_default_create()
 	if err != nil {
 		return nil, err
 	}
 	defer C.mc_memo_builder_free(memo_builder)
 
 	var build_error *C.McError
-	transaction_builder, err := C.mc_transaction_builder_create(C.uint64_t(fee), C.uint64_t(tokenID), C.uint64_t(tombstone), fog_resolver, memo_builder, C.uint32_t(version), &build_error)
+	transaction_builder, err := C.mc_transaction_builder_create(C.uint64_t(fee), C.uint64_t(feeTokenID), C.uint64_t(tombstone), fog_resolver, memo_builder, C.uint32_t(version), &build_error)
 	if err != nil {
 		return nil, err
 	}
 	if transaction_builder == nil {
-		err = fmt.Errorf("mc_transaction_builder_create failed: [%d] %s", build_error.error_code, C.GoString(build_error.error_description))
-		C.mc_error_free(build_error)
-		return nil, err
+		return nil, mcError("mc_transaction_builder_create", build_error)
 	}
 	defer C.mc_transaction_builder_free(transaction_builder)
 
-	// add input
 	for _, input := range inputCs {
-		view_private_input_buf := input.ViewPrivate.Bytes()
-		view_private_key_bytes := C.CBytes(view_private_input_buf)
-		defer C.free(view_private_key_bytes)
-		view_private_key := &C.McBuffer{
-			buffer: (*C.uint8_t)(view_private_key_bytes),
-			len:    C.size_t(len(view_private_input_buf)),
-		}
-
-		subaddress_spend_private_buf := input.SubAddressSpendPrivate.Bytes()
-		subaddress_spend_private_key_bytes := C.CBytes(subaddress_spend_private_buf)
-		defer C.free(subaddress_spend_private_key_bytes)
-		subaddress_spend_private_key := &C.McBuffer{
-			buffer: (*C.uint8_t)(subaddress_spend_private_key_bytes),
-			len:    C.size_t(len(subaddress_spend_private_buf)),
+		err = addInputC(transaction_builder, input)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		ring, err := C.mc_transaction_builder_ring_create()
+	if len(sci) > 0 {
+		presigned_input, free_presigned_input := newBufferC(sci)
+		defer free_presigned_input()
+		var presigned_error *C.McError
+		b, err := C.mc_transaction_builder_add_presigned_input(transaction_builder, presigned_input, &presigned_error)
 		if err != nil {
 			return nil, err
+		} else if !b {
+			return nil, mcError("mc_transaction_builder_add_presigned_input", presigned_error)
 		}
-		defer C.mc_transaction_builder_ring_free(ring)
-
-		for _, r := range input.TxOutWithProofCs {
-			tx_out_buf, err := proto.Marshal(r.TxOut)
-			if err != nil {
-				return nil, err
-			}
-			tx_out_proto_bytes := C.CBytes(tx_out_buf)
-			defer C.free(tx_out_proto_bytes)
-			tx_out_proto := &C.McBuffer{
-				buffer: (*C.uint8_t)(tx_out_proto_bytes),
-				len:    C.size_t(len(tx_out_buf)),
-			}
-			membership_proof_buf, err := proto.Marshal(r.TxOutMembershipProof)
-			if err != nil {
-				return nil, err
-			}
-			membership_proof_proto_bytes := C.CBytes(membership_proof_buf)
-			defer C.free(membership_proof_proto_bytes)
-			membership_proof_proto := &C.McBuffer{
-				buffer: (*C.uint8_t)(membership_proof_proto_bytes),
-				len:    C.size_t(len(membership_proof_buf)),
-			}
-			b, err := C.mc_transaction_builder_ring_add_element(ring, tx_out_proto, membership_proof_proto)
-			if err != nil {
-				return nil, err
-			} else if !b {
-				return nil, errors.New("mc_transaction_builder_ring_add_element failure")
-			}
-		}
+	}
 
-		var out_error *C.McError
-		b, err := C.mc_transaction_builder_add_input(transaction_builder, view_private_key, subaddress_spend_private_key, C.size_t(input.RealIndex), ring, &out_error)
+	outputs := make([]*TxOutC, len(outlays))
+	for i, outlay := range outlays {
+		output, err := addOutputMixedC(transaction_builder, account_key, outlay)
 		if err != nil {
 			return nil, err
-		} else if !b {
-			if out_error == nil {
-				return nil, fmt.Errorf("mc_transaction_builder_add_input failure")
-			} else {
-				err = fmt.Errorf("mc_transaction_builder_add_input failed: [%d] %s", out_error.error_code, C.GoString(out_error.error_description))
-				C.mc_error_free(out_error)
-				return nil, err
-			}
 		}
+		outputs[i] = output
 	}
 
-	// mc_transaction_builder_add_output
-	view_public_key_buf := account.HexToBytes(recipient.ViewPublicKey)
-	view_public_key_bytes := C.CBytes(view_public_key_buf)
-	defer C.free(view_public_key_bytes)
-	view_public := &C.McBuffer{
-		buffer: (*C.uint8_t)(view_public_key_bytes),
-		len:    C.size_t(len(view_public_key_buf)),
+	var rng_callback *C.McRngCallback
+	var out_error *C.McError
+	mcData, err := C.mc_transaction_builder_build(transaction_builder, rng_callback, &out_error)
+	if err != nil {
+		return nil, err
 	}
+	if mcData == nil {
+		return nil, mcError("mc_transaction_builder_build", out_error)
+	}
+	defer C.mc_data_free(mcData)
+
+	return &TxC{
+		Tx:      mcDataBytes(mcData),
+		Outputs: outputs,
+	}, nil
+}
 
-	spend_public_key_buf := account.HexToBytes(recipient.SpendPublicKey)
-	spend_public_key_bytes := C.CBytes(spend_public_key_buf)
-	defer C.free(spend_public_key_bytes)
-	spend_public := &C.McBuffer{
-		buffer: (*C.uint8_t)(spend_public_key_bytes),
-		len:    C.size_t(len(spend_public_key_buf)),
+// mc_transaction_builder_add_output_mixed, or the change output mixed to
+// account_key for a nil recipient
+func addOutputMixedC(transaction_builder *C.McTransactionBuilder, account_key *C.McAccountKey, outlay *MixedOutlayC) (*TxOutC, error) {
+	out_tx_out_shared_secret, free_shared_secret := newMutableBufferC(32)
+	defer free_shared_secret()
+	out_tx_out_confirmation_number, free_confirmation_number := newMutableBufferC(32)
+	defer free_confirmation_number()
+
+	var rng_callback *C.McRngCallback
+	var out_error *C.McError
+	var mcDataOut *C.McData
+	var err error
+	if outlay.Recipient == nil {
+		mcDataOut, err = C.mc_transaction_builder_add_change_output_mixed(account_key, transaction_builder, C.uint64_t(outlay.Amount), C.uint64_t(outlay.TokenID), rng_callback, out_tx_out_confirmation_number, out_tx_out_shared_secret, &out_error)
+	} else {
+		recipient_address, free_recipient_address := newPublicAddressC(outlay.Recipient)
+		defer free_recipient_address()
+		mcDataOut, err = C.mc_transaction_builder_add_output_mixed(transaction_builder, C.uint64_t(outlay.Amount), C.uint64_t(outlay.TokenID), recipient_address, rng_callback, out_tx_out_confirmation_number, out_tx_out_shared_secret, &out_error)
+	}
+	if err != nil {
+		return nil, err
 	}
+	if mcDataOut == nil {
+		return nil, mcError("mc_transaction_builder_add_output_mixed", out_error)
+	}
+	defer C.mc_data_free(mcDataOut)
 
-	report_url_recipient_str := C.CString(recipient.FogReportUrl)
-	defer C.free(unsafe.Pointer(report_url_recipient_str))
-	report_id_recipient_str := C.CString(recipient.FogReportId)
-	defer C.free(unsafe.Pointer(report_id_recipient_str))
-	sig_buf := account.HexToBytes(recipient.FogAuthoritySig)
-	sig_bytes := C.CBytes(sig_buf)
-	defer C.free(sig_bytes)
-	authority_sig := &C.McBuffer{
-		buffer: (*C.uint8_t)(sig_bytes),
-		len:    C.size_t(len(sig_buf)),
+	txOut, err := txOutFromMcData(mcDataOut)
+	if err != nil {
+		return nil, err
 	}
-	fog_info := (*C.McPublicAddressFogInfo)(C.malloc(C.sizeof_McPublicAddressFogInfo))
-	defer C.free(unsafe.Pointer(fog_info))
-	fog_info.report_url = (*C.char)(report_url_recipient_str)
-	fog_info.report_id = (*C.char)(report_id_recipient_str)
-	fog_info.authority_sig = authority_sig
-	recipient_address := (*C.McPublicAddress)(C.malloc(C.sizeof_McPublicAddress))
-	defer C.free(unsafe.Pointer(recipient_address))
-	recipient_address.view_public_key = view_public
-	recipient_address.spend_public_key = spend_public
-	recipient_address.fog_info = fog_info
+	return &TxOutC{
+		TxOut:        txOut,
+		SharedSecret: mutableBufferBytes(out_tx_out_shared_secret),
+		Confirmation: mutableBufferBytes(out_tx_out_confirmation_number),
+	}, nil
+}
+
+// mc_transaction_builder_add_input
+func addInputC(transaction_builder *C.McTransactionBuilder, input *InputC) error {
+	view_private_key, free_view_private_key := newBufferC(input.ViewPrivate.Bytes())
+	defer free_view_private_key()
+	subaddress_spend_private_key, free_subaddress_spend_private_key := newBufferC(input.SubAddressSpendPrivate.Bytes())
+	defer free_subaddress_spend_private_key()
 
-	secret_recipient_buf := make([]byte, 32)
-	secret_recipient_bytes := C.CBytes(secret_recipient_buf)
-	defer C.free(secret_recipient_bytes)
-	out_tx_out_shared_secret := &C.McMutableBuffer{
-		buffer: (*C.uint8_t)(secret_recipient_bytes),
-		len:    C.size_t(len(secret_recipient_buf)),
+	ring, err := newRingC(input.TxOutWithProofCs)
+	if err != nil {
+		return err
 	}
-	confirmation_recipient_buf := make([]byte, 32)
-	confirmation_recipient_bytes := C.CBytes(confirmation_recipient_buf)
-	defer C.free(confirmation_recipient_bytes)
-	out_tx_out_confirmation_number := &C.McMutableBuffer{
-		buffer: (*C.uint8_t)(confirmation_recipient_bytes),
-		len:    C.size_t(len(confirmation_recipient_buf)),
+	defer C.mc_transaction_builder_ring_free(ring)
+
+	var out_error *C.McError
+	b, err := C.mc_transaction_builder_add_input(transaction_builder, view_private_key, subaddress_spend_private_key, C.size_t(input.RealIndex), ring, &out_error)
+	if err != nil {
+		return err
+	} else if !b {
+		return mcError("mc_transaction_builder_add_input", out_error)
 	}
+	return nil
+}
+
+// mc_transaction_builder_add_output
+func addOutputC(transaction_builder *C.McTransactionBuilder, amount uint64, recipient *account.PublicAddress) (*TxOutC, error) {
+	recipient_address, free_recipient_address := newPublicAddressC(recipient)
+	defer free_recipient_address()
+
+	out_tx_out_shared_secret, free_shared_secret := newMutableBufferC(32)
+	defer free_shared_secret()
+	out_tx_out_confirmation_number, free_confirmation_number := newMutableBufferC(32)
+	defer free_confirmation_number()
 
 	var rng_callback *C.McRngCallback
 	var out_error *C.McError
@@ -346,146 +409,179 @@ func MCTransactionBuilderCreateCWithEnclave(inputCs []*InputC, amount, changeAmo
 	if err != nil {
 		return nil, err
 	}
-	if out_error != nil {
-		err = fmt.Errorf("mc_transaction_builder_add_output failed: [%d] %s", out_error.error_code, C.GoString(out_error.error_description))
-		C.mc_error_free(out_error)
-		return nil, err
+	if mcDataOut == nil {
+		return nil, mcError("mc_transaction_builder_add_output", out_error)
 	}
-	secret_recipient_buf = C.GoBytes(unsafe.Pointer(out_tx_out_shared_secret.buffer), C.int(len(secret_recipient_buf)))
-	confirmation_recipient_buf = C.GoBytes(unsafe.Pointer(out_tx_out_confirmation_number.buffer), C.int(len(confirmation_recipient_buf)))
-
 	defer C.mc_data_free(mcDataOut)
-	var tx_out_size_bytes *C.McMutableBuffer
-	tx_out_size := C.mc_data_get_bytes(mcDataOut, tx_out_size_bytes)
 
-	tx_out_data_buf := make([]byte, int(tx_out_size))
-	tx_out_data_bytes := C.CBytes(tx_out_data_buf)
-	defer C.free(tx_out_data_bytes)
-	tx_out_data := &C.McMutableBuffer{
-		buffer: (*C.uint8_t)(tx_out_data_bytes),
-		len:    C.size_t(len(tx_out_data_buf)),
+	txOut, err := txOutFromMcData(mcDataOut)
+	if err != nil {
+		return nil, err
 	}
-	tx_out_size = C.mc_data_get_bytes(mcDataOut, tx_out_data)
+	return &TxOutC{
+		TxOut:        txOut,
+		SharedSecret: mutableBufferBytes(out_tx_out_shared_secret),
+		Confirmation: mutableBufferBytes(out_tx_out_confirmation_number),
+	}, nil
+}
+
+func txOutFromMcData(mcData *C.McData) (*types.TxOut, error) {
 	txOut := &types.TxOut{}
-	err = proto.Unmarshal(C.GoBytes(tx_out_data_bytes, C.int(tx_out_size)), txOut)
+	err := proto.Unmarshal(mcDataBytes(mcData), txOut)
 	if err != nil {
 		return nil, err
 	}
+	return txOut, nil
+}
 
-	// mc_transaction_builder_add_output for change
-	secret_change_buf := make([]byte, 32)
-	confirmation_change_buf := make([]byte, 32)
-	txOutChange := &types.TxOut{}
-	if changeAmount > 0 {
-		view_public_key_change_buf := account.HexToBytes(change.ViewPublicKey)
-		view_public_key_change_bytes := C.CBytes(view_public_key_change_buf)
-		defer C.free(view_public_key_change_bytes)
-		view_public_change := &C.McBuffer{
-			buffer: (*C.uint8_t)(view_public_key_change_bytes),
-			len:    C.size_t(len(view_public_key_change_buf)),
-		}
+// mc_data_get_bytes, the first call with a nil buffer gets the size
+func mcDataBytes(mcData *C.McData) []byte {
+	var out_size_bytes *C.McMutableBuffer
+	data_size := C.mc_data_get_bytes(mcData, out_size_bytes)
+	out_data, free_out_data := newMutableBufferC(int(data_size))
+	defer free_out_data()
+	data_size = C.mc_data_get_bytes(mcData, out_data)
+	return C.GoBytes(unsafe.Pointer(out_data.buffer), C.int(data_size))
+}
 
-		spend_public_key_change_buf := account.HexToBytes(change.SpendPublicKey)
-		spend_public_key_change_bytes := C.CBytes(spend_public_key_change_buf)
-		defer C.free(spend_public_key_change_bytes)
-		spend_public_change := &C.McBuffer{
-			buffer: (*C.uint8_t)(spend_public_key_change_bytes),
-			len:    C.size_t(len(spend_public_key_change_buf)),
-		}
+// mcError converts and frees the error of a failed libmobilecoin call
+func mcError(name string, out_error *C.McError) error {
+	if out_error == nil {
+		return fmt.Errorf("%s failure", name)
+	}
+	err := fmt.Errorf("%s failed: [%d] %s", name, out_error.error_code, C.GoString(out_error.error_description))
+	C.mc_error_free(out_error)
+	return err
+}
 
-		report_url_change_str := C.CString(change.FogReportUrl)
-		defer C.free(unsafe.Pointer(report_url_change_str))
-		report_id_change_str := C.CString(change.FogReportId)
-		defer C.free(unsafe.Pointer(report_id_change_str))
-		sig_change_buf := account.HexToBytes(change.FogAuthoritySig)
-		sig_change_bytes := C.CBytes(sig_change_buf)
-		defer C.free(sig_change_bytes)
-		authority_sig_change := &C.McBuffer{
-			buffer: (*C.uint8_t)(sig_change_bytes),
-			len:    C.size_t(len(sig_change_buf)),
-		}
-		fog_info_change := (*C.McPublicAddressFogInfo)(C.malloc(C.sizeof_McPublicAddressFogInfo))
-		defer C.free(unsafe.Pointer(fog_info_change))
-		fog_info_change.report_url = (*C.char)(report_url_change_str)
-		fog_info_change.report_id = (*C.char)(report_id_change_str)
-		fog_info_change.authority_sig = authority_sig_change
-		change_address := (*C.McPublicAddress)(C.malloc(C.sizeof_McPublicAddress))
-		defer C.free(unsafe.Pointer(change_address))
-		change_address.view_public_key = view_public_change
-		change_address.spend_public_key = spend_public_change
-		change_address.fog_info = fog_info_change
-
-		secret_change_bytes := C.CBytes(secret_change_buf)
-		defer C.free(secret_change_bytes)
-		change_tx_out_shared_secret := &C.McMutableBuffer{
-			buffer: (*C.uint8_t)(secret_change_bytes),
-			len:    C.size_t(len(secret_change_buf)),
-		}
-		confirmation_change_bytes := C.CBytes(confirmation_change_buf)
-		defer C.free(confirmation_change_bytes)
-		change_tx_out_confirmation_number := &C.McMutableBuffer{
-			buffer: (*C.uint8_t)(confirmation_change_bytes),
-			len:    C.size_t(len(confirmation_change_buf)),
+// newBufferC copies buf to a McBuffer in C memory, it must be freed by the
+// returned func
+func newBufferC(buf []byte) (*C.McBuffer, func()) {
+	bytes := C.CBytes(buf)
+	buffer := (*C.McBuffer)(C.malloc(C.sizeof_McBuffer))
+	buffer.buffer = (*C.uint8_t)(bytes)
+	buffer.len = C.size_t(len(buf))
+	return buffer, func() {
+		C.free(unsafe.Pointer(buffer))
+		C.free(bytes)
+	}
+}
+
+func newMutableBufferC(size int) (*C.McMutableBuffer, func()) {
+	bytes := C.CBytes(make([]byte, size))
+	buffer := (*C.McMutableBuffer)(C.malloc(C.sizeof_McMutableBuffer))
+	buffer.buffer = (*C.uint8_t)(bytes)
+	buffer.len = C.size_t(size)
+	return buffer, func() {
+		C.free(unsafe.Pointer(buffer))
+		C.free(bytes)
+	}
+}
+
+func mutableBufferBytes(buffer *C.McMutableBuffer) []byte {
+	return C.GoBytes(unsafe.Pointer(buffer.buffer), C.int(buffer.len))
+}
+
+// newAccountKeyC is the account key without fog info, the change and the
+// memos are computed from it
+func newAccountKeyC(viewPrivate, spendPrivate *ristretto.Scalar) (*C.McAccountKey, func()) {
+	return newFogAccountKeyC(viewPrivate, spendPrivate, nil)
+}
+
+// newFogAccountKeyC with the fog info of the account, nil for no fog
+func newFogAccountKeyC(viewPrivate, spendPrivate *ristretto.Scalar, fog *AccountFogInfo) (*C.McAccountKey, func()) {
+	view_private_key, free_view_private_key := newBufferC(viewPrivate.Bytes())
+	spend_private_key, free_spend_private_key := newBufferC(spendPrivate.Bytes())
+	account_key := (*C.McAccountKey)(C.malloc(C.sizeof_McAccountKey))
+	account_key.view_private_key = view_private_key
+	account_key.spend_private_key = spend_private_key
+	account_key.fog_info = nil
+	if fog == nil {
+		return account_key, func() {
+			C.free(unsafe.Pointer(account_key))
+			free_spend_private_key()
+			free_view_private_key()
 		}
+	}
+
+	authority_fingerprint, free_authority_fingerprint := newBufferC(fog.AuthoritySpki)
+	report_url_str := C.CString(fog.ReportUrl)
+	report_id_str := C.CString(fog.ReportId)
+	fog_info := (*C.McAccountKeyFogInfo)(C.malloc(C.sizeof_McAccountKeyFogInfo))
+	fog_info.report_url = report_url_str
+	fog_info.report_id = report_id_str
+	fog_info.authority_fingerprint = authority_fingerprint
+	account_key.fog_info = fog_info
+	return account_key, func() {
+		C.free(unsafe.Pointer(account_key))
+		C.free(unsafe.Pointer(fog_info))
+		C.free(unsafe.Pointer(report_id_str))
+		C.free(unsafe.Pointer(report_url_str))
+		free_authority_fingerprint()
+		free_spend_private_key()
+		free_view_private_key()
+	}
+}
+
+func newPublicAddressC(recipient *account.PublicAddress) (*C.McPublicAddress, func()) {
+	view_public, free_view_public := newBufferC(account.HexToBytes(recipient.ViewPublicKey))
+	spend_public, free_spend_public := newBufferC(account.HexToBytes(recipient.SpendPublicKey))
+	authority_sig, free_authority_sig := newBufferC(account.HexToBytes(recipient.FogAuthoritySig))
+	report_url_recipient_str := C.CString(recipient.FogReportUrl)
+	report_id_recipient_str := C.CString(recipient.FogReportId)
+
+	fog_info := (*C.McPublicAddressFogInfo)(C.malloc(C.sizeof_McPublicAddressFogInfo))
+	fog_info.report_url = (*C.char)(report_url_recipient_str)
+	fog_info.report_id = (*C.char)(report_id_recipient_str)
+	fog_info.authority_sig = authority_sig
+	recipient_address := (*C.McPublicAddress)(C.malloc(C.sizeof_McPublicAddress))
+	recipient_address.view_public_key = view_public
+	recipient_address.spend_public_key = spend_public
+	recipient_address.fog_info = fog_info
+	return recipient_address, func() {
+		C.free(unsafe.Pointer(recipient_address))
+		C.free(unsafe.Pointer(fog_info))
+		C.free(unsafe.Pointer(report_id_recipient_str))
+		C.free(unsafe.Pointer(report_url_recipient_str))
+		free_authority_sig()
+		free_spend_public()
+		free_view_public()
+	}
+}
 
-		mcDataChange, err := C.mc_transaction_builder_add_output(transaction_builder, C.uint64_t(changeAmount), change_address, rng_callback, change_tx_out_confirmation_number, change_tx_out_shared_secret, &out_error)
+// newRingC must be freed by mc_transaction_builder_ring_free
+func newRingC(txOutWithProofCs []*TxOutWithProofC) (*C.McTransactionBuilderRing, error) {
+	ring, err := C.mc_transaction_builder_ring_create()
+	if err != nil {
+		return nil, err
+	}
+	if ring == nil {
+		return nil, errors.New("mc_transaction_builder_ring_create failure")
+	}
+
+	for _, r := range txOutWithProofCs {
+		tx_out_buf, err := proto.Marshal(r.TxOut)
 		if err != nil {
+			C.mc_transaction_builder_ring_free(ring)
 			return nil, err
 		}
-		if out_error != nil {
-			err = fmt.Errorf("mc_transaction_builder_add_output change failed: [%d] %s", out_error.error_code, C.GoString(out_error.error_description))
-			C.mc_error_free(out_error)
+		membership_proof_buf, err := proto.Marshal(r.TxOutMembershipProof)
+		if err != nil {
+			C.mc_transaction_builder_ring_free(ring)
 			return nil, err
 		}
-		secret_change_buf = C.GoBytes(unsafe.Pointer(change_tx_out_shared_secret.buffer), C.int(len(secret_change_buf)))
-		confirmation_change_buf = C.GoBytes(unsafe.Pointer(change_tx_out_confirmation_number.buffer), C.int(len(confirmation_change_buf)))
-
-		defer C.mc_data_free(mcDataChange)
-		var tx_out_change_size_bytes *C.McMutableBuffer
-		data_size := C.mc_data_get_bytes(mcDataChange, tx_out_change_size_bytes)
-
-		tx_out_change_data_buf := make([]byte, int(data_size))
-		tx_out_change_data_bytes := C.CBytes(tx_out_change_data_buf)
-		defer C.free(tx_out_change_data_bytes)
-		tx_out_change_data := &C.McMutableBuffer{
-			buffer: (*C.uint8_t)(tx_out_change_data_bytes),
-			len:    C.size_t(len(tx_out_change_data_buf)),
-		}
-		data_size = C.mc_data_get_bytes(mcDataChange, tx_out_change_data)
-		err = proto.Unmarshal(C.GoBytes(tx_out_change_data_bytes, C.int(data_size)), txOutChange)
+		tx_out_proto, free_tx_out_proto := newBufferC(tx_out_buf)
+		membership_proof_proto, free_membership_proof_proto := newBufferC(membership_proof_buf)
+		b, err := C.mc_transaction_builder_ring_add_element(ring, tx_out_proto, membership_proof_proto)
+		free_membership_proof_proto()
+		free_tx_out_proto()
 		if err != nil {
+			C.mc_transaction_builder_ring_free(ring)
 			return nil, err
+		} else if !b {
+			C.mc_transaction_builder_ring_free(ring)
+			return nil, errors.New("mc_transaction_builder_ring_add_element failure")
 		}
 	}
-
-	mcData, err := C.mc_transaction_builder_build(transaction_builder, rng_callback, &out_error)
-	if err != nil {
-		return nil, err
-	}
-	if out_error != nil {
-		err = fmt.Errorf("mc_transaction_builder_build failed: [%d] %s", out_error.error_code, C.GoString(out_error.error_description))
-		C.mc_error_free(out_error)
-		return nil, err
-	}
-	defer C.mc_data_free(mcData)
-	var out_size_bytes *C.McMutableBuffer
-	data_size := C.mc_data_get_bytes(mcData, out_size_bytes)
-
-	out_data_buf := make([]byte, int(data_size))
-	out_data_bytes := C.CBytes(out_data_buf)
-	defer C.free(out_data_bytes)
-	out_data := &C.McMutableBuffer{
-		buffer: (*C.uint8_t)(out_data_bytes),
-		len:    C.size_t(len(out_data_buf)),
-	}
-	data_size = C.mc_data_get_bytes(mcData, out_data)
-	return &TxC{
-		Tx:                 C.GoBytes(out_data_bytes, C.int(data_size)),
-		TxOut:              txOut,
-		ShareSecretOut:     secret_recipient_buf,
-		ConfirmationOut:    confirmation_recipient_buf,
-		TxOutChange:        txOutChange,
-		ShareSecretChange:  secret_change_buf,
-		ConfirmationChange: confirmation_change_buf,
-	}, nil
+	return ring, nil
 }
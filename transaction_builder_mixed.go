@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+)
+
+// TokenInputs are the utxos of a single token with their proofs
+type TokenInputs struct {
+	TokenID uint
+	Inputs  []*UTXO
+	Proofs  *Proofs
+}
+
+type MixedPaymentOutlay struct {
+	Address string
+	Amount  uint64
+	TokenID uint
+}
+
+// TransactionBuilderBuildMixed pays outlays of several tokens in a single
+// transaction with the fee in feeTokenID. Each token is balanced separately,
+// its change is sent by mc_transaction_builder_add_change_output_mixed to the
+// change subaddress of the account of the first input, which must also own
+// changeStr. The change outputs follow the outlays in the Outlays of the
+// output, the change of the fee token at ChangeIndex, or -1 without it.
+// ChangeAmount is the change of the fee token.
+func TransactionBuilderBuildMixed(ctx context.Context, inputs []*TokenInputs, outlays []*MixedPaymentOutlay, fee uint64, feeTokenID uint, tombstone uint64, version uint, changeStr string, trust *FogTrustConfig, fetcher FogReportFetcher) (*Output, error) {
+	if len(outlays) == 0 {
+		return nil, errors.New("empty outlays")
+	}
+	change, err := account.DecodeB58Code(changeStr)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := mixedChangeAmounts(inputs, outlays, fee, feeTokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []uint
+	for token := range changes {
+		tokens = append(tokens, token)
+	}
+	slices.Sort(tokens)
+	if len(outlays)+len(tokens) > MAX_OUTPUTS {
+		return nil, fmt.Errorf("too many outputs %d, max %d", len(outlays)+len(tokens), MAX_OUTPUTS)
+	}
+
+	var inputCs []*InputC
+	for _, group := range inputs {
+		groupCs, err := BuildRingElements(group.Inputs, group.Proofs)
+		if err != nil {
+			return nil, fmt.Errorf("token %d: %v", group.TokenID, err)
+		}
+		inputCs = append(inputCs, groupCs...)
+	}
+	err = checkChangeOwner(change, inputCs[0].ViewPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	outlayCs := make([]*MixedOutlayC, 0, len(outlays)+len(tokens))
+	for _, outlay := range outlays {
+		recipient, err := account.DecodeB58Code(outlay.Address)
+		if err != nil {
+			return nil, err
+		}
+		outlayCs = append(outlayCs, &MixedOutlayC{
+			Amount:    outlay.Amount,
+			TokenID:   uint64(outlay.TokenID),
+			Recipient: recipient,
+		})
+	}
+	for _, token := range tokens {
+		outlayCs = append(outlayCs, &MixedOutlayC{
+			Amount:  changes[token],
+			TokenID: uint64(token),
+		})
+	}
+	fogReportUrls := outlaysFogReportUrls(mixedOutlayRecipients(outlayCs))
+	var verifier *FogVerifier
+	if len(fogReportUrls) > 0 {
+		verifier, err = trust.Verifier(fogReportUrls)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txC, err := MCTransactionBuilderCreateMixedC(ctx, inputCs, outlayCs, fee, tombstone, feeTokenID, version, verifier, fetcher)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &Output{
+		RawTransaction: hex.EncodeToString(txC.Tx),
+		Fee:            fee,
+		ChangeIndex:    -1,
+		ChangeAmount:   changes[feeTokenID],
+	}
+	for i, out := range txC.Outputs {
+		outlay := &OutlayOutput{
+			Address:            changeStr,
+			Amount:             outlayCs[i].Amount,
+			TokenID:            uint(outlayCs[i].TokenID),
+			OutputHash:         hex.EncodeToString(out.TxOut.PublicKey.GetData()),
+			SharedSecret:       hex.EncodeToString(out.SharedSecret),
+			ConfirmationNumber: hex.EncodeToString(out.Confirmation),
+		}
+		if i < len(outlays) {
+			outlay.Address = outlays[i].Address
+		} else if outlay.TokenID == feeTokenID {
+			output.ChangeIndex = int64(i)
+			output.ChangeHash = outlay.OutputHash
+		}
+		output.Outlays = append(output.Outlays, outlay)
+	}
+	output.TransactionHash = output.Outlays[0].OutputHash
+	output.OutputHash = output.Outlays[0].OutputHash
+	output.SharedSecret = output.Outlays[0].SharedSecret
+	return output, nil
+}
+
+// checkChangeOwner checks that the view public key of the change address is
+// its spend public key multiplied by viewPrivate, as for every subaddress of
+// the account.
+func checkChangeOwner(change *account.PublicAddress, viewPrivate *ristretto.Scalar) error {
+	spendPublic, err := decodePoint(change.SpendPublicKey)
+	if err != nil {
+		return err
+	}
+	var viewPublic ristretto.Point
+	viewPublic.ScalarMult(spendPublic, viewPrivate)
+	if hex.EncodeToString(viewPublic.Bytes()) != change.ViewPublicKey {
+		return errors.New("change address not owned by the account of the inputs")
+	}
+	return nil
+}
+
+// mixedChangeAmounts balances each token of the inputs against the outlays and
+// the fee. The change of each token is rejected below the minimum change of the
+// default fee policy of the token, as by checkChangeAmount.
+func mixedChangeAmounts(inputs []*TokenInputs, outlays []*MixedPaymentOutlay, fee uint64, feeTokenID uint) (map[uint]uint64, error) {
+	var count int
+	credits := make(map[uint]uint64)
+	for _, group := range inputs {
+		if _, found := credits[group.TokenID]; found {
+			return nil, fmt.Errorf("duplicated inputs of token %d", group.TokenID)
+		}
+		credits[group.TokenID] = 0
+		for _, input := range group.Inputs {
+			credits[group.TokenID] += input.Amount
+		}
+		count += len(group.Inputs)
+	}
+	if count == 0 || count > MAX_INPUTS {
+		return nil, fmt.Errorf("invalid inputs count %d, max %d", count, MAX_INPUTS)
+	}
+
+	debits := map[uint]uint64{feeTokenID: fee}
+	for _, outlay := range outlays {
+		if outlay.Amount == 0 {
+			return nil, fmt.Errorf("invalid outlay amount 0 to %s", outlay.Address)
+		}
+		debits[outlay.TokenID] += outlay.Amount
+	}
+	for token, debit := range debits {
+		if credits[token] < debit {
+			return nil, fmt.Errorf("%w: token %d %d, required %d", ErrInsufficientFunds, token, credits[token], debit)
+		}
+	}
+
+	changes := make(map[uint]uint64)
+	for token, credit := range credits {
+		err := checkChangeAmount(credit-debits[token], token)
+		if err != nil {
+			return nil, err
+		}
+		if credit > debits[token] {
+			changes[token] = credit - debits[token]
+		}
+	}
+	return changes, nil
+}
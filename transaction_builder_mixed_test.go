@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixedChangeAmounts(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := []*TokenInputs{
+		{TokenID: 0, Inputs: []*UTXO{{Amount: 10 * MILLIMOB_TO_PICOMOB}}},
+		{TokenID: 1, Inputs: []*UTXO{{Amount: 3 * MINIMUM_EUSD}, {Amount: 2 * MINIMUM_EUSD}}},
+	}
+	outlays := []*MixedPaymentOutlay{
+		{Amount: 4 * MILLIMOB_TO_PICOMOB, TokenID: 0},
+		{Amount: 4 * MINIMUM_EUSD, TokenID: 1},
+	}
+	changes, err := mixedChangeAmounts(inputs, outlays, MOB_MINIMUM_FEE, 0)
+	assert.Nil(err)
+	assert.Equal(uint64(6*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE), changes[0])
+	assert.Equal(uint64(MINIMUM_EUSD), changes[1])
+
+	// the mob change below a millimob is rejected, not added to the fee
+	outlays[0].Amount = 9*MILLIMOB_TO_PICOMOB + 500_000_000
+	_, err = mixedChangeAmounts(inputs, outlays, MOB_MINIMUM_FEE, 0)
+	assert.ErrorContains(err, "change amount 100000000 of token 0 below minimum")
+	outlays[0].Amount = 10*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE
+	changes, err = mixedChangeAmounts(inputs, outlays, MOB_MINIMUM_FEE, 0)
+	assert.Nil(err)
+	assert.NotContains(changes, uint(0))
+
+	// and so is the dust change of the other tokens
+	outlays[1].Amount = 5*MINIMUM_EUSD - 1
+	_, err = mixedChangeAmounts(inputs, outlays, MOB_MINIMUM_FEE, 0)
+	assert.ErrorContains(err, "change amount 1 of token 1 below minimum")
+
+	outlays[1].Amount = 6 * MINIMUM_EUSD
+	_, err = mixedChangeAmounts(inputs, outlays, MOB_MINIMUM_FEE, 0)
+	assert.True(errors.Is(err, ErrInsufficientFunds))
+
+	// the fee paid in eusd
+	outlays[0].Amount = 10 * MILLIMOB_TO_PICOMOB
+	outlays[1].Amount = 4 * MINIMUM_EUSD
+	changes, err = mixedChangeAmounts(inputs, outlays, EUSD_MINIMUM_FEE, EUSD_TOKEN_ID)
+	assert.Nil(err)
+	assert.Equal(uint64(MINIMUM_EUSD-EUSD_MINIMUM_FEE), changes[1])
+	assert.NotContains(changes, uint(0))
+	outlays[1].Amount = 5*MINIMUM_EUSD - EUSD_MINIMUM_FEE - 1
+	_, err = mixedChangeAmounts(inputs, outlays, EUSD_MINIMUM_FEE, EUSD_TOKEN_ID)
+	assert.ErrorContains(err, "change amount 1 of token 1 below minimum")
+
+	_, err = mixedChangeAmounts(append(inputs, inputs[0]), outlays, MOB_MINIMUM_FEE, 0)
+	assert.ErrorContains(err, "duplicated inputs")
+}
+
+func TestTransactionBuilderBuildMixedChange(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	assert.Nil(checkChangeOwner(acc.PublicAddress(0), acc.ViewPrivateKey))
+	assert.Nil(checkChangeOwner(acc.PublicAddress(CHANGE_SUBADDRESS_INDEX), acc.ViewPrivateKey))
+	var otherView, otherSpend ristretto.Scalar
+	other := &account.Account{ViewPrivateKey: otherView.Rand(), SpendPrivateKey: otherSpend.Rand()}
+	otherAddress, err := other.B58Code(0)
+	assert.Nil(err)
+	assert.NotNil(checkChangeOwner(other.PublicAddress(0), acc.ViewPrivateKey))
+
+	inputs, proofs := newTestInputs(assert, acc, []uint64{10 * MILLIMOB_TO_PICOMOB}, 0)
+	outlays := []*MixedPaymentOutlay{{Address: otherAddress, Amount: 4 * MILLIMOB_TO_PICOMOB, TokenID: 0}}
+	_, err = TransactionBuilderBuildMixed(context.Background(), []*TokenInputs{{TokenID: 0, Inputs: inputs, Proofs: proofs}}, outlays, MOB_MINIMUM_FEE, 0, 100, 3, otherAddress, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "change address not owned by the account of the inputs")
+}
+
+func TestTransactionBuilderBuildMixedC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	change, err := acc.B58Code(0)
+	require.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+	recipientAddress, err := recipient.B58Code(0)
+	require.Nil(err)
+
+	mobInputs, mobProofs := newTestInputs(assert.New(t), acc, []uint64{10 * MILLIMOB_TO_PICOMOB}, 0)
+	eusdInputs, eusdProofs := newTestInputs(assert.New(t), acc, []uint64{5 * MINIMUM_EUSD}, 1)
+	inputs := []*TokenInputs{
+		{TokenID: 0, Inputs: mobInputs, Proofs: mobProofs},
+		{TokenID: 1, Inputs: eusdInputs, Proofs: eusdProofs},
+	}
+	outlays := []*MixedPaymentOutlay{
+		{Address: recipientAddress, Amount: 4 * MILLIMOB_TO_PICOMOB, TokenID: 0},
+		{Address: recipientAddress, Amount: 3 * MINIMUM_EUSD, TokenID: 1},
+	}
+	output, err := TransactionBuilderBuildMixed(context.Background(), inputs, outlays, MOB_MINIMUM_FEE, 0, 100, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	require.Len(output.Outlays, 4)
+	require.Equal(int64(2), output.ChangeIndex)
+	require.Equal(uint64(6*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE), output.ChangeAmount)
+	tx, err := decodeTestTx(output.RawTransaction)
+	require.Nil(err)
+	require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3}))
+
+	scanner, err := NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(account.PublicKey(&spend).Bytes()), 1)
+	require.Nil(err)
+	matches, err := scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 2)
+	changes := make(map[uint64]uint64)
+	for _, match := range matches {
+		require.Equal(uint64(CHANGE_SUBADDRESS_INDEX), match.SubaddressIndex)
+		changes[match.TokenID] = match.Value
+	}
+	require.Equal(output.ChangeAmount, changes[0])
+	require.Equal(uint64(2*MINIMUM_EUSD), changes[1])
+	for _, outlay := range output.Outlays[2:] {
+		require.Equal(change, outlay.Address)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -24,20 +25,31 @@ const (
 	PICOMOB                              = 1_000_000_000_000 // precision = 12
 	MOB_MINIMUM_FEE                      = 400_000_000
 	MINIMUM_EUSD                         = 1_000_000
+	EUSD_MINIMUM_FEE                     = 2560
+	EUSD_TOKEN_ID                        = 1
 
 	MAX_TOMBSTONE_BLOCKS = 20160
 	MAX_INPUTS           = 16
+	MAX_OUTPUTS          = 16
 	RING_SIZE            = 11 // Each input ring must contain this many elements.
 )
 
+// UTXO is owned by Account, or by subaddress 0 of the 128 hex PrivateKey of
+// the view and spend private keys when Account is nil. Amount is of TokenID.
 type UTXO struct {
 	TransactionHash string
 	Index           uint32
 	Amount          uint64
+	TokenID         uint
 	PrivateKey      string
 	ScriptPubKey    string
+	Account         *SpendAccount
 }
 
+// Output is a built transaction, Outlays are all of its outputs, the payees
+// in the order of the outlays and then the change of the fee token at
+// ChangeIndex, which is -1 without change. TransactionHash, OutputHash and
+// SharedSecret are of the first payee at OutputIndex.
 type Output struct {
 	TransactionHash string
 	RawTransaction  string
@@ -48,55 +60,162 @@ type Output struct {
 	ChangeIndex     int64
 	ChangeHash      string
 	ChangeAmount    uint64
+	Outlays         []*OutlayOutput
+}
+
+// PaymentOutlay is a single payee of a transaction, Memo is the optional
+// payment request id attached by the sender memo builder. The builder writes
+// one payment request id for the whole transaction, so the outlays which set
+// a Memo must agree on it.
+type PaymentOutlay struct {
+	Address string
+	Amount  uint64
+	Memo    uint64
+}
+
+// OutlayOutput is an output of a transaction, RequestMemo is the memo of the
+// payment request of Address, which is only shown to the payer and not in the
+// transaction.
+type OutlayOutput struct {
+	Address            string
+	Amount             uint64
+	TokenID            uint
+	OutputHash         string
+	SharedSecret       string
+	ConfirmationNumber string
+	RequestMemo        string
 }
 
 func TransactionBuilderBuild(inputs []*UTXO, proofs *Proofs, output string, amount, fee uint64, tombstone, memo uint64, tokenID, version uint, changeStr string) (*Output, error) {
-	recipient, err := account.DecodeB58Code(output)
-	if err != nil {
-		return nil, err
+	outlays := []*PaymentOutlay{{Address: output, Amount: amount, Memo: memo}}
+	return TransactionBuilderBuildOutlays(context.Background(), inputs, proofs, outlays, fee, tombstone, tokenID, version, changeStr, DefaultFogTrustConfig, DefaultFogReportFetcher)
+}
+
+// TransactionBuilderBuildOutlays pays all the outlays in a single transaction.
+// The memo builder attaches one payment request id to the whole transaction,
+// so the outlays which set a memo must agree on it. The fog reports of the
+// outlays are fetched by fetcher and attested with trust.
+func TransactionBuilderBuildOutlays(ctx context.Context, inputs []*UTXO, proofs *Proofs, outlays []*PaymentOutlay, fee uint64, tombstone uint64, tokenID, version uint, changeStr string, trust *FogTrustConfig, fetcher FogReportFetcher) (*Output, error) {
+	var memo uint64
+	for _, outlay := range outlays {
+		if outlay.Memo > 0 {
+			if memo > 0 && memo != outlay.Memo {
+				return nil, fmt.Errorf("conflicting outlay memo %d %d", memo, outlay.Memo)
+			}
+			memo = outlay.Memo
+		}
+	}
+	return TransactionBuilderBuildOutlaysWithMemo(ctx, inputs, proofs, outlays, fee, tombstone, tokenID, version, changeStr, PaymentRequestMemoOptions(memo), trust, fetcher)
+}
+
+// TransactionBuilderBuildOutlaysWithMemo is TransactionBuilderBuildOutlays
+// with the memo builder selected by memo, the Memo of an outlay must be the
+// PaymentRequestID of memo. The inputs left after the outlays and fee are paid
+// to a change output, which is rejected below the minimum change of the
+// FeePolicy of the token, the caller includes such dust in the fee, e.g. with
+// SelectCoins.
+func TransactionBuilderBuildOutlaysWithMemo(ctx context.Context, inputs []*UTXO, proofs *Proofs, outlays []*PaymentOutlay, fee uint64, tombstone uint64, tokenID, version uint, changeStr string, memo *MemoOptions, trust *FogTrustConfig, fetcher FogReportFetcher) (*Output, error) {
+	if len(outlays) == 0 {
+		return nil, errors.New("empty outlays")
 	}
 	change, err := account.DecodeB58Code(changeStr)
 	if err != nil {
 		return nil, err
 	}
 
+	var amount uint64
+	outlayCs := make([]*OutlayC, len(outlays))
+	requestMemos := make([]string, len(outlays))
+	for i, outlay := range outlays {
+		if outlay.Memo > 0 && (memo == nil || memo.PaymentRequestID != outlay.Memo) {
+			return nil, fmt.Errorf("outlay memo %d not the payment request id of the memo builder", outlay.Memo)
+		}
+		payment, err := decodeRecipient(outlay.Address, outlay.Amount, tokenID)
+		if err != nil {
+			return nil, err
+		}
+		if amount+payment.Value < amount {
+			return nil, fmt.Errorf("outlays amount overflow %d %d", amount, payment.Value)
+		}
+		amount += payment.Value
+		outlayCs[i] = &OutlayC{
+			Amount:    payment.Value,
+			Recipient: payment.Address,
+		}
+		requestMemos[i] = payment.Memo
+	}
+
 	var totalAmount uint64
 	for _, input := range inputs {
 		totalAmount += input.Amount
 	}
 
+	if amount+fee < amount {
+		return nil, fmt.Errorf("outlays amount overflow %d %d", amount, fee)
+	}
+	if totalAmount < amount+fee {
+		return nil, fmt.Errorf("%w: %d, required %d", ErrInsufficientFunds, totalAmount, amount+fee)
+	}
 	changeAmount := totalAmount - amount - fee
-	if changeAmount < MOB_MINIMUM_FEE {
-		changeAmount = 0
-		fee += changeAmount
+	err = checkChangeAmount(changeAmount, tokenID)
+	if err != nil {
+		return nil, err
 	}
-	if changeAmount > 0 && changeAmount < MILLIMOB_TO_PICOMOB {
-		return nil, errors.New("invalid change amount")
+	outputs := len(outlays)
+	if changeAmount > 0 {
+		outputs += 1
 	}
-	if totalAmount != (amount + fee + changeAmount) {
-		return nil, errors.New("invalid amount")
+	if outputs > MAX_OUTPUTS {
+		return nil, fmt.Errorf("too many outputs %d, max %d", outputs, MAX_OUTPUTS)
 	}
 	inputCs, err := BuildRingElements(inputs, proofs)
 	if err != nil {
 		return nil, err
 	}
 
-	txC, err := MCTransactionBuilderCreateC(inputCs, amount, changeAmount, fee, tombstone, memo, tokenID, version, recipient, change)
+	verifier, err := outlaysVerifier(outlayCs, trust)
+	if err != nil {
+		return nil, err
+	}
+	txC, err := MCTransactionBuilderCreateOutlaysCWithMemo(ctx, inputCs, outlayCs, changeAmount, fee, tombstone, tokenID, version, change, memo, verifier, fetcher)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Output{
+	output := &Output{
 		TransactionHash: hex.EncodeToString(txC.TxOut.PublicKey.GetData()),
 		RawTransaction:  hex.EncodeToString(txC.Tx),
 		SharedSecret:    hex.EncodeToString(txC.ShareSecretOut),
 		Fee:             fee,
 		OutputIndex:     0,
 		OutputHash:      hex.EncodeToString(txC.TxOut.PublicKey.GetData()),
-		ChangeIndex:     0,
-		ChangeHash:      hex.EncodeToString(txC.TxOutChange.PublicKey.GetData()),
+		ChangeIndex:     -1,
 		ChangeAmount:    changeAmount,
-	}, nil
+	}
+	for i, out := range txC.Outputs {
+		output.Outlays = append(output.Outlays, &OutlayOutput{
+			Address:            outlays[i].Address,
+			Amount:             outlayCs[i].Amount,
+			TokenID:            tokenID,
+			RequestMemo:        requestMemos[i],
+			OutputHash:         hex.EncodeToString(out.TxOut.PublicKey.GetData()),
+			SharedSecret:       hex.EncodeToString(out.SharedSecret),
+			ConfirmationNumber: hex.EncodeToString(out.Confirmation),
+		})
+	}
+	if changeAmount > 0 {
+		output.ChangeIndex = int64(len(output.Outlays))
+		output.ChangeHash = hex.EncodeToString(txC.TxOutChange.PublicKey.GetData())
+		output.Outlays = append(output.Outlays, &OutlayOutput{
+			Address:            changeStr,
+			Amount:             changeAmount,
+			TokenID:            tokenID,
+			OutputHash:         output.ChangeHash,
+			SharedSecret:       hex.EncodeToString(txC.ShareSecretChange),
+			ConfirmationNumber: hex.EncodeToString(txC.ConfirmationChange),
+		})
+	}
+	return output, nil
 }
 
 func UnmarshalTx(tx *types.Tx) *Tx {
@@ -132,6 +251,7 @@ func UnmarshalPrefix(prefix *types.TxPrefix) *TxPrefix {
 		Inputs:         ins,
 		Outputs:        outs,
 		Fee:            FeeValue(prefix.Fee),
+		FeeTokenID:     prefix.FeeTokenId,
 		TombstoneBlock: TombstoneValue(prefix.TombstoneBlock),
 	}
 }
@@ -189,10 +309,17 @@ func UnmarshalSignatureRctBulletproofs(signature *types.SignatureRctBulletproofs
 	for i, c := range signature.PseudoOutputCommitments {
 		commitments[i] = hex.EncodeToString(c.GetData())
 	}
+	rangeProofs := make([]string, len(signature.RangeProofs))
+	for i, p := range signature.RangeProofs {
+		rangeProofs[i] = hex.EncodeToString(p)
+	}
 	return &SignatureRctBulletproofs{
 		RingSignatures:          signatures,
 		PseudoOutputCommitments: commitments,
 		RangeProofs:             hex.EncodeToString(signature.RangeProofBytes),
+		TokenRangeProofs:        rangeProofs,
+		PseudoOutputTokenIDs:    signature.PseudoOutputTokenIds,
+		OutputTokenIDs:          signature.OutputTokenIds,
 	}
 }
 
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionBuilderBuildOutlays(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	address, err := acc.B58Code(0)
+	assert.Nil(err)
+
+	inputs := []*UTXO{{Amount: 100 * MILLIMOB_TO_PICOMOB}}
+	outlays := make([]*PaymentOutlay, MAX_OUTPUTS)
+	for i := range outlays {
+		outlays[i] = &PaymentOutlay{Address: address, Amount: MILLIMOB_TO_PICOMOB}
+	}
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "too many outputs")
+
+	outlays = []*PaymentOutlay{
+		{Address: address, Amount: MILLIMOB_TO_PICOMOB, Memo: 1},
+		{Address: address, Amount: MILLIMOB_TO_PICOMOB, Memo: 2},
+	}
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "conflicting outlay memo")
+
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, nil, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.NotNil(err)
+	_, err = TransactionBuilderBuildOutlaysWithMemo(context.Background(), inputs, &Proofs{}, outlays[:1], MOB_MINIMUM_FEE, 100, 0, 3, address, PaymentIntentMemoOptions(1), DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "outlay memo 1 not the payment request id")
+
+	outlays = []*PaymentOutlay{{Address: address, Amount: MILLIMOB_TO_PICOMOB}, {Address: address}}
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "zero amount")
+	outlays = []*PaymentOutlay{{Address: address, Amount: math.MaxUint64}, {Address: address, Amount: 2}}
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "outlays amount overflow")
+	outlays = []*PaymentOutlay{{Address: address, Amount: math.MaxUint64 - 1}}
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "outlays amount overflow")
+
+	outlays = []*PaymentOutlay{{Address: address, Amount: 100*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE - 1}}
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "change amount 1 of token 0 below minimum")
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE+1, 100, 0, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "Invalid proofs")
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, EUSD_TOKEN_ID, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "change amount 1 of token 1 below minimum 2560")
+	_, err = TransactionBuilderBuildOutlays(context.Background(), inputs, &Proofs{}, outlays, MOB_MINIMUM_FEE, 100, 5, 3, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "no default fee policy for token 5")
+
+	outlayCs := []*OutlayC{{Amount: MILLIMOB_TO_PICOMOB, Recipient: acc.PublicAddress(0)}}
+	_, err = MCTransactionBuilderCreateOutlaysCWithMemo(context.Background(), nil, outlayCs, 0, MOB_MINIMUM_FEE, 100, 0, 3, acc.PublicAddress(0), nil, nil, DefaultFogReportFetcher)
+	assert.ErrorContains(err, "no inputs")
+}
+
+func TestTransactionBuilderBuildOutlaysC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	change, err := acc.B58Code(0)
+	require.Nil(err)
+	var recipientView, recipientSpend ristretto.Scalar
+	recipient := &account.Account{ViewPrivateKey: recipientView.Rand(), SpendPrivateKey: recipientSpend.Rand()}
+
+	values := []uint64{MILLIMOB_TO_PICOMOB, 2 * MILLIMOB_TO_PICOMOB, 3 * MILLIMOB_TO_PICOMOB}
+	outlays := make([]*PaymentOutlay, len(values))
+	for i, v := range values {
+		address, err := recipient.B58Code(uint64(i))
+		require.Nil(err)
+		outlays[i] = &PaymentOutlay{Address: address, Amount: v}
+	}
+	inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{4 * MILLIMOB_TO_PICOMOB, 5 * MILLIMOB_TO_PICOMOB}, 0)
+	output, err := TransactionBuilderBuildOutlays(context.Background(), inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, 3, change, DefaultFogTrustConfig, DefaultFogReportFetcher)
+	require.Nil(err)
+	require.Equal(uint64(MOB_MINIMUM_FEE), output.Fee)
+	require.Equal(uint64(3*MILLIMOB_TO_PICOMOB-MOB_MINIMUM_FEE), output.ChangeAmount)
+
+	tx, err := decodeTestTx(output.RawTransaction)
+	require.Nil(err)
+	require.Len(tx.Prefix.Outputs, len(outlays)+1)
+	require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3}))
+
+	scanner, err := NewScanner(hex.EncodeToString(recipientView.Bytes()), hex.EncodeToString(account.PublicKey(&recipientSpend).Bytes()), uint64(len(outlays)))
+	require.Nil(err)
+	matches, err := scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, len(outlays))
+	received := make(map[string]*ScanMatch)
+	for _, m := range matches {
+		received[m.TxOut.PublicKey] = m
+	}
+
+	require.Len(output.Outlays, len(outlays)+1)
+	require.Equal(output.OutputHash, output.Outlays[0].OutputHash)
+	require.Equal(int64(len(outlays)), output.ChangeIndex)
+	require.Equal(change, output.Outlays[len(outlays)].Address)
+	require.Equal(output.ChangeHash, output.Outlays[len(outlays)].OutputHash)
+	require.Equal(output.ChangeAmount, output.Outlays[len(outlays)].Amount)
+	for i, o := range output.Outlays[:len(outlays)] {
+		require.Equal(outlays[i].Address, o.Address)
+		require.Equal(values[i], o.Amount)
+		require.Equal(uint(0), o.TokenID)
+		require.Len(o.SharedSecret, 64)
+		require.Len(o.ConfirmationNumber, 64)
+
+		m := received[o.OutputHash]
+		require.NotNil(m)
+		require.Equal(uint64(i), m.SubaddressIndex)
+		require.Equal(values[i], m.Value)
+	}
+
+	scanner, err = NewScanner(hex.EncodeToString(view.Bytes()), hex.EncodeToString(account.PublicKey(&spend).Bytes()), 1)
+	require.Nil(err)
+	matches, err = scanner.Scan(tx.Prefix.Outputs)
+	require.Nil(err)
+	require.Len(matches, 1)
+	require.Equal(output.ChangeHash, matches[0].TxOut.PublicKey)
+	require.Equal(output.ChangeAmount, matches[0].Value)
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/bwesterb/go-ristretto"
@@ -16,100 +18,194 @@ const (
 	AGGREGATE_END = "agg-end"
 	VARIANT       = "var"
 	NONE          = ""
+
+	EXTENDED_MESSAGE_DOMAIN_TAG = "mc_extended_message"
 )
 
-// Convert tx_prefix to merlin transcript
+// Convert tx_prefix to merlin transcript, the version of each TxOut amount
+// selects the digest of the block version it was created in. It panics on an
+// invalid prefix, use TxPrefixDigest to get the error instead.
 func HashOfTxPrefix(tx *TxPrefix) []byte {
+	digest, err := TxPrefixDigest(tx)
+	if err != nil {
+		panic(err)
+	}
+	return digest
+}
+
+// TxPrefixDigest is HashOfTxPrefix returning the error of an invalid prefix
+func TxPrefixDigest(tx *TxPrefix) ([]byte, error) {
 	t := merlin.NewTranscript("digestible")
-	appendTxPrefix(tx, t)
-	return t.ExtractBytes([]byte("digest32"), 32)
+	err := appendTxPrefix(tx, t)
+	if err != nil {
+		return nil, err
+	}
+	return t.ExtractBytes([]byte("digest32"), 32), nil
+}
+
+// ExtendedMessageDigest is the message signed by the ring signatures of the
+// transaction, the tx prefix digest extended with the pseudo output commitments
+// and the range proofs. From block version 2 it is the merlin digest of them,
+// before that their concatenation.
+func ExtendedMessageDigest(blockVersion uint, message []byte, signature *SignatureRctBulletproofs) ([]byte, error) {
+	commitments := make([][]byte, len(signature.PseudoOutputCommitments))
+	for i, c := range signature.PseudoOutputCommitments {
+		buf, err := hex.DecodeString(c)
+		if err != nil {
+			return nil, err
+		}
+		commitments[i] = buf
+	}
+	rangeProofBytes, err := hex.DecodeString(signature.RangeProofs)
+	if err != nil {
+		return nil, err
+	}
+	rangeProofs := make([][]byte, len(signature.TokenRangeProofs))
+	for i, p := range signature.TokenRangeProofs {
+		rangeProofs[i], err = hex.DecodeString(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if blockVersion < 2 {
+		extended := append([]byte{}, message...)
+		for _, c := range commitments {
+			extended = append(extended, c...)
+		}
+		return append(extended, rangeProofBytes...), nil
+	}
+
+	t := merlin.NewTranscript(EXTENDED_MESSAGE_DOMAIN_TAG)
+	appendBytes([]byte("message"), []byte(PRIMITIVE), t)
+	appendBytes([]byte("bytes"), message, t)
+
+	appendBytes([]byte("pseudo_output_commitments"), []byte(SEQUENCE), t)
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, uint64(len(commitments)))
+	appendBytes([]byte("len"), bytes, t)
+	for _, c := range commitments {
+		appendBytes([]byte(NONE), []byte(PRIMITIVE), t)
+		appendBytes([]byte("ristretto"), c, t)
+	}
+
+	// Only one of the legacy range proof and the range proofs of each token
+	// is present, the empty one is omitted.
+	if len(rangeProofBytes) > 0 {
+		appendBytes([]byte("range_proof_bytes"), []byte(PRIMITIVE), t)
+		appendBytes([]byte("bytes"), rangeProofBytes, t)
+	}
+	if len(rangeProofs) > 0 {
+		appendBytes([]byte("range_proofs"), []byte(SEQUENCE), t)
+		binary.LittleEndian.PutUint64(bytes, uint64(len(rangeProofs)))
+		appendBytes([]byte("len"), bytes, t)
+		for _, p := range rangeProofs {
+			appendBytes([]byte(NONE), []byte(PRIMITIVE), t)
+			appendBytes([]byte("bytes"), p, t)
+		}
+	}
+	return t.ExtractBytes([]byte("digest32"), 32), nil
 }
 
 // TxIn: append transaction inputs to transcript
 // TxOutMembershipProof: append membership proof to transcript
-func appendIndex(index string, t *merlin.Transcript) {
+func appendIndex(index string, t *merlin.Transcript) error {
 	i, err := strconv.ParseUint(index, 10, 64)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	appendBytes([]byte("index"), []byte(PRIMITIVE), t)
 
 	bytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bytes, i)
 	appendBytes([]byte("uint"), bytes, t)
+	return nil
 }
 
-func appendHighestIndex(index string, t *merlin.Transcript) {
+func appendHighestIndex(index string, t *merlin.Transcript) error {
 	i, err := strconv.ParseUint(index, 10, 64)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	appendBytes([]byte("highest_index"), []byte(PRIMITIVE), t)
 
 	bytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bytes, i)
 	appendBytes([]byte("uint"), bytes, t)
+	return nil
 }
 
-func appendRange(r *Range, t *merlin.Transcript) {
+func appendRange(r *Range, t *merlin.Transcript) error {
+	i, err := strconv.ParseUint(r.From, 10, 64)
+	if err != nil {
+		return err
+	}
+	j, err := strconv.ParseUint(r.To, 10, 64)
+	if err != nil {
+		return err
+	}
 	appendBytes([]byte("range"), []byte(AGGREGATE), t)
 	appendBytes([]byte("name"), []byte("Range"), t)
 
 	appendBytes([]byte("from"), []byte("prim"), t)
-	i, err := strconv.ParseUint(r.From, 10, 64)
-	if err != nil {
-		panic(err)
-	}
 	bufi := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bufi, i)
 	appendBytes([]byte("uint"), bufi, t)
 
 	appendBytes([]byte("to"), []byte("prim"), t)
-	j, err := strconv.ParseUint(r.To, 10, 64)
-	if err != nil {
-		panic(err)
-	}
 	bufj := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bufj, j)
 	appendBytes([]byte("uint"), bufj, t)
 	appendBytes([]byte("range"), []byte(AGGREGATE_END), t)
 	appendBytes([]byte("name"), []byte("Range"), t)
+	return nil
 }
 
-func appendHash(hash string, t *merlin.Transcript) {
-	appendBytes([]byte("hash"), []byte("prim"), t)
-
+func appendHash(hash string, t *merlin.Transcript) error {
 	buf, err := hex.DecodeString(hash)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	appendBytes([]byte("hash"), []byte("prim"), t)
 	appendBytes([]byte("bytes"), buf, t)
+	return nil
 }
 
-func appendElement(element *TxOutMembershipElement, t *merlin.Transcript) {
+func appendElement(element *TxOutMembershipElement, t *merlin.Transcript) error {
 	appendBytes([]byte(""), []byte(AGGREGATE), t)
 	appendBytes([]byte("name"), []byte("TxOutMembershipElement"), t)
 
-	appendRange(element.Range, t)
-	appendHash(element.Hash, t)
+	err := appendRange(element.Range, t)
+	if err != nil {
+		return err
+	}
+	err = appendHash(element.Hash, t)
+	if err != nil {
+		return err
+	}
 
 	appendBytes([]byte(""), []byte(AGGREGATE_END), t)
 	appendBytes([]byte("name"), []byte("TxOutMembershipElement"), t)
+	return nil
 }
 
-func appendElements(elements []*TxOutMembershipElement, t *merlin.Transcript) {
+func appendElements(elements []*TxOutMembershipElement, t *merlin.Transcript) error {
 	appendBytes([]byte("elements"), []byte(SEQUENCE), t)
 	bytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bytes, uint64(len(elements)))
 	appendBytes([]byte("len"), bytes, t)
 
 	for _, element := range elements {
-		appendElement(element, t)
+		err := appendElement(element, t)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // "Ring" of inputs, one of which is actually being spent.
-func appendRing(outputs []*TxOut, t *merlin.Transcript) {
+func appendRing(outputs []*TxOut, t *merlin.Transcript) error {
 	appendBytes([]byte("ring"), []byte(SEQUENCE), t)
 
 	bytes := make([]byte, 8)
@@ -117,24 +213,38 @@ func appendRing(outputs []*TxOut, t *merlin.Transcript) {
 	appendBytes([]byte("len"), bytes, t)
 
 	for _, output := range outputs {
-		appendTxOut(output, t)
+		err := appendTxOut(output, t)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Proof that each TxOut in `ring` is in the ledger.
-func appendTxOutMembershipProof(proof *TxOutMembershipProof, t *merlin.Transcript) {
+func appendTxOutMembershipProof(proof *TxOutMembershipProof, t *merlin.Transcript) error {
 	appendBytes([]byte(""), []byte(AGGREGATE), t)
 	appendBytes([]byte("name"), []byte("TxOutMembershipProof"), t)
 
-	appendIndex(proof.Index, t)
-	appendHighestIndex(proof.HighestIndex, t)
-	appendElements(proof.Elements, t)
+	err := appendIndex(proof.Index, t)
+	if err != nil {
+		return err
+	}
+	err = appendHighestIndex(proof.HighestIndex, t)
+	if err != nil {
+		return err
+	}
+	err = appendElements(proof.Elements, t)
+	if err != nil {
+		return err
+	}
 
 	appendBytes([]byte(""), []byte(AGGREGATE_END), t)
 	appendBytes([]byte("name"), []byte("TxOutMembershipProof"), t)
+	return nil
 }
 
-func appendTxOutMembershipProofs(proofs []*TxOutMembershipProof, t *merlin.Transcript) {
+func appendTxOutMembershipProofs(proofs []*TxOutMembershipProof, t *merlin.Transcript) error {
 	appendBytes([]byte("proofs"), []byte(SEQUENCE), t)
 
 	bytes := make([]byte, 8)
@@ -142,22 +252,33 @@ func appendTxOutMembershipProofs(proofs []*TxOutMembershipProof, t *merlin.Trans
 	appendBytes([]byte("len"), bytes, t)
 
 	for _, proof := range proofs {
-		appendTxOutMembershipProof(proof, t)
+		err := appendTxOutMembershipProof(proof, t)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func appendTxIn(in *TxIn, t *merlin.Transcript) {
+func appendTxIn(in *TxIn, t *merlin.Transcript) error {
 	appendBytes([]byte(""), []byte(AGGREGATE), t)
 	appendBytes([]byte("name"), []byte("TxIn"), t)
 
-	appendRing(in.Ring, t)
-	appendTxOutMembershipProofs(in.Proofs, t)
+	err := appendRing(in.Ring, t)
+	if err != nil {
+		return err
+	}
+	err = appendTxOutMembershipProofs(in.Proofs, t)
+	if err != nil {
+		return err
+	}
 
 	appendBytes([]byte(""), []byte(AGGREGATE_END), t)
 	appendBytes([]byte("name"), []byte("TxIn"), t)
+	return nil
 }
 
-func appendInputs(inputs []*TxIn, t *merlin.Transcript) {
+func appendInputs(inputs []*TxIn, t *merlin.Transcript) error {
 	appendBytes([]byte("inputs"), []byte(SEQUENCE), t)
 
 	bytes := make([]byte, 8)
@@ -165,20 +286,25 @@ func appendInputs(inputs []*TxIn, t *merlin.Transcript) {
 	appendBytes([]byte("len"), bytes, t)
 
 	for _, input := range inputs {
-		appendTxIn(input, t)
+		err := appendTxIn(input, t)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // TxOut: append tx out to transcript
 
 // Append TxOut Amount
-func appendCommitment(commitment string, t *merlin.Transcript) {
+func appendCommitment(commitment string, t *merlin.Transcript) error {
 	buf, err := hex.DecodeString(commitment)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	appendBytes([]byte("commitment"), []byte(PRIMITIVE), t)
 	appendBytes([]byte("ristretto"), buf, t)
+	return nil
 }
 
 func appendMaskedValue(value MaskedValue, t *merlin.Transcript) {
@@ -189,61 +315,138 @@ func appendMaskedValue(value MaskedValue, t *merlin.Transcript) {
 	appendBytes([]byte("uint"), bytes, t)
 }
 
-func appendAmount(amount *Amount, t *merlin.Transcript) {
+// Empty masked_token_id is omitted, which keeps the digest of TxOut created
+// before block version 2 unchanged.
+func appendMaskedTokenID(maskedTokenID string, t *merlin.Transcript) error {
+	buf, err := hex.DecodeString(maskedTokenID)
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	appendBytes([]byte("masked_token_id"), []byte(PRIMITIVE), t)
+	appendBytes([]byte("bytes"), buf, t)
+	return nil
+}
+
+// The MaskedAmount enum is transparent, so the amount is digested as the
+// struct of its version, MaskedAmountV1 keeps the legacy name Amount.
+func amountDigestName(amount *Amount) (string, error) {
+	switch amount.Version {
+	case 0, 1:
+		return "Amount", nil
+	case 2:
+		return "MaskedAmountV2", nil
+	default:
+		return "", fmt.Errorf("invalid amount version %d", amount.Version)
+	}
+}
+
+func appendAmount(amount *Amount, t *merlin.Transcript) error {
+	if amount == nil {
+		return errors.New("missing amount")
+	}
+	name, err := amountDigestName(amount)
+	if err != nil {
+		return err
+	}
 	appendBytes([]byte("amount"), []byte(AGGREGATE), t)
-	appendBytes([]byte("name"), []byte("Amount"), t)
+	appendBytes([]byte("name"), []byte(name), t)
 
-	appendCommitment(amount.Commitment, t)
+	err = appendCommitment(amount.Commitment, t)
+	if err != nil {
+		return err
+	}
 	appendMaskedValue(amount.MaskedValue, t)
+	err = appendMaskedTokenID(amount.MaskedTokenID, t)
+	if err != nil {
+		return err
+	}
 
 	appendBytes([]byte("amount"), []byte(AGGREGATE_END), t)
-	appendBytes([]byte("name"), []byte("Amount"), t)
+	appendBytes([]byte("name"), []byte(name), t)
+	return nil
 }
 
 // Append TxOut TargetKey
-func appendTargetKey(key string, t *merlin.Transcript) {
+func appendTargetKey(key string, t *merlin.Transcript) error {
 	buf, err := hex.DecodeString(key)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	appendBytes([]byte("target_key"), []byte(PRIMITIVE), t)
 	appendBytes([]byte("ristretto"), buf, t)
+	return nil
 }
 
 // Append TxOut PublicKey
-func appendPublicKey(key string, t *merlin.Transcript) {
+func appendPublicKey(key string, t *merlin.Transcript) error {
 	buf, err := hex.DecodeString(key)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	appendBytes([]byte("public_key"), []byte(PRIMITIVE), t)
 	appendBytes([]byte("ristretto"), buf, t)
+	return nil
 }
 
 // Append TxOut EFogHint
-func appendEFogHint(hint string, t *merlin.Transcript) {
+func appendEFogHint(hint string, t *merlin.Transcript) error {
 	buf, err := hex.DecodeString(hint)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	appendBytes([]byte("e_fog_hint"), []byte(PRIMITIVE), t)
 	appendBytes([]byte("bytes"), buf, t)
+	return nil
 }
 
-func appendTxOut(txOut *TxOut, t *merlin.Transcript) {
+// Append TxOut EMemo, absent before block version 2
+func appendEMemo(memo string, t *merlin.Transcript) error {
+	buf, err := hex.DecodeString(memo)
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	appendBytes([]byte("e_memo"), []byte(PRIMITIVE), t)
+	appendBytes([]byte("bytes"), buf, t)
+	return nil
+}
+
+func appendTxOut(txOut *TxOut, t *merlin.Transcript) error {
 	appendBytes([]byte(""), []byte(AGGREGATE), t)
 	appendBytes([]byte("name"), []byte("TxOut"), t)
 
-	appendAmount(txOut.Amount, t)
-	appendTargetKey(txOut.TargetKey, t)
-	appendPublicKey(txOut.PublicKey, t)
-	appendEFogHint(txOut.EFogHint, t)
+	err := appendAmount(txOut.Amount, t)
+	if err != nil {
+		return err
+	}
+	err = appendTargetKey(txOut.TargetKey, t)
+	if err != nil {
+		return err
+	}
+	err = appendPublicKey(txOut.PublicKey, t)
+	if err != nil {
+		return err
+	}
+	err = appendEFogHint(txOut.EFogHint, t)
+	if err != nil {
+		return err
+	}
+	err = appendEMemo(txOut.EMemo, t)
+	if err != nil {
+		return err
+	}
 
 	appendBytes([]byte(""), []byte(AGGREGATE_END), t)
 	appendBytes([]byte("name"), []byte("TxOut"), t)
+	return nil
 }
 
-func appendOutputs(outputs []*TxOut, t *merlin.Transcript) {
+func appendOutputs(outputs []*TxOut, t *merlin.Transcript) error {
 	appendBytes([]byte("outputs"), []byte(SEQUENCE), t)
 
 	bytes := make([]byte, 8)
@@ -251,8 +454,12 @@ func appendOutputs(outputs []*TxOut, t *merlin.Transcript) {
 	appendBytes([]byte("len"), bytes, t)
 
 	for _, output := range outputs {
-		appendTxOut(output, t)
+		err := appendTxOut(output, t)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Fee: append fee to transcript
@@ -264,6 +471,19 @@ func appendFee(fee uint64, t *merlin.Transcript) {
 	appendBytes([]byte("uint"), bytes, t)
 }
 
+// Fee token id, omitted for MOB which keeps the digest of the transactions
+// created before block version 2 unchanged
+func appendFeeTokenID(tokenID uint64, t *merlin.Transcript) {
+	if tokenID == 0 {
+		return
+	}
+	appendBytes([]byte("fee_token_id"), []byte(PRIMITIVE), t)
+
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, tokenID)
+	appendBytes([]byte("uint"), bytes, t)
+}
+
 // Tombstone: append tombstone block to transcript
 func appendTombstoneBlock(tombstone uint64, t *merlin.Transcript) {
 	appendBytes([]byte("tombstone_block"), []byte("prim"), t)
@@ -273,17 +493,25 @@ func appendTombstoneBlock(tombstone uint64, t *merlin.Transcript) {
 	appendBytes([]byte("uint"), bytes, t)
 }
 
-func appendTxPrefix(tx *TxPrefix, t *merlin.Transcript) {
+func appendTxPrefix(tx *TxPrefix, t *merlin.Transcript) error {
 	appendBytes([]byte("mobilecoin-tx-prefix"), []byte(AGGREGATE), t)
 	appendBytes([]byte("name"), []byte("TxPrefix"), t)
 
-	appendInputs(tx.Inputs, t)
-	appendOutputs(tx.Outputs, t)
+	err := appendInputs(tx.Inputs, t)
+	if err != nil {
+		return err
+	}
+	err = appendOutputs(tx.Outputs, t)
+	if err != nil {
+		return err
+	}
 	appendFee(uint64(tx.Fee), t)
+	appendFeeTokenID(tx.FeeTokenID, t)
 	appendTombstoneBlock(uint64(tx.TombstoneBlock), t)
 
 	appendBytes([]byte("mobilecoin-tx-prefix"), []byte(AGGREGATE_END), t)
 	appendBytes([]byte("name"), []byte("TxPrefix"), t)
+	return nil
 }
 
 func appendInt64(label string, i uint64, t *merlin.Transcript) {
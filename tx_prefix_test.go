@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHashOfTxPrefix pins the digests of the prefixes of testTxPrefix, with
+// and without the masked token id and the e_memo, for both amount versions.
+// TestHashOfTxPrefixC checks the digest against libmobilecoin.
+func TestHashOfTxPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	memo := strings.Repeat("f0", 66)
+	for _, v := range []struct {
+		version       int64
+		maskedTokenID string
+		eMemo         string
+		feeTokenID    uint64
+		digest        string
+	}{
+		{1, "", "", 0, "ebf8f5f9b83df83fe98e360f1fbde45e6e189474d61fd3b2ccadc50160944b45"},
+		{1, "0102030405060708", memo, 0, "c420d01c3859388501123da7299d4dc260c14794c27a5d7970ffdced951862db"},
+		{2, "0102030405060708", memo, 0, "4cb0b15c70d9b41cbf8314fb45575b9090e2fbd04771f4856d81bf53ce9014b4"},
+		{2, "0102030405060708", memo, 1, "65e0186feb68ee868d89030ed4ccd97fd5d48261d57f39c4bafb2ed10e7c48fe"},
+	} {
+		prefix := testTxPrefix(v.version, v.maskedTokenID, v.eMemo)
+		prefix.FeeTokenID = v.feeTokenID
+		digest, err := TxPrefixDigest(prefix)
+		assert.Nil(err)
+		assert.Equal(v.digest, hex.EncodeToString(digest))
+		assert.Equal(digest, HashOfTxPrefix(prefix))
+
+		for i, out := range prefix.Outputs {
+			prefix.Outputs[i] = UnmarshalTxOut(MarshalTxOut(out))
+		}
+		roundTrip, err := TxPrefixDigest(prefix)
+		assert.Nil(err)
+		assert.Equal(digest, roundTrip)
+	}
+
+	_, err := TxPrefixDigest(testTxPrefix(3, "", ""))
+	assert.ErrorContains(err, "invalid amount version 3")
+	assert.Panics(func() { HashOfTxPrefix(testTxPrefix(3, "", "")) })
+	prefix := testTxPrefix(2, "", "")
+	prefix.Outputs[0].Amount = nil
+	_, err = TxPrefixDigest(prefix)
+	assert.NotNil(err)
+	prefix = testTxPrefix(2, "", "")
+	prefix.Inputs[0].Proofs[0].Index = "-1"
+	_, err = TxPrefixDigest(prefix)
+	assert.NotNil(err)
+	prefix = testTxPrefix(2, "zz", "")
+	_, err = TxPrefixDigest(prefix)
+	assert.NotNil(err)
+}
+
+// TestHashOfTxPrefixC checks the digests of the tx prefixes with the amounts
+// of both versions against the ring signatures made by libmobilecoin, which
+// sign the extended message of the Rust digest of the prefix.
+func TestHashOfTxPrefixC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	acc := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	address, err := acc.B58Code(0)
+	require.Nil(err)
+
+	for version, amountVersion := range map[uint]int64{2: 1, 3: 2} {
+		inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{5 * MILLIMOB_TO_PICOMOB}, 0)
+		outlays := []*PaymentOutlay{{Address: address, Amount: MILLIMOB_TO_PICOMOB}}
+		output, err := TransactionBuilderBuildOutlays(context.Background(), inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, version, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+		require.Nil(err)
+		tx, err := decodeTestTx(output.RawTransaction)
+		require.Nil(err)
+		for _, out := range tx.Prefix.Outputs {
+			require.Equal(amountVersion, out.Amount.Version)
+			require.Len(out.EMemo, 132)
+		}
+
+		digest, err := TxPrefixDigest(tx.Prefix)
+		require.Nil(err)
+		message, err := ExtendedMessageDigest(version, digest, tx.Signature)
+		require.Nil(err)
+		for i, in := range tx.Prefix.Inputs {
+			require.Nil(VerifyRingMLSAG(tx.Signature.RingSignatures[i], message, in.Ring, tx.Signature.PseudoOutputCommitments[i]))
+		}
+		digest[0] ^= 1
+		message, err = ExtendedMessageDigest(version, digest, tx.Signature)
+		require.Nil(err)
+		require.NotNil(VerifyRingMLSAG(tx.Signature.RingSignatures[0], message, tx.Prefix.Inputs[0].Ring, tx.Signature.PseudoOutputCommitments[0]))
+	}
+}
+
+func testTxPrefix(version int64, maskedTokenID, eMemo string) *TxPrefix {
+	point := func(k uint64) string {
+		var p ristretto.Point
+		return hex.EncodeToString(p.ScalarMultBase(uint64ToScalar(k)).Bytes())
+	}
+	txOut := func(seed uint64) *TxOut {
+		return &TxOut{
+			Amount: &Amount{
+				Commitment:    point(seed + 1),
+				MaskedValue:   MaskedValue(0x0102030405060708),
+				MaskedTokenID: maskedTokenID,
+				Version:       version,
+			},
+			TargetKey: point(seed + 2),
+			PublicKey: point(seed + 3),
+			EFogHint:  strings.Repeat("a4", 84),
+			EMemo:     eMemo,
+		}
+	}
+	return &TxPrefix{
+		Inputs: []*TxIn{{
+			Ring: []*TxOut{txOut(10), txOut(20)},
+			Proofs: []*TxOutMembershipProof{{
+				Index:        "7",
+				HighestIndex: "100",
+				Elements: []*TxOutMembershipElement{{
+					Range: &Range{From: "0", To: "7"},
+					Hash:  strings.Repeat("c5", 32),
+				}},
+			}, {
+				Index:        "8",
+				HighestIndex: "100",
+			}},
+		}},
+		Outputs:        []*TxOut{txOut(30), txOut(40)},
+		Fee:            MOB_MINIMUM_FEE,
+		TombstoneBlock: 1234,
+	}
+}
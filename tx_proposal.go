@@ -116,6 +116,7 @@ type TxPrefix struct {
 	Inputs         []*TxIn        `json:"inputs"`
 	Outputs        []*TxOut       `json:"outputs"`
 	Fee            FeeValue       `json:"fee"`
+	FeeTokenID     uint64         `json:"fee_token_id"`
 	TombstoneBlock TombstoneValue `json:"tombstone_block"`
 }
 
@@ -129,6 +130,9 @@ type SignatureRctBulletproofs struct {
 	RingSignatures          []*RingMLSAG `json:"ring_signatures"`
 	PseudoOutputCommitments []string     `json:"pseudo_output_commitments"`
 	RangeProofs             string       `json:"range_proofs"`
+	TokenRangeProofs        []string     `json:"token_range_proofs"`
+	PseudoOutputTokenIDs    []uint64     `json:"pseudo_output_token_ids"`
+	OutputTokenIDs          []uint64     `json:"output_token_ids"`
 }
 
 type Tx struct {
@@ -171,3 +171,28 @@ func McTxOutGetSharedSecret(publicKeyStr, viewPrivateKeyStr string) (string, err
 	}
 	return hex.EncodeToString(C.GoBytes(out_shared_secret_bytes, 32)), nil
 }
+
+// mc_tx_out_get_key_image, the hex key image of the TxOut received by the
+// subaddress with the spend private key
+func MCTxOutGetKeyImage(txOutTargetKeyStr, txOutPublicKeyStr, viewPrivateKeyStr, subaddressSpendPrivateKeyStr string) (string, error) {
+	tx_out_target_key, free_tx_out_target_key := newBufferC(account.HexToBytes(txOutTargetKeyStr))
+	defer free_tx_out_target_key()
+	tx_out_public_key, free_tx_out_public_key := newBufferC(account.HexToBytes(txOutPublicKeyStr))
+	defer free_tx_out_public_key()
+	view_private_key, free_view_private_key := newBufferC(account.HexToBytes(viewPrivateKeyStr))
+	defer free_view_private_key()
+	subaddress_spend_private_key, free_subaddress_spend_private_key := newBufferC(account.HexToBytes(subaddressSpendPrivateKeyStr))
+	defer free_subaddress_spend_private_key()
+	out_key_image, free_out_key_image := newMutableBufferC(32)
+	defer free_out_key_image()
+
+	var out_error *C.McError
+	b, err := C.mc_tx_out_get_key_image(tx_out_target_key, tx_out_public_key, view_private_key, subaddress_spend_private_key, out_key_image, &out_error)
+	if err != nil {
+		return "", err
+	}
+	if !b {
+		return "", mcError("mc_tx_out_get_key_image", out_error)
+	}
+	return hex.EncodeToString(mutableBufferBytes(out_key_image)), nil
+}
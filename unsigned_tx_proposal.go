@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	UNSIGNED_TX_PROPOSAL_VERSION = 1
+)
+
+// UnsignedTxProposal is assembled by the online host without any spend key,
+// with everything the offline signer needs to build the TxProposal. The fog
+// reports are the hex protobuf ReportResponse of each fog report url.
+type UnsignedTxProposal struct {
+	Version          int               `json:"version"`
+	InputList        []*UnspentTxOut   `json:"input_list"`
+	Proofs           *Proofs           `json:"proofs"`
+	OutlayList       []*Outlay         `json:"outlay_list"`
+	ChangeAddress    string            `json:"change_address"`
+	FogReports       map[string]string `json:"fog_reports"`
+	Fee              FeeValue          `json:"fee"`
+	TombstoneBlock   TombstoneValue    `json:"tombstone_block"`
+	TokenID          uint              `json:"token_id"`
+	BlockVersion     uint              `json:"block_version"`
+	PaymentRequestID uint64            `json:"payment_request_id,string"`
+}
+
+// NewUnsignedTxProposal fetches the fog reports of the outlays and the change
+// with fetcher, nil for DefaultFogReportFetcher. The key images of the inputs
+// are left to the offline signer. The memos of the payment requests are only
+// for the payer and not in the proposal.
+func NewUnsignedTxProposal(ctx context.Context, inputs []*UnspentTxOut, proofs *Proofs, outlays []*PaymentOutlay, fee, tombstone uint64, tokenID, version uint, changeStr string, fetcher FogReportFetcher) (*UnsignedTxProposal, error) {
+	if len(inputs) == 0 || len(outlays) == 0 {
+		return nil, errors.New("empty inputs or outlays")
+	}
+	if fetcher == nil {
+		fetcher = DefaultFogReportFetcher
+	}
+	change, err := account.DecodeB58Code(changeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	unsigned := &UnsignedTxProposal{
+		Version:        UNSIGNED_TX_PROPOSAL_VERSION,
+		InputList:      inputs,
+		Proofs:         proofs,
+		ChangeAddress:  changeStr,
+		FogReports:     make(map[string]string),
+		Fee:            FeeValue(fee),
+		TombstoneBlock: TombstoneValue(tombstone),
+		TokenID:        tokenID,
+		BlockVersion:   version,
+	}
+	fogReportUrls := []string{change.FogReportUrl}
+	for _, outlay := range outlays {
+		if outlay.Memo > 0 {
+			if unsigned.PaymentRequestID > 0 && unsigned.PaymentRequestID != outlay.Memo {
+				return nil, fmt.Errorf("conflicting outlay memo %d %d", unsigned.PaymentRequestID, outlay.Memo)
+			}
+			unsigned.PaymentRequestID = outlay.Memo
+		}
+		payment, err := decodeRecipient(outlay.Address, outlay.Amount, tokenID)
+		if err != nil {
+			return nil, err
+		}
+		unsigned.OutlayList = append(unsigned.OutlayList, &Outlay{
+			Value:    strconv.FormatUint(payment.Value, 10),
+			Receiver: payment.Address,
+		})
+		fogReportUrls = append(fogReportUrls, payment.Address.FogReportUrl)
+	}
+
+	for _, fogReportUrl := range fogReportUrls {
+		if fogReportUrl == "" || unsigned.FogReports[fogReportUrl] != "" {
+			continue
+		}
+		report, err := fetcher.FetchReports(ctx, fogReportUrl)
+		if err != nil {
+			return nil, err
+		}
+		data, err := proto.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		unsigned.FogReports[fogReportUrl] = hex.EncodeToString(data)
+	}
+	return unsigned, nil
+}
+
+func UnmarshalUnsignedTxProposal(data []byte) (*UnsignedTxProposal, error) {
+	var unsigned UnsignedTxProposal
+	err := json.Unmarshal(data, &unsigned)
+	if err != nil {
+		return nil, err
+	}
+	if unsigned.Version != UNSIGNED_TX_PROPOSAL_VERSION {
+		return nil, fmt.Errorf("unsupported unsigned tx proposal version %d", unsigned.Version)
+	}
+	return &unsigned, nil
+}
+
+// Sign builds the TxProposal on the offline host, the fog reports are attested
+// with trust against the enclaves of the offline host.
+func (unsigned *UnsignedTxProposal) Sign(ctx context.Context, viewPrivateStr, spendPrivateStr string, trust *FogTrustConfig) (*TxProposal, error) {
+	acc, err := account.NewAccountKey(viewPrivateStr, spendPrivateStr)
+	if err != nil {
+		return nil, err
+	}
+	if acc.SpendPrivateKey == nil {
+		return nil, errors.New("no spend private key")
+	}
+
+	reports := make(map[string]*types.ReportResponse)
+	for fogReportUrl, reportHex := range unsigned.FogReports {
+		data, err := hex.DecodeString(reportHex)
+		if err != nil {
+			return nil, err
+		}
+		var report types.ReportResponse
+		err = proto.Unmarshal(data, &report)
+		if err != nil {
+			return nil, err
+		}
+		reports[fogReportUrl] = &report
+	}
+
+	utxos := make([]*UTXO, len(unsigned.InputList))
+	inputList := make([]*UnspentTxOut, len(unsigned.InputList))
+	for i, input := range unsigned.InputList {
+		amount, err := strconv.ParseUint(input.Value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		script, err := json.Marshal(input.TxOut)
+		if err != nil {
+			return nil, err
+		}
+		utxos[i] = &UTXO{
+			Amount:       amount,
+			ScriptPubKey: hex.EncodeToString(script),
+			Account: &SpendAccount{
+				ViewPrivateKey:  viewPrivateStr,
+				SpendPrivateKey: spendPrivateStr,
+				SubaddressIndex: input.SubaddressIndex,
+			},
+		}
+
+		keyImage, err := KeyImageForTxOut(input.TxOut, acc.ViewPrivateKey, acc.SubaddressSpendPrivateKey(input.SubaddressIndex))
+		if err != nil {
+			return nil, err
+		}
+		signed := *input
+		signed.KeyImage = keyImage
+		inputList[i] = &signed
+	}
+
+	outlays := make([]*PaymentOutlay, len(unsigned.OutlayList))
+	for i, outlay := range unsigned.OutlayList {
+		amount, err := strconv.ParseUint(outlay.Value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		address, err := outlay.Receiver.B58Code()
+		if err != nil {
+			return nil, err
+		}
+		outlays[i] = &PaymentOutlay{Address: address, Amount: amount}
+	}
+
+	memo := PaymentRequestMemoOptions(unsigned.PaymentRequestID)
+	fetcher := NewStaticFogReportFetcher(reports)
+	output, err := TransactionBuilderBuildOutlaysWithMemo(ctx, utxos, unsigned.Proofs, outlays, uint64(unsigned.Fee), uint64(unsigned.TombstoneBlock), unsigned.TokenID, unsigned.BlockVersion, unsigned.ChangeAddress, memo, trust, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return txProposalFromOutput(output, inputList, unsigned.OutlayList)
+}
+
+func txProposalFromOutput(output *Output, inputList []*UnspentTxOut, outlayList []*Outlay) (*TxProposal, error) {
+	data, err := hex.DecodeString(output.RawTransaction)
+	if err != nil {
+		return nil, err
+	}
+	var tx types.Tx
+	err = proto.Unmarshal(data, &tx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := &TxProposal{
+		InputList:  inputList,
+		OutlayList: outlayList,
+		Tx:         UnmarshalTx(&tx),
+		Fee:        output.Fee,
+	}
+	for i, outlay := range output.Outlays {
+		if int64(i) == output.ChangeIndex {
+			continue
+		}
+		index := slices.IndexFunc(proposal.Tx.Prefix.Outputs, func(out *TxOut) bool {
+			return out.PublicKey == outlay.OutputHash
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("outlay %d output %s not found", i, outlay.OutputHash)
+		}
+		proposal.OutlayIndexToTxOutIndex = append(proposal.OutlayIndexToTxOutIndex, []int{i, index})
+
+		confirmation, err := hex.DecodeString(outlay.ConfirmationNumber)
+		if err != nil {
+			return nil, err
+		}
+		numbers := make([]int, len(confirmation))
+		for j, b := range confirmation {
+			numbers[j] = int(b)
+		}
+		proposal.OutlayConfirmationNumbers = append(proposal.OutlayConfirmationNumbers, numbers)
+	}
+	return proposal, nil
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUnsignedTxProposal(t *testing.T) {
+	assert := assert.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	change, err := acc.B58Code(0)
+	assert.Nil(err)
+	out := newScannerTestTxOut(assert, acc.PublicAddress(2), 100, 0, nil)
+	inputs := []*UnspentTxOut{{TxOut: out, SubaddressIndex: 2, Value: "100"}}
+	outlays := []*PaymentOutlay{{Address: change, Amount: 10, Memo: 7}}
+
+	unsigned, err := NewUnsignedTxProposal(context.Background(), inputs, &Proofs{}, outlays, 1, 1000, 0, 3, change, NewStaticFogReportFetcher(nil))
+	assert.Nil(err)
+	assert.Equal(uint64(7), unsigned.PaymentRequestID)
+	assert.Len(unsigned.FogReports, 0)
+	assert.Equal("10", unsigned.OutlayList[0].Value)
+
+	data, err := json.Marshal(unsigned)
+	assert.Nil(err)
+	decoded, err := UnmarshalUnsignedTxProposal(data)
+	assert.Nil(err)
+	assert.Equal(unsigned, decoded)
+
+	decoded.Version = 2
+	data, err = json.Marshal(decoded)
+	assert.Nil(err)
+	_, err = UnmarshalUnsignedTxProposal(data)
+	assert.NotNil(err)
+
+	outlays = append(outlays, &PaymentOutlay{Address: change, Amount: 10, Memo: 8})
+	_, err = NewUnsignedTxProposal(context.Background(), inputs, &Proofs{}, outlays, 1, 1000, 0, 3, change, NewStaticFogReportFetcher(nil))
+	assert.NotNil(err)
+}
+
+func TestTxProposalFromOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	tx := &types.Tx{
+		Prefix: &types.TxPrefix{
+			Outputs: []*types.TxOut{
+				{PublicKey: &types.CompressedRistretto{Data: []byte{1}}},
+				{PublicKey: &types.CompressedRistretto{Data: []byte{2}}},
+			},
+		},
+		Signature: &types.SignatureRctBulletproofs{},
+	}
+	data, err := proto.Marshal(tx)
+	assert.Nil(err)
+	output := &Output{
+		RawTransaction: hex.EncodeToString(data),
+		Fee:            400000000,
+		ChangeIndex:    1,
+		Outlays: []*OutlayOutput{
+			{OutputHash: "02", ConfirmationNumber: "0a0b"},
+			{OutputHash: "01", ConfirmationNumber: "0c0d"},
+		},
+	}
+	proposal, err := txProposalFromOutput(output, nil, nil)
+	assert.Nil(err)
+	assert.Equal([][]int{{0, 1}}, proposal.OutlayIndexToTxOutIndex)
+	assert.Equal([][]int{{10, 11}}, proposal.OutlayConfirmationNumbers)
+	assert.Equal(uint64(400000000), proposal.Fee)
+
+	output.Outlays[0].OutputHash = "03"
+	_, err = txProposalFromOutput(output, nil, nil)
+	assert.NotNil(err)
+}
@@ -1,9 +1,23 @@
 package api
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"log"
+	mrand "math/rand"
+	"sync"
 	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/MixinNetwork/mobilecoin-account/types"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestUtils(t *testing.T) {
@@ -14,3 +28,114 @@ func TestUtils(t *testing.T) {
 	confirmation := ConfirmationNumberFromSecret(buf)
 	log.Println(hex.EncodeToString(confirmation))
 }
+
+var libMobileCoin struct {
+	sync.Once
+	err error
+}
+
+// skipWithoutLibMobileCoin skips the tests of the libmobilecoin bindings when
+// the linked library is a stub, which fails to derive the keys of any root
+// entropy.
+func skipWithoutLibMobileCoin(t *testing.T) {
+	libMobileCoin.Do(func() {
+		_, _, libMobileCoin.err = MCAccountPrivateKeysFromRootEntropy(make([]byte, 32))
+	})
+	if libMobileCoin.err != nil {
+		t.Skipf("libmobilecoin unavailable: %v", libMobileCoin.err)
+	}
+}
+
+// newTestInputs returns the UTXOs of values owned by subaddress 0 of acc and
+// their proofs, each owned TxOut is mixed into a ring of RING_SIZE with decoys.
+func newTestInputs(assert *assert.Assertions, acc *account.Account, values []uint64, tokenID uint64) ([]*UTXO, *Proofs) {
+	owner := &SpendAccount{
+		ViewPrivateKey:  hex.EncodeToString(acc.ViewPrivateKey.Bytes()),
+		SpendPrivateKey: hex.EncodeToString(acc.SpendPrivateKey.Bytes()),
+	}
+	utxos := make([]*UTXO, len(values))
+	proofs := &Proofs{}
+	for i, value := range values {
+		out := newScannerTestTxOut(assert, acc.PublicAddress(0), value, tokenID, nil)
+		script, err := json.Marshal(out)
+		assert.Nil(err)
+		utxos[i] = &UTXO{
+			TransactionHash: out.PublicKey,
+			Index:           uint32(i),
+			Amount:          value,
+			TokenID:         uint(tokenID),
+			ScriptPubKey:    hex.EncodeToString(script),
+			Account:         owner,
+		}
+
+		real, ring := newTestRing(assert, out, uint64(i*RING_SIZE), value, tokenID)
+		proofs.Ring = append(proofs.Ring, real)
+		proofs.Rings = append(proofs.Rings, ring)
+	}
+	return utxos, proofs
+}
+
+// newTestRing mixes out at the global index into a ring of RING_SIZE with the
+// decoys of the same value and token at the following indices.
+func newTestRing(assert *assert.Assertions, out *TxOut, index, value, tokenID uint64) (*TxOutWithProof, []*TxOutWithProof) {
+	var view, spend ristretto.Scalar
+	decoys := &account.Account{ViewPrivateKey: view.Rand(), SpendPrivateKey: spend.Rand()}
+	membershipProof := func(index uint64) *TxOutMembershipProof {
+		hash := make([]byte, 32)
+		rand.Read(hash)
+		return &TxOutMembershipProof{
+			Index:        fmt.Sprint(index),
+			HighestIndex: fmt.Sprint(index + RING_SIZE),
+			Elements: []*TxOutMembershipElement{{
+				Range: &Range{From: fmt.Sprint(index), To: fmt.Sprint(index)},
+				Hash:  hex.EncodeToString(hash),
+			}},
+		}
+	}
+
+	real := &TxOutWithProof{TxOut: out, Proof: membershipProof(index)}
+	ring := []*TxOutWithProof{real}
+	for j := uint64(1); j < RING_SIZE; j++ {
+		decoy := newScannerTestTxOut(assert, decoys.PublicAddress(0), value, tokenID, nil)
+		ring = append(ring, &TxOutWithProof{TxOut: decoy, Proof: membershipProof(index + j)})
+	}
+	mrand.Shuffle(len(ring), func(a, b int) { ring[a], ring[b] = ring[b], ring[a] })
+	return real, ring
+}
+
+// decodeTestTx decodes the hex protobuf RawTransaction of a built Output
+func decodeTestTx(raw string) (*Tx, error) {
+	buf, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var tx types.Tx
+	err = proto.Unmarshal(buf, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalTx(&tx), nil
+}
+
+// nonceReader streams sha512(label || u32 counter) blocks, one scalar per
+// block, so the signature and proof vectors can be reproduced outside of Go.
+type nonceReader struct {
+	label   []byte
+	counter uint32
+	buf     []byte
+}
+
+func (r *nonceReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			block := sha512.Sum512(binary.LittleEndian.AppendUint32(bytes.Clone(r.label), r.counter))
+			r.buf = block[:]
+			r.counter++
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
+}
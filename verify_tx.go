@@ -0,0 +1,237 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bwesterb/go-ristretto"
+)
+
+const (
+	VERIFY_TX_CHECK_FORMAT       = "format"
+	VERIFY_TX_CHECK_INPUTS       = "inputs"
+	VERIFY_TX_CHECK_OUTPUTS      = "outputs"
+	VERIFY_TX_CHECK_RING_SIZE    = "ring_size"
+	VERIFY_TX_CHECK_RING_SORTED  = "ring_sorted"
+	VERIFY_TX_CHECK_INPUT_SORTED = "inputs_sorted"
+	VERIFY_TX_CHECK_KEY_IMAGES   = "key_images"
+	VERIFY_TX_CHECK_TOMBSTONE    = "tombstone"
+	VERIFY_TX_CHECK_BALANCE      = "balance"
+	VERIFY_TX_CHECK_SIGNATURE    = "signature"
+	VERIFY_TX_CHECK_RANGE_PROOFS = "range_proofs"
+)
+
+// VerifyTxOptions is the ledger context the transaction is verified against.
+// BlockVersion selects the message signed by the ring signatures and the
+// layout of the range proofs. Message defaults to the digest of the tx prefix
+// when empty, and is extended with the pseudo outputs and range proofs. The fee
+// and the range proof before block version 3 are of the FeeTokenID of the
+// prefix.
+type VerifyTxOptions struct {
+	CurrentBlock uint64
+	BlockVersion uint
+	Message      []byte
+}
+
+// VerifyTxError reports the name of the check that rejected the transaction.
+type VerifyTxError struct {
+	Check string
+	Err   error
+}
+
+func (e *VerifyTxError) Error() string {
+	return fmt.Sprintf("verify tx %s: %v", e.Check, e.Err)
+}
+
+func (e *VerifyTxError) Unwrap() error {
+	return e.Err
+}
+
+func verifyTxError(check string, format string, args ...any) error {
+	return &VerifyTxError{Check: check, Err: fmt.Errorf(format, args...)}
+}
+
+// VerifyTx runs the consensus checks on a decoded transaction before it is
+// submitted, the returned error is a *VerifyTxError naming the failed check.
+func VerifyTx(tx *Tx, opts *VerifyTxOptions) error {
+	if opts == nil {
+		opts = &VerifyTxOptions{}
+	}
+	if tx == nil || tx.Prefix == nil || tx.Signature == nil {
+		return verifyTxError(VERIFY_TX_CHECK_FORMAT, "missing prefix or signature")
+	}
+	prefix, signature := tx.Prefix, tx.Signature
+
+	if len(prefix.Inputs) == 0 || len(prefix.Inputs) > MAX_INPUTS {
+		return verifyTxError(VERIFY_TX_CHECK_INPUTS, "invalid inputs count %d, max %d", len(prefix.Inputs), MAX_INPUTS)
+	}
+	if len(prefix.Outputs) == 0 || len(prefix.Outputs) > MAX_OUTPUTS {
+		return verifyTxError(VERIFY_TX_CHECK_OUTPUTS, "invalid outputs count %d, max %d", len(prefix.Outputs), MAX_OUTPUTS)
+	}
+	if len(signature.RingSignatures) != len(prefix.Inputs) {
+		return verifyTxError(VERIFY_TX_CHECK_FORMAT, "ring signatures count %d, inputs count %d", len(signature.RingSignatures), len(prefix.Inputs))
+	}
+	if len(signature.PseudoOutputCommitments) != len(prefix.Inputs) {
+		return verifyTxError(VERIFY_TX_CHECK_FORMAT, "pseudo outputs count %d, inputs count %d", len(signature.PseudoOutputCommitments), len(prefix.Inputs))
+	}
+
+	for i, in := range prefix.Inputs {
+		if len(in.Ring) != RING_SIZE {
+			return verifyTxError(VERIFY_TX_CHECK_RING_SIZE, "input %d ring size %d, expected %d", i, len(in.Ring), RING_SIZE)
+		}
+		if len(in.Proofs) != len(in.Ring) {
+			return verifyTxError(VERIFY_TX_CHECK_RING_SIZE, "input %d proofs count %d, ring size %d", i, len(in.Proofs), len(in.Ring))
+		}
+		for j := 1; j < len(in.Ring); j++ {
+			if in.Ring[j-1].PublicKey >= in.Ring[j].PublicKey {
+				return verifyTxError(VERIFY_TX_CHECK_RING_SORTED, "input %d ring element %d not sorted or duplicated", i, j)
+			}
+		}
+		if i > 0 && prefix.Inputs[i-1].Ring[0].PublicKey >= in.Ring[0].PublicKey {
+			return verifyTxError(VERIFY_TX_CHECK_INPUT_SORTED, "input %d not sorted or duplicated", i)
+		}
+	}
+
+	for i := 1; i < len(prefix.Outputs); i++ {
+		if prefix.Outputs[i-1].PublicKey >= prefix.Outputs[i].PublicKey {
+			return verifyTxError(VERIFY_TX_CHECK_OUTPUTS, "output %d not sorted or duplicated", i)
+		}
+	}
+	for i, out := range prefix.Outputs {
+		if out.Amount == nil {
+			return verifyTxError(VERIFY_TX_CHECK_OUTPUTS, "output %d without amount", i)
+		}
+	}
+
+	keyImages := make(map[string]bool)
+	for i, mlsag := range signature.RingSignatures {
+		if keyImages[mlsag.KeyImage] {
+			return verifyTxError(VERIFY_TX_CHECK_KEY_IMAGES, "duplicated key image %s of input %d", mlsag.KeyImage, i)
+		}
+		keyImages[mlsag.KeyImage] = true
+	}
+
+	tombstone := uint64(prefix.TombstoneBlock)
+	if tombstone <= opts.CurrentBlock {
+		return verifyTxError(VERIFY_TX_CHECK_TOMBSTONE, "tombstone block %d exceeded at block %d", tombstone, opts.CurrentBlock)
+	}
+	if tombstone > opts.CurrentBlock+MAX_TOMBSTONE_BLOCKS {
+		return verifyTxError(VERIFY_TX_CHECK_TOMBSTONE, "tombstone block %d too far from block %d", tombstone, opts.CurrentBlock)
+	}
+
+	err := verifyTxBalance(tx, prefix.FeeTokenID)
+	if err != nil {
+		return &VerifyTxError{Check: VERIFY_TX_CHECK_BALANCE, Err: err}
+	}
+
+	err = verifyTxRangeProofs(tx, opts.BlockVersion, prefix.FeeTokenID)
+	if err != nil {
+		return &VerifyTxError{Check: VERIFY_TX_CHECK_RANGE_PROOFS, Err: err}
+	}
+
+	message := opts.Message
+	if len(message) == 0 {
+		message, err = TxPrefixDigest(prefix)
+		if err != nil {
+			return &VerifyTxError{Check: VERIFY_TX_CHECK_FORMAT, Err: err}
+		}
+	}
+	message, err = ExtendedMessageDigest(opts.BlockVersion, message, signature)
+	if err != nil {
+		return &VerifyTxError{Check: VERIFY_TX_CHECK_FORMAT, Err: err}
+	}
+	for i, in := range prefix.Inputs {
+		err := VerifyRingMLSAG(signature.RingSignatures[i], message, in.Ring, signature.PseudoOutputCommitments[i])
+		if err != nil {
+			return verifyTxError(VERIFY_TX_CHECK_SIGNATURE, "input %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Before block version 3 a single range proof of the fee token covers the pseudo
+// outputs and outputs. From block version 3 there is one range proof for each
+// token, in the order of the token ids, covering the pseudo outputs and then
+// the outputs of the token.
+func verifyTxRangeProofs(tx *Tx, blockVersion uint, feeTokenID uint64) error {
+	prefix, signature := tx.Prefix, tx.Signature
+	if blockVersion < 3 {
+		if len(signature.TokenRangeProofs) > 0 {
+			return fmt.Errorf("unexpected range proofs of tokens at block version %d", blockVersion)
+		}
+		commitments := append([]string{}, signature.PseudoOutputCommitments...)
+		for _, out := range prefix.Outputs {
+			commitments = append(commitments, out.Amount.Commitment)
+		}
+		return CheckRangeProofsBytes(signature.RangeProofs, commitments, feeTokenID)
+	}
+
+	if signature.RangeProofs != "" {
+		return fmt.Errorf("unexpected range proof bytes at block version %d", blockVersion)
+	}
+	if len(signature.PseudoOutputTokenIDs) != len(prefix.Inputs) {
+		return fmt.Errorf("pseudo output token ids count %d, inputs count %d", len(signature.PseudoOutputTokenIDs), len(prefix.Inputs))
+	}
+	if len(signature.OutputTokenIDs) != len(prefix.Outputs) {
+		return fmt.Errorf("output token ids count %d, outputs count %d", len(signature.OutputTokenIDs), len(prefix.Outputs))
+	}
+	var tokens []uint64
+	for _, id := range append(append([]uint64{}, signature.PseudoOutputTokenIDs...), signature.OutputTokenIDs...) {
+		if !slices.Contains(tokens, id) {
+			tokens = append(tokens, id)
+		}
+	}
+	slices.Sort(tokens)
+	if len(signature.TokenRangeProofs) != len(tokens) {
+		return fmt.Errorf("range proofs count %d, tokens count %d", len(signature.TokenRangeProofs), len(tokens))
+	}
+	for i, id := range tokens {
+		var commitments []string
+		for j, c := range signature.PseudoOutputCommitments {
+			if signature.PseudoOutputTokenIDs[j] == id {
+				commitments = append(commitments, c)
+			}
+		}
+		for j, out := range prefix.Outputs {
+			if signature.OutputTokenIDs[j] == id {
+				commitments = append(commitments, out.Amount.Commitment)
+			}
+		}
+		err := CheckRangeProofsBytes(signature.TokenRangeProofs[i], commitments, id)
+		if err != nil {
+			return fmt.Errorf("token %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// sum(pseudo_outputs) - sum(outputs) - fee * B must be the identity, where B
+// is the generator of the fee token
+func verifyTxBalance(tx *Tx, feeTokenID uint64) error {
+	var sum ristretto.Point
+	sum.SetZero()
+	for i, c := range tx.Signature.PseudoOutputCommitments {
+		p, err := decodePoint(c)
+		if err != nil {
+			return fmt.Errorf("invalid pseudo output %d commitment: %v", i, err)
+		}
+		sum.Add(&sum, p)
+	}
+	for i, out := range tx.Prefix.Outputs {
+		p, err := decodePoint(out.Amount.Commitment)
+		if err != nil {
+			return fmt.Errorf("invalid output %d commitment: %v", i, err)
+		}
+		sum.Sub(&sum, p)
+	}
+
+	var zero ristretto.Scalar
+	var identity ristretto.Point
+	fee := NewPedersenGensForToken(feeTokenID).Commit(uint64ToScalar(uint64(tx.Prefix.Fee)), zero.SetZero())
+	sum.Sub(&sum, fee)
+	if !sum.Equals(identity.SetZero()) {
+		return errors.New("commitments not balanced")
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"testing"
+
+	account "github.com/MixinNetwork/mobilecoin-account"
+	"github.com/bwesterb/go-ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTx(t *testing.T) {
+	assert := assert.New(t)
+
+	checkFailed := func(err error) string {
+		var verr *VerifyTxError
+		if !errors.As(err, &verr) {
+			return ""
+		}
+		return verr.Check
+	}
+
+	for _, version := range []uint{1, 2, 3} {
+		tx := buildTestTx(t, version, 0, []uint64{5 * MILLIMOB_TO_PICOMOB}, []uint64{5*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE}, MOB_MINIMUM_FEE, 100)
+		assert.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version}))
+		for _, other := range []uint{1, 2, 3} {
+			if other != version {
+				assert.NotNil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: other}))
+			}
+		}
+	}
+
+	// the transactions of another token pay the fee in it
+	for _, version := range []uint{2, 3} {
+		tx := buildTestTx(t, version, 1, []uint64{5 * MILLIMOB_TO_PICOMOB}, []uint64{5*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE}, MOB_MINIMUM_FEE, 100)
+		assert.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version}))
+		tx.Prefix.FeeTokenID = 0
+		assert.Equal(VERIFY_TX_CHECK_BALANCE, checkFailed(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version})))
+	}
+
+	tx := buildTestTx(t, 3, 0, []uint64{5 * MILLIMOB_TO_PICOMOB, 7 * MILLIMOB_TO_PICOMOB}, []uint64{3 * MILLIMOB_TO_PICOMOB, 9*MILLIMOB_TO_PICOMOB - MOB_MINIMUM_FEE}, MOB_MINIMUM_FEE, 100)
+	opts := &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3}
+	assert.Nil(VerifyTx(tx, opts))
+
+	assert.Equal(VERIFY_TX_CHECK_TOMBSTONE, checkFailed(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 100, BlockVersion: 3})))
+	tx.Prefix.TombstoneBlock = 10 + MAX_TOMBSTONE_BLOCKS + 1
+	assert.Equal(VERIFY_TX_CHECK_TOMBSTONE, checkFailed(VerifyTx(tx, opts)))
+	tx.Prefix.TombstoneBlock = 100
+
+	tx.Prefix.Fee += 1
+	assert.Equal(VERIFY_TX_CHECK_BALANCE, checkFailed(VerifyTx(tx, opts)))
+	tx.Prefix.Fee -= 1
+
+	keyImage := tx.Signature.RingSignatures[1].KeyImage
+	tx.Signature.RingSignatures[1].KeyImage = tx.Signature.RingSignatures[0].KeyImage
+	assert.Equal(VERIFY_TX_CHECK_KEY_IMAGES, checkFailed(VerifyTx(tx, opts)))
+	tx.Signature.RingSignatures[1].KeyImage = keyImage
+
+	assert.Equal(VERIFY_TX_CHECK_SIGNATURE, checkFailed(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: 3, Message: []byte("another")})))
+	// the fee is committed with the generator of the fee token of the prefix
+	tx.Prefix.FeeTokenID = 1
+	assert.Equal(VERIFY_TX_CHECK_BALANCE, checkFailed(VerifyTx(tx, opts)))
+	tx.Prefix.FeeTokenID = 0
+
+	rangeProofs := tx.Signature.TokenRangeProofs
+	tx.Signature.TokenRangeProofs = buildTestTx(t, 3, 0, []uint64{10}, []uint64{10}, 0, 100).Signature.TokenRangeProofs
+	assert.Equal(VERIFY_TX_CHECK_RANGE_PROOFS, checkFailed(VerifyTx(tx, opts)))
+	tx.Signature.TokenRangeProofs = append(rangeProofs, rangeProofs[0])
+	assert.Equal(VERIFY_TX_CHECK_RANGE_PROOFS, checkFailed(VerifyTx(tx, opts)))
+	tx.Signature.TokenRangeProofs = rangeProofs
+	tx.Signature.OutputTokenIDs[0] = 1
+	assert.Equal(VERIFY_TX_CHECK_RANGE_PROOFS, checkFailed(VerifyTx(tx, opts)))
+	tx.Signature.OutputTokenIDs[0] = 0
+
+	tx.Prefix.Inputs[0], tx.Prefix.Inputs[1] = tx.Prefix.Inputs[1], tx.Prefix.Inputs[0]
+	assert.Equal(VERIFY_TX_CHECK_INPUT_SORTED, checkFailed(VerifyTx(tx, opts)))
+	tx.Prefix.Inputs[0], tx.Prefix.Inputs[1] = tx.Prefix.Inputs[1], tx.Prefix.Inputs[0]
+
+	ring := tx.Prefix.Inputs[0].Ring
+	ring[1], ring[2] = ring[2], ring[1]
+	assert.Equal(VERIFY_TX_CHECK_RING_SORTED, checkFailed(VerifyTx(tx, opts)))
+	ring[1], ring[2] = ring[2], ring[1]
+
+	tx.Prefix.Inputs[0].Ring = ring[1:]
+	assert.Equal(VERIFY_TX_CHECK_RING_SIZE, checkFailed(VerifyTx(tx, opts)))
+	tx.Prefix.Inputs[0].Ring = ring
+
+	assert.Nil(VerifyTx(tx, opts))
+}
+
+// TestVerifyTxC verifies the transactions built and signed by libmobilecoin,
+// which checks the tx prefix digest, the extended message and the range
+// proofs of each block version against the Rust implementation.
+func TestVerifyTxC(t *testing.T) {
+	skipWithoutLibMobileCoin(t)
+	require := require.New(t)
+
+	var view, spend ristretto.Scalar
+	view.Rand()
+	spend.Rand()
+	acc := &account.Account{ViewPrivateKey: &view, SpendPrivateKey: &spend}
+	address, err := acc.B58Code(0)
+	require.Nil(err)
+
+	for _, version := range []uint{2, 3} {
+		inputs, proofs := newTestInputs(assert.New(t), acc, []uint64{3 * MILLIMOB_TO_PICOMOB, 4 * MILLIMOB_TO_PICOMOB}, 0)
+		outlays := []*PaymentOutlay{{Address: address, Amount: 2 * MILLIMOB_TO_PICOMOB}}
+		output, err := TransactionBuilderBuildOutlays(context.Background(), inputs, proofs, outlays, MOB_MINIMUM_FEE, 100, 0, version, address, DefaultFogTrustConfig, DefaultFogReportFetcher)
+		require.Nil(err)
+
+		tx, err := decodeTestTx(output.RawTransaction)
+		require.Nil(err)
+		require.Len(tx.Prefix.Inputs, 2)
+		require.Len(tx.Prefix.Outputs, 2)
+		require.Nil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version}))
+		require.NotNil(VerifyTx(tx, &VerifyTxOptions{CurrentBlock: 10, BlockVersion: version - 1}))
+	}
+}
+
+func buildTestTx(t *testing.T, blockVersion uint, tokenID uint64, inputValues, outputValues []uint64, fee, tombstone uint64) *Tx {
+	generators := NewPedersenGensForToken(tokenID)
+	randomPoint := func() string {
+		var p ristretto.Point
+		return hex.EncodeToString(p.Rand().Bytes())
+	}
+
+	var blindingSum ristretto.Scalar
+	blindingSum.SetZero()
+	outputBlindings := make([]*ristretto.Scalar, len(outputValues))
+	outputs := make([]*TxOut, len(outputValues))
+	for i, v := range outputValues {
+		var b ristretto.Scalar
+		outputBlindings[i] = b.Rand()
+		blindingSum.Add(&blindingSum, &b)
+		outputs[i] = &TxOut{
+			Amount:    &Amount{Commitment: hex.EncodeToString(generators.Commit(uint64ToScalar(v), &b).Bytes())},
+			TargetKey: randomPoint(),
+			PublicKey: randomPoint(),
+		}
+	}
+	order := make([]int, len(outputs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return outputs[order[i]].PublicKey < outputs[order[j]].PublicKey })
+	sortedOutputs := make([]*TxOut, len(outputs))
+	sortedValues := make([]uint64, len(outputs))
+	sortedBlindings := make([]*ristretto.Scalar, len(outputs))
+	for i, j := range order {
+		sortedOutputs[i], sortedValues[i], sortedBlindings[i] = outputs[j], outputValues[j], outputBlindings[j]
+	}
+
+	type testInput struct {
+		in             *TxIn
+		realIndex      int
+		onetime        *ristretto.Scalar
+		value          uint64
+		blinding       *ristretto.Scalar
+		pseudoBlinding *ristretto.Scalar
+	}
+	inputs := make([]*testInput, len(inputValues))
+	for i, v := range inputValues {
+		var onetime, blinding, pseudoBlinding ristretto.Scalar
+		onetime.Rand()
+		blinding.Rand()
+		if i == len(inputValues)-1 {
+			pseudoBlinding.Set(&blindingSum)
+		} else {
+			pseudoBlinding.Rand()
+			blindingSum.Sub(&blindingSum, &pseudoBlinding)
+		}
+
+		ring := make([]*TxOut, RING_SIZE)
+		proofs := make([]*TxOutMembershipProof, RING_SIZE)
+		for j := range ring {
+			ring[j] = &TxOut{
+				Amount:    &Amount{Commitment: randomPoint()},
+				TargetKey: randomPoint(),
+				PublicKey: randomPoint(),
+			}
+			proofs[j] = &TxOutMembershipProof{Index: "0", HighestIndex: "0"}
+		}
+		var targetKey ristretto.Point
+		ring[0].TargetKey = hex.EncodeToString(targetKey.ScalarMultBase(&onetime).Bytes())
+		ring[0].Amount.Commitment = hex.EncodeToString(generators.Commit(uint64ToScalar(v), &blinding).Bytes())
+		real := ring[0]
+		sort.Slice(ring, func(i, j int) bool { return ring[i].PublicKey < ring[j].PublicKey })
+		realIndex := 0
+		for j := range ring {
+			if ring[j] == real {
+				realIndex = j
+			}
+		}
+
+		inputs[i] = &testInput{
+			in:             &TxIn{Ring: ring, Proofs: proofs},
+			realIndex:      realIndex,
+			onetime:        &onetime,
+			value:          v,
+			blinding:       &blinding,
+			pseudoBlinding: &pseudoBlinding,
+		}
+	}
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].in.Ring[0].PublicKey < inputs[j].in.Ring[0].PublicKey })
+
+	prefix := &TxPrefix{Outputs: sortedOutputs, Fee: FeeValue(fee), FeeTokenID: tokenID, TombstoneBlock: TombstoneValue(tombstone)}
+	for _, input := range inputs {
+		prefix.Inputs = append(prefix.Inputs, input.in)
+	}
+	signature := &SignatureRctBulletproofs{}
+	var values []uint64
+	var blindings []*ristretto.Scalar
+	for _, input := range inputs {
+		signature.PseudoOutputCommitments = append(signature.PseudoOutputCommitments, hex.EncodeToString(generators.Commit(uint64ToScalar(input.value), input.pseudoBlinding).Bytes()))
+		signature.PseudoOutputTokenIDs = append(signature.PseudoOutputTokenIDs, tokenID)
+		values = append(values, input.value)
+		blindings = append(blindings, input.pseudoBlinding)
+	}
+	for range sortedOutputs {
+		signature.OutputTokenIDs = append(signature.OutputTokenIDs, tokenID)
+	}
+	values = append(values, sortedValues...)
+	blindings = append(blindings, sortedBlindings...)
+	proof, _, err := GenerateRangeProofs(values, blindings, generators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockVersion < 3 {
+		signature.RangeProofs = hex.EncodeToString(proof.Bytes())
+		signature.PseudoOutputTokenIDs, signature.OutputTokenIDs = nil, nil
+	} else {
+		signature.TokenRangeProofs = []string{hex.EncodeToString(proof.Bytes())}
+	}
+
+	digest, err := TxPrefixDigest(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := ExtendedMessageDigest(blockVersion, digest, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		mlsag, err := SignRingMLSAG(message, input.in.Ring, input.realIndex, input.onetime, input.value, input.blinding, input.pseudoBlinding, tokenID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature.RingSignatures = append(signature.RingSignatures, mlsag)
+	}
+	return &Tx{Prefix: prefix, Signature: signature}
+}